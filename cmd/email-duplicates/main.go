@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/repository"
+	"golang-authentication/internal/usecase"
+	"log"
+	"os"
+)
+
+// Lists the accounts whose emails collide once normalized with the rules in config.json.
+//
+//	go run ./cmd/email-duplicates [-backfill]
+func main() {
+	backfill := flag.Bool("backfill", false, "update the stored canonical email of every user to the current rules, except the colliding ones")
+	flag.Parse()
+
+	viperConfig := config.NewViper("./../../")
+	database := config.NewGorm(viperConfig)
+	userRepository := repository.NewUserRepository(database)
+	emailCollisionUseCase := usecase.NewEmailCollisionUseCase(userRepository, viperConfig)
+
+	collisions, err := emailCollisionUseCase.FindCollisions(context.Background(), *backfill)
+	if err != nil {
+		log.Fatalf("Error while checking email collisions %v", err)
+	}
+
+	if len(collisions) == 0 {
+		fmt.Println("No colliding emails found")
+		return
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(collisions)
+	if err != nil {
+		log.Fatalf("Error while printing email collisions %v", err)
+	}
+	os.Exit(1)
+}
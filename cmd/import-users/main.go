@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"golang-authentication/internal/usecase"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Imports users with their existing password hashes from a CSV or JSONL file, or resumes an interrupted import.
+// Every row that can't be imported is printed with its row number.
+//
+//	go run ./cmd/import-users -file users.csv [-format csv|jsonl]
+//	go run ./cmd/import-users -resume <import id>
+func main() {
+	path := flag.String("file", "", "CSV or JSONL file to import")
+	format := flag.String("format", "", "format of the file, csv or jsonl. Defaults to the file extension")
+	resume := flag.Int("resume", 0, "id of an import to resume")
+	flag.Parse()
+
+	if (*path == "") == (*resume == 0) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	viperConfig := config.NewViper("./../../")
+	err := config.CheckSecrets(viperConfig)
+	if err != nil {
+		log.Fatalf("Error while loading the secrets %v", err)
+	}
+	validator := config.NewValidator()
+	database := config.NewGorm(viperConfig)
+	userRepository := repository.NewUserRepository(database)
+	userImportRepository := repository.NewUserImportRepository(database)
+	userImportUseCase := usecase.NewUserImportUseCase(userRepository, userImportRepository, validator, viperConfig)
+	ctx := context.Background()
+
+	jobID := *resume
+	if jobID == 0 {
+		file, err := os.Open(*path)
+		if err != nil {
+			log.Fatalf("Error while opening %s %v", *path, err)
+		}
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(*path), ".")
+		}
+
+		job, err := userImportUseCase.CreateImport(ctx, *format, file, nil)
+		file.Close()
+		if err != nil {
+			log.Fatalf("Error while creating import %v", err)
+		}
+		jobID = job.Id
+		fmt.Printf("Created import %d\n", jobID)
+	}
+
+	_, err = userImportUseCase.RunImport(ctx, jobID)
+	if err != nil {
+		log.Fatalf("Error while running import %d %v", jobID, err)
+	}
+
+	//print the summary with every row error
+	page := models.PageRequest{Page: 1, Size: models.MaxPageSize}
+	result, total, err := userImportUseCase.GetImport(ctx, jobID, page)
+	if err != nil {
+		log.Fatalf("Error while getting import %d %v", jobID, err)
+	}
+	for int64(len(result.Errors)) < total {
+		page.Page++
+		next, _, err := userImportUseCase.GetImport(ctx, jobID, page)
+		if err != nil {
+			log.Fatalf("Error while getting import %d %v", jobID, err)
+		}
+		if len(next.Errors) == 0 {
+			break
+		}
+		result.Errors = append(result.Errors, next.Errors...)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(result)
+	if err != nil {
+		log.Fatalf("Error while printing import %v", err)
+	}
+
+	if result.FailedRows > 0 || result.ErrorMessage != "" {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,13 @@
+ALTER TABLE users
+    ADD COLUMN created_at_old BIGINT,
+    ADD COLUMN updated_at_old BIGINT;
+
+UPDATE users
+SET created_at_old = ROUND(UNIX_TIMESTAMP(created_at) * 1000),
+    updated_at_old = ROUND(UNIX_TIMESTAMP(updated_at) * 1000);
+
+ALTER TABLE users
+    DROP COLUMN created_at,
+    DROP COLUMN updated_at,
+    RENAME COLUMN created_at_old TO created_at,
+    RENAME COLUMN updated_at_old TO updated_at;
@@ -0,0 +1,15 @@
+ALTER TABLE users
+    ADD COLUMN created_at_new DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    ADD COLUMN updated_at_new DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3);
+
+-- The old columns were written through a uint8 field, so only values that still look like
+-- unix milliseconds are kept. Everything else is unrecoverable and falls back to the migration time.
+UPDATE users
+SET created_at_new = IF(created_at >= 1000000000000, FROM_UNIXTIME(created_at / 1000), created_at_new),
+    updated_at_new = IF(updated_at >= 1000000000000, FROM_UNIXTIME(updated_at / 1000), updated_at_new);
+
+ALTER TABLE users
+    DROP COLUMN created_at,
+    DROP COLUMN updated_at,
+    RENAME COLUMN created_at_new TO created_at,
+    RENAME COLUMN updated_at_new TO updated_at;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP INDEX idx_users_deleted_at,
+    DROP COLUMN deleted_at,
+    DROP COLUMN role;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user',
+    ADD COLUMN deleted_at DATETIME(3) NULL,
+    ADD INDEX idx_users_deleted_at (deleted_at);
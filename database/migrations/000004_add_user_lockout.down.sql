@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP COLUMN locked_until,
+    DROP COLUMN lockout_count,
+    DROP COLUMN failed_sign_in_attempts;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN failed_sign_in_attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN lockout_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN locked_until DATETIME(3) NULL;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS sessions;
+
+ALTER TABLE users
+    DROP COLUMN status_expires_at,
+    DROP COLUMN status_reason,
+    DROP COLUMN status;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP INDEX idx_users_username,
+    DROP COLUMN username;
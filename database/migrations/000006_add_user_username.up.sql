@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN username VARCHAR(32) NULL,
+    ADD UNIQUE INDEX idx_users_username (username);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP INDEX idx_users_email_canonical,
+    DROP COLUMN email_canonical;
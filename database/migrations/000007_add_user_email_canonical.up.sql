@@ -0,0 +1,7 @@
+-- The index is not unique yet, existing accounts may collide once normalized.
+-- Run `go run ./cmd/email-duplicates -backfill` to apply the configured provider rules and list the collisions.
+ALTER TABLE users
+    ADD COLUMN email_canonical VARCHAR(255) NULL,
+    ADD INDEX idx_users_email_canonical (email_canonical);
+
+UPDATE users SET email_canonical = LOWER(TRIM(email));
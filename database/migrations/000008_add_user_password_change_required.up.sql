@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN password_change_required BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN password_changed_at DATETIME(3) NULL;
+
+UPDATE users SET password_changed_at = created_at;
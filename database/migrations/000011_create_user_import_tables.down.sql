@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_import_errors;
+DROP TABLE IF EXISTS user_import_jobs;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS user_import_jobs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    format VARCHAR(10) NOT NULL,
+    file_path VARCHAR(512) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    processed_rows INT NOT NULL DEFAULT 0,
+    imported_rows INT NOT NULL DEFAULT 0,
+    failed_rows INT NOT NULL DEFAULT 0,
+    error_message VARCHAR(255) NOT NULL DEFAULT '',
+    created_by INT NULL,
+    lease_expires_at DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    finished_at DATETIME(3) NULL,
+    INDEX idx_user_import_jobs_status (status),
+    CONSTRAINT fk_user_import_jobs_created_by FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_import_errors (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    job_id INT NOT NULL,
+    row_no INT NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    message VARCHAR(255) NOT NULL,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_user_import_errors_job_id (job_id, row_no),
+    CONSTRAINT fk_user_import_errors_job FOREIGN KEY (job_id) REFERENCES user_import_jobs (id) ON DELETE CASCADE
+);
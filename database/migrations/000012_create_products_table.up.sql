@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS products (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    stock INT NOT NULL DEFAULT 0,
+    price INT NOT NULL DEFAULT 0,
+    user_id INT NOT NULL,
+    INDEX idx_products_user_id (user_id),
+    CONSTRAINT fk_products_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
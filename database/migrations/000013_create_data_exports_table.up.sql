@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    file_path VARCHAR(512) NOT NULL DEFAULT '',
+    error_message VARCHAR(255) NOT NULL DEFAULT '',
+    lease_expires_at DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    completed_at DATETIME(3) NULL,
+    expires_at DATETIME(3) NULL,
+    INDEX idx_data_exports_user_id (user_id),
+    INDEX idx_data_exports_status (status),
+    CONSTRAINT fk_data_exports_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP COLUMN last_login_at;
+
+DROP TABLE IF EXISTS login_attempts;
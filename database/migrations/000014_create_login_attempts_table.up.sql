@@ -0,0 +1,16 @@
+CREATE TABLE login_attempts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NULL,
+    identifier VARCHAR(255) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    outcome VARCHAR(32) NOT NULL,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_login_attempts_user_id (user_id, id),
+    INDEX idx_login_attempts_ip_address (ip_address),
+    INDEX idx_login_attempts_created_at (created_at),
+    CONSTRAINT fk_login_attempts_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+ALTER TABLE users
+    ADD COLUMN last_login_at DATETIME(3) NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE magic_links (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    browser_hash CHAR(64) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    expires_at DATETIME(3) NOT NULL,
+    used_at DATETIME(3) NULL,
+    UNIQUE INDEX idx_magic_links_token_hash (token_hash),
+    INDEX idx_magic_links_expires_at (expires_at),
+    CONSTRAINT fk_magic_links_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,14 @@
+CREATE TABLE one_time_codes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    destination VARCHAR(255) NOT NULL DEFAULT '',
+    code_hash CHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    expires_at DATETIME(3) NOT NULL,
+    used_at DATETIME(3) NULL,
+    INDEX idx_one_time_codes_user_purpose (user_id, purpose, id),
+    INDEX idx_one_time_codes_expires_at (expires_at),
+    CONSTRAINT fk_one_time_codes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
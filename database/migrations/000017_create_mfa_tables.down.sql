@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS totp_credentials;
+
+ALTER TABLE users
+    DROP COLUMN mfa_enabled;
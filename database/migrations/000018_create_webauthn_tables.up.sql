@@ -0,0 +1,28 @@
+CREATE TABLE webauthn_credentials (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    credential_id VARCHAR(1366) NOT NULL,
+    credential_id_hash CHAR(64) NOT NULL,
+    public_key BLOB NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(64) NOT NULL DEFAULT '',
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    last_used_at DATETIME(3) NULL,
+    UNIQUE INDEX idx_webauthn_credentials_credential_id_hash (credential_id_hash),
+    INDEX idx_webauthn_credentials_user_id (user_id),
+    CONSTRAINT fk_webauthn_credentials_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE webauthn_challenges (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    challenge_hash CHAR(64) NOT NULL,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    expires_at DATETIME(3) NOT NULL,
+    used_at DATETIME(3) NULL,
+    UNIQUE INDEX idx_webauthn_challenges_challenge_hash (challenge_hash),
+    INDEX idx_webauthn_challenges_expires_at (expires_at),
+    CONSTRAINT fk_webauthn_challenges_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
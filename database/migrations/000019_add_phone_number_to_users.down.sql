@@ -0,0 +1,4 @@
+DROP INDEX idx_one_time_codes_destination ON one_time_codes;
+
+ALTER TABLE users
+    DROP COLUMN phone_number;
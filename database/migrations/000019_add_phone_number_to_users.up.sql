@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN phone_number VARCHAR(16) NULL;
+
+CREATE INDEX idx_one_time_codes_destination ON one_time_codes (destination, created_at);
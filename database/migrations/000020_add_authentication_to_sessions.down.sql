@@ -0,0 +1,4 @@
+ALTER TABLE sessions
+    DROP COLUMN acr,
+    DROP COLUMN amr,
+    DROP COLUMN auth_time;
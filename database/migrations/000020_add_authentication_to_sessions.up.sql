@@ -0,0 +1,8 @@
+ALTER TABLE sessions
+    ADD COLUMN auth_time DATETIME(3) NULL AFTER ip_address,
+    ADD COLUMN amr VARCHAR(64) NOT NULL DEFAULT '' AFTER auth_time,
+    ADD COLUMN acr VARCHAR(16) NOT NULL DEFAULT '' AFTER amr;
+
+UPDATE sessions SET auth_time = created_at;
+
+ALTER TABLE sessions MODIFY auth_time DATETIME(3) NOT NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE trusted_devices (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    last_used_at DATETIME(3) NULL,
+    expires_at DATETIME(3) NOT NULL,
+    UNIQUE INDEX idx_trusted_devices_token_hash (token_hash),
+    INDEX idx_trusted_devices_user_id (user_id),
+    INDEX idx_trusted_devices_expires_at (expires_at),
+    CONSTRAINT fk_trusted_devices_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
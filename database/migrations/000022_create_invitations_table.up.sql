@@ -0,0 +1,14 @@
+CREATE TABLE invitations (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    code_hash CHAR(64) NOT NULL,
+    email VARCHAR(255) NULL,
+    role VARCHAR(32) NOT NULL DEFAULT 'user',
+    max_uses INT NOT NULL DEFAULT 1,
+    uses INT NOT NULL DEFAULT 0,
+    created_by INT NULL,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    expires_at DATETIME(3) NOT NULL,
+    revoked_at DATETIME(3) NULL,
+    UNIQUE INDEX idx_invitations_code_hash (code_hash),
+    CONSTRAINT fk_invitations_created_by FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
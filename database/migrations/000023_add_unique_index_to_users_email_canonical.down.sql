@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP INDEX idx_users_email_canonical,
+    ADD INDEX idx_users_email_canonical (email_canonical);
@@ -0,0 +1,5 @@
+-- Fails while two users have the same stored canonical email, `go run ./cmd/email-duplicates` lists them.
+-- Users whose emails only collide under the provider rules keep their previous canonical email, see the README.
+ALTER TABLE users
+    DROP INDEX idx_users_email_canonical,
+    ADD UNIQUE INDEX idx_users_email_canonical (email_canonical);
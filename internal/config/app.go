@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
@@ -20,31 +21,40 @@ type App struct {
 }
 
 func errorHandlerConfig(ctx *fiber.Ctx, err error) error {
-	code := fiber.StatusInternalServerError
-	var status string
+	response := models.ErrorResponse{Code: fiber.StatusInternalServerError, Message: "Something wrong"}
 	var e *fiber.Error
-	if errors.As(err, &e) {
-		code = e.Code
+	var errorResponse *models.ErrorResponse
+	if errors.As(err, &errorResponse) {
+		response = *errorResponse
+	} else if errors.As(err, &e) {
+		response.Code = e.Code
+		response.Message = e.Message
 	}
-	switch code {
+
+	switch response.Code {
 	case 400:
-		status = "Bad Request"
+		response.Status = "Bad Request"
 	case 401:
-		status = "Unauthorized"
+		response.Status = "Unauthorized"
 	case 403:
-		status = "Forbidden"
+		response.Status = "Forbidden"
 	case 404:
-		status = "Not Found"
+		response.Status = "Not Found"
 	case 408:
-		status = "Request Timeout"
+		response.Status = "Request Timeout"
+	case 409:
+		response.Status = "Conflict"
+	case 423:
+		response.Status = "Locked"
+	case 429:
+		response.Status = "Too Many Requests"
 	case 500:
-		status = "Internal Server Error"
+		response.Status = "Internal Server Error"
 
 	}
-	return ctx.Status(code).JSON(models.ErrorResponse{
-		Status:  status,
-		Message: e.Message,
-	})
+	code := response.Code
+	response.Code = 0
+	return ctx.Status(code).JSON(response)
 }
 
 func NewApp(viper *viper.Viper, validator *validator.Validate, database *gorm.DB) *App {
@@ -62,14 +72,31 @@ func (app *App) StartServer() {
 	err := app.Fiber.Listen(fmt.Sprintf(":%d", port))
 
 	if err != nil {
-		log.Fatal("Error connecting to server %v", err)
+		log.Fatalf("Error connecting to server %v", err)
 	}
 }
 
 func (app *App) Setup() {
-	signUpRoute := injector.InjectSignUpRoute(app.Fiber, app.database, app.validator)
+	signUpRoute := injector.InjectSignUpRoute(app.Fiber, app.database, app.validator, app.viper)
 	signUpRoute.Setup()
 
 	authRoute := injector.InjectAuthRoute(app.Fiber, app.database, app.validator, app.viper)
 	authRoute.Setup()
+
+	profileRoute := injector.InjectProfileRoute(app.Fiber, app.database, app.validator, app.viper)
+	profileRoute.Setup()
+
+	adminRoute := injector.InjectAdminRoute(app.Fiber, app.database, app.validator, app.viper)
+	adminRoute.Setup()
+
+	dataExportRoute := injector.InjectDataExportRoute(app.Fiber, app.database, app.validator, app.viper)
+	dataExportRoute.Setup()
+
+	mfaRoute := injector.InjectMfaRoute(app.Fiber, app.database, app.validator, app.viper)
+	mfaRoute.Setup()
+}
+
+func (app *App) StartWorkers(ctx context.Context) {
+	scheduler := injector.InjectScheduler(app.database, app.validator, app.viper)
+	scheduler.Start(ctx)
 }
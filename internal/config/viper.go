@@ -1,6 +1,14 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+// secretKeys are kept out of config.json, which only carries placeholders for them
+var secretKeys = []string{"key.otp", "key.mfa", "key.export", "key.trusted_device"}
 
 func NewViper(path string) *viper.Viper {
 	config := viper.New()
@@ -14,5 +22,41 @@ func NewViper(path string) *viper.Viper {
 		panic(err)
 	}
 
+	//AUTH_SECRETS_FILE points to a json file outside the repository, merged over config.json
+	secretsFile := os.Getenv("AUTH_SECRETS_FILE")
+	if secretsFile != "" {
+		file, err := os.Open(secretsFile)
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+		err = config.MergeConfig(file)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	//any key can also be set with an environment variable, key.otp with AUTH_KEY_OTP
+	config.SetEnvPrefix("auth")
+	config.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	config.AutomaticEnv()
+
 	return config
 }
+
+// CheckSecrets returns an error while a secret is empty or still the placeholder of config.json
+func CheckSecrets(viper *viper.Viper) error {
+	keys := append([]string{}, secretKeys...)
+	version := viper.GetInt("key.pepper.current_version")
+	if version != 0 {
+		keys = append(keys, fmt.Sprintf("key.pepper.secrets.%d", version))
+	}
+
+	for _, key := range keys {
+		secret := viper.GetString(key)
+		if secret == "" || strings.HasPrefix(secret, "<") {
+			return fmt.Errorf("%s is not set, use AUTH_SECRETS_FILE or AUTH_%s", key, strings.ToUpper(strings.ReplaceAll(key, ".", "_")))
+		}
+	}
+	return nil
+}
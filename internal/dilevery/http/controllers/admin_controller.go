@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+)
+
+type AdminController struct {
+	AdminUseCase *usecase.AdminUseCase
+}
+
+func NewAdminController(adminUseCase *usecase.AdminUseCase) *AdminController {
+	return &AdminController{
+		AdminUseCase: adminUseCase,
+	}
+}
+
+func (c *AdminController) RestoreUser(ctx *fiber.Ctx) error {
+	userID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(400, "id must be a number")
+	}
+
+	result, err := c.AdminUseCase.RestoreUser(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while restoring user: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.UserResponse]{Message: "User successfully restored", Data: result})
+}
+
+func (c *AdminController) UnlockUser(ctx *fiber.Ctx) error {
+	userID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(400, "id must be a number")
+	}
+
+	result, err := c.AdminUseCase.UnlockUser(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while unlocking user: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.UserResponse]{Message: "User successfully unlocked", Data: result})
+}
+
+func (c *AdminController) RequirePasswordChange(ctx *fiber.Ctx) error {
+	userID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(400, "id must be a number")
+	}
+
+	result, err := c.AdminUseCase.RequirePasswordChange(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while requiring password change: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.UserResponse]{Message: "User must change password at next sign in", Data: result})
+}
+
+func (c *AdminController) UpdateUserStatus(ctx *fiber.Ctx) error {
+	userID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(400, "id must be a number")
+	}
+
+	body := new(models.UpdateUserStatusRequest)
+	err = ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	result, err := c.AdminUseCase.UpdateUserStatus(ctx.Context(), userID, body)
+	if err != nil {
+		fmt.Println("Error while updating user status: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.UserResponse]{Message: "User status successfully updated", Data: result})
+}
@@ -27,32 +27,64 @@ func (c *AuthController) SignIn(ctx *fiber.Ctx) error {
 		return fiber.NewError(500, "Something wrong")
 	}
 
+	body.ClientInfo = clientInfo(ctx)
+
 	result, err := c.AuthUseCase.SignIn(ctx.Context(), body)
 	if err != nil {
 		fmt.Println("Error while sign in user: ", err)
-		if e := err.(*models.ErrorResponse); e != nil {
+		if e, ok := err.(*models.ErrorResponse); ok {
 			return fiber.NewError(e.Code, e.Message)
 		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return signInResponse(ctx, result)
+
+}
+
+// signInResponse sets the refresh token cookie and renders the result of a sign in, whichever way it happened
+func signInResponse(ctx *fiber.Ctx, result *models.SignInResponse) error {
+	if result.PasswordChangeRequired {
+		return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.SignInResponse]{
+			Message: "Password change required",
+			Data:    result,
+		})
+	}
+	if result.MfaRequired {
+		return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.SignInResponse]{
+			Message: "Two-factor authentication required",
+			Data:    result,
+		})
 	}
 
 	cookie := new(fiber.Cookie)
 	cookie.Name = "refresh_token"
 	cookie.Value = result.RefreshToken
-	cookie.Expires = time.Now().Add(3 * (24 * time.Hour))
+	cookie.Expires = time.Now().Add(usecase.RefreshTokenLifetime)
 	cookie.HTTPOnly = true
 
 	ctx.Cookie(cookie)
 
+	if result.TrustedDeviceToken != "" {
+		trustedDevice := new(fiber.Cookie)
+		trustedDevice.Name = usecase.TrustedDeviceCookie
+		trustedDevice.Value = result.TrustedDeviceToken
+		trustedDevice.Expires = result.TrustedDeviceExpiresAt
+		trustedDevice.HTTPOnly = true
+
+		ctx.Cookie(trustedDevice)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.SignInResponse]{
 		Message: "Sign in successfully",
 		Data:    result,
 	})
-
 }
 
 func (c *AuthController) GetToken(ctx *fiber.Ctx) error {
 	refreshToken := ctx.Cookies("refresh_token", "")
-	result, err := c.AuthUseCase.GetToken(refreshToken)
+	result, err := c.AuthUseCase.GetToken(ctx.Context(), refreshToken)
 
 	if err != nil {
 		if e := err.(*models.ErrorResponse); e != nil {
@@ -66,3 +98,12 @@ func (c *AuthController) GetToken(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusCreated).JSON(models.Response[*models.GetTokenResponse]{Message: "Token successfully generated", Data: result})
 
 }
+
+func clientInfo(ctx *fiber.Ctx) models.ClientInfo {
+	return models.ClientInfo{
+		IpAddress: ctx.IP(),
+		UserAgent: ctx.Get(fiber.HeaderUserAgent),
+		// only read when the user has MFA enabled, to skip the second factor
+		TrustedDeviceToken: ctx.Cookies(usecase.TrustedDeviceCookie, ""),
+	}
+}
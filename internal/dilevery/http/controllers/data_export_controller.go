@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/middleware"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+)
+
+type DataExportController struct {
+	DataExportUseCase *usecase.DataExportUseCase
+}
+
+func NewDataExportController(dataExportUseCase *usecase.DataExportUseCase) *DataExportController {
+	return &DataExportController{
+		DataExportUseCase: dataExportUseCase,
+	}
+}
+
+func (c *DataExportController) RequestExport(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	result, err := c.DataExportUseCase.RequestExport(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while requesting data export: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(models.Response[*models.DataExportResponse]{Message: "Data export queued", Data: result})
+}
+
+func (c *DataExportController) GetExport(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+	exportID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(400, "id must be a number")
+	}
+
+	result, err := c.DataExportUseCase.GetExport(ctx.Context(), userID, exportID)
+	if err != nil {
+		fmt.Println("Error while getting data export: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.DataExportResponse]{Message: "Data export successfully retrieved", Data: result})
+}
+
+// Download is reached through the signed link, so it does not need the access token
+func (c *DataExportController) Download(ctx *fiber.Ctx) error {
+	exportID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(400, "id must be a number")
+	}
+	expires := int64(ctx.QueryInt("expires"))
+
+	filePath, err := c.DataExportUseCase.OpenDownload(ctx.Context(), exportID, expires, ctx.Query("signature"))
+	if err != nil {
+		fmt.Println("Error while downloading data export: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.Download(filePath, fmt.Sprintf("data-export-%d.json", exportID))
+}
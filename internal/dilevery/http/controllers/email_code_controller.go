@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+)
+
+type EmailCodeController struct {
+	EmailCodeUseCase *usecase.EmailCodeUseCase
+}
+
+func NewEmailCodeController(emailCodeUseCase *usecase.EmailCodeUseCase) *EmailCodeController {
+	return &EmailCodeController{
+		EmailCodeUseCase: emailCodeUseCase,
+	}
+}
+
+func (c *EmailCodeController) RequestCode(ctx *fiber.Ctx) error {
+	body := new(models.EmailCodeRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	err = c.EmailCodeUseCase.RequestCode(ctx.Context(), body)
+	if err != nil {
+		fmt.Println("Error while requesting email code: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(models.Response[any]{Message: "If the email belongs to an account, a sign in code is on its way"})
+}
+
+func (c *EmailCodeController) SignIn(ctx *fiber.Ctx) error {
+	body := new(models.EmailCodeSignInRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	body.ClientInfo = clientInfo(ctx)
+
+	result, err := c.EmailCodeUseCase.SignIn(ctx.Context(), body)
+	if err != nil {
+		fmt.Println("Error while sign in with email code: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return signInResponse(ctx, result)
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/middleware"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+)
+
+type InvitationController struct {
+	InvitationUseCase *usecase.InvitationUseCase
+}
+
+func NewInvitationController(invitationUseCase *usecase.InvitationUseCase) *InvitationController {
+	return &InvitationController{
+		InvitationUseCase: invitationUseCase,
+	}
+}
+
+func (c *InvitationController) CreateInvitation(ctx *fiber.Ctx) error {
+	adminID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.CreateInvitationRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	result, err := c.InvitationUseCase.CreateInvitation(ctx.Context(), adminID, body)
+	if err != nil {
+		fmt.Println("Error while creating invitation: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(models.Response[*models.InvitationResponse]{Message: "Invitation successfully created", Data: result})
+}
+
+func (c *InvitationController) GetInvitations(ctx *fiber.Ctx) error {
+	page := models.PageRequest{}
+	err := ctx.QueryParser(&page)
+	if err != nil {
+		return fiber.NewError(400, "page and size must be numbers")
+	}
+	page.Normalize()
+
+	result, total, err := c.InvitationUseCase.GetInvitations(ctx.Context(), page)
+	if err != nil {
+		fmt.Println("Error while getting invitations: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[[]*models.InvitationResponse]{
+		Message:    "Invitations successfully retrieved",
+		Data:       result,
+		Pagination: helpers.NewPaginationMetaData(ctx.Path(), page, total),
+	})
+}
+
+func (c *InvitationController) RevokeInvitation(ctx *fiber.Ctx) error {
+	invitationID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(400, "id must be a number")
+	}
+
+	err = c.InvitationUseCase.RevokeInvitation(ctx.Context(), invitationID)
+	if err != nil {
+		fmt.Println("Error while revoking invitation: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[any]{Message: "Invitation successfully revoked"})
+}
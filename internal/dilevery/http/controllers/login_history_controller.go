@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/middleware"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"net/url"
+	"strconv"
+)
+
+type LoginHistoryController struct {
+	LoginHistoryUseCase *usecase.LoginHistoryUseCase
+}
+
+func NewLoginHistoryController(loginHistoryUseCase *usecase.LoginHistoryUseCase) *LoginHistoryController {
+	return &LoginHistoryController{
+		LoginHistoryUseCase: loginHistoryUseCase,
+	}
+}
+
+func (c *LoginHistoryController) GetLoginHistory(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	page := models.PageRequest{}
+	err := ctx.QueryParser(&page)
+	if err != nil {
+		return fiber.NewError(400, "page and size must be numbers")
+	}
+	page.Normalize()
+
+	result, total, err := c.LoginHistoryUseCase.GetLoginHistory(ctx.Context(), userID, page)
+	if err != nil {
+		fmt.Println("Error while getting login history: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[[]*models.LoginAttemptResponse]{
+		Message:    "Login history successfully retrieved",
+		Data:       result,
+		Pagination: helpers.NewPaginationMetaData(ctx.Path(), page, total),
+	})
+}
+
+func (c *LoginHistoryController) SearchLoginHistory(ctx *fiber.Ctx) error {
+	page := models.PageRequest{}
+	err := ctx.QueryParser(&page)
+	if err != nil {
+		return fiber.NewError(400, "page and size must be numbers")
+	}
+	page.Normalize()
+
+	query := new(models.LoginHistoryQuery)
+	err = ctx.QueryParser(query)
+	if err != nil {
+		return fiber.NewError(400, "user_id must be a number")
+	}
+
+	result, total, err := c.LoginHistoryUseCase.SearchLoginHistory(ctx.Context(), query, page)
+	if err != nil {
+		fmt.Println("Error while searching login history: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[[]*models.LoginAttemptResponse]{
+		Message:    "Login history successfully retrieved",
+		Data:       result,
+		Pagination: helpers.NewPaginationMetaData(loginHistorySearchPath(ctx.Path(), query), page, total),
+	})
+}
+
+// loginHistorySearchPath keeps the filters in the previous and next page links
+func loginHistorySearchPath(path string, query *models.LoginHistoryQuery) string {
+	values := url.Values{}
+	if query.UserId != 0 {
+		values.Set("user_id", strconv.Itoa(query.UserId))
+	}
+	if query.IpAddress != "" {
+		values.Set("ip_address", query.IpAddress)
+	}
+	if query.Outcome != "" {
+		values.Set("outcome", query.Outcome)
+	}
+	if query.From != "" {
+		values.Set("from", query.From)
+	}
+	if query.To != "" {
+		values.Set("to", query.To)
+	}
+
+	if len(values) == 0 {
+		return path
+	}
+	return path + "?" + values.Encode()
+}
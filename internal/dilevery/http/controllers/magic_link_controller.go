@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"time"
+)
+
+// magicLinkBrowserCookie holds the secret binding a magic link to the browser that requested it
+const magicLinkBrowserCookie = "magic_link_browser"
+
+type MagicLinkController struct {
+	MagicLinkUseCase *usecase.MagicLinkUseCase
+}
+
+func NewMagicLinkController(magicLinkUseCase *usecase.MagicLinkUseCase) *MagicLinkController {
+	return &MagicLinkController{
+		MagicLinkUseCase: magicLinkUseCase,
+	}
+}
+
+func (c *MagicLinkController) RequestMagicLink(ctx *fiber.Ctx) error {
+	body := new(models.MagicLinkRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	body.ClientInfo = clientInfo(ctx)
+
+	browserSecret, err := c.MagicLinkUseCase.RequestMagicLink(ctx.Context(), body)
+	if err != nil {
+		fmt.Println("Error while requesting magic link: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	ctx.Cookie(&fiber.Cookie{
+		Name:     magicLinkBrowserCookie,
+		Value:    browserSecret,
+		Path:     "/auth/magic-link",
+		Expires:  time.Now().Add(c.MagicLinkUseCase.TTL()),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return ctx.Status(fiber.StatusAccepted).JSON(models.Response[any]{Message: "If the email belongs to an account, a sign in link is on its way"})
+}
+
+func (c *MagicLinkController) RedeemMagicLink(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	result, err := c.MagicLinkUseCase.RedeemMagicLink(ctx.Context(), ctx.Query("token"), ctx.Cookies(magicLinkBrowserCookie), clientInfo(ctx))
+	if err != nil {
+		fmt.Println("Error while redeeming magic link: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	ctx.ClearCookie(magicLinkBrowserCookie)
+
+	return signInResponse(ctx, result)
+}
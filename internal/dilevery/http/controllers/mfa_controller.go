@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/middleware"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+)
+
+type MfaController struct {
+	MfaUseCase *usecase.MfaUseCase
+}
+
+func NewMfaController(mfaUseCase *usecase.MfaUseCase) *MfaController {
+	return &MfaController{
+		MfaUseCase: mfaUseCase,
+	}
+}
+
+func (c *MfaController) EnrollTotp(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	result, err := c.MfaUseCase.EnrollTotp(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while enrolling totp: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.TotpEnrollmentResponse]{Message: "Scan the code with your authenticator app and confirm it", Data: result})
+}
+
+func (c *MfaController) ConfirmTotp(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.MfaCodeRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	result, err := c.MfaUseCase.ConfirmTotp(ctx.Context(), userID, body)
+	if err != nil {
+		fmt.Println("Error while confirming totp: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.RecoveryCodesResponse]{Message: "Two-factor authentication enabled", Data: result})
+}
+
+func (c *MfaController) DisableTotp(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.MfaCodeRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	err = c.MfaUseCase.DisableTotp(ctx.Context(), userID, body)
+	if err != nil {
+		fmt.Println("Error while disabling totp: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[any]{Message: "Two-factor authentication disabled"})
+}
+
+func (c *MfaController) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.MfaCodeRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	result, err := c.MfaUseCase.RegenerateRecoveryCodes(ctx.Context(), userID, body)
+	if err != nil {
+		fmt.Println("Error while regenerating recovery codes: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.RecoveryCodesResponse]{Message: "Recovery codes regenerated", Data: result})
+}
+
+func (c *MfaController) SignIn(ctx *fiber.Ctx) error {
+	body := new(models.MfaSignInRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	body.ClientInfo = clientInfo(ctx)
+
+	result, err := c.MfaUseCase.SignIn(ctx.Context(), body)
+	if err != nil {
+		fmt.Println("Error while sign in with second factor: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return signInResponse(ctx, result)
+}
+
+func (c *MfaController) EnrollPhone(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.PhoneEnrollmentRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	result, err := c.MfaUseCase.EnrollPhone(ctx.Context(), userID, body)
+	if err != nil {
+		fmt.Println("Error while enrolling phone number: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.SmsCodeResponse]{Message: "We texted a code to your phone, please confirm it", Data: result})
+}
+
+func (c *MfaController) ConfirmPhone(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.MfaCodeRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	result, err := c.MfaUseCase.ConfirmPhone(ctx.Context(), userID, body)
+	if err != nil {
+		fmt.Println("Error while confirming phone number: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.PhoneEnrollmentResponse]{Message: "Phone number verified", Data: result})
+}
+
+func (c *MfaController) RemovePhone(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.MfaCodeRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	err = c.MfaUseCase.RemovePhone(ctx.Context(), userID, body)
+	if err != nil {
+		fmt.Println("Error while removing phone number: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[any]{Message: "Phone number removed"})
+}
+
+func (c *MfaController) SendSignInCode(ctx *fiber.Ctx) error {
+	body := new(models.MfaSmsRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	result, err := c.MfaUseCase.SendSignInCode(ctx.Context(), body)
+	if err != nil {
+		fmt.Println("Error while sending sms code: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.SmsCodeResponse]{Message: "We texted a code to your phone", Data: result})
+}
+
+func (c *MfaController) Reauthenticate(ctx *fiber.Ctx) error {
+	claims := ctx.Locals(middleware.AccessClaimsKey).(*usecase.AccessClaims)
+
+	body := new(models.ReauthenticateRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	body.ClientInfo = clientInfo(ctx)
+
+	result, err := c.MfaUseCase.Reauthenticate(ctx.Context(), claims, body)
+	if err != nil {
+		fmt.Println("Error while re-authenticating: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.GetTokenResponse]{Message: "Authenticated again", Data: result})
+}
+
+func (c *MfaController) SendReauthenticationCode(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	result, err := c.MfaUseCase.SendReauthenticationCode(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while sending sms code: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.SmsCodeResponse]{Message: "We texted a code to your phone", Data: result})
+}
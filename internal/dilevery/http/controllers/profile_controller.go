@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/middleware"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+)
+
+type ProfileController struct {
+	ProfileUseCase *usecase.ProfileUseCase
+}
+
+func NewProfileController(profileUseCase *usecase.ProfileUseCase) *ProfileController {
+	return &ProfileController{
+		ProfileUseCase: profileUseCase,
+	}
+}
+
+func (c *ProfileController) GetProfile(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	result, err := c.ProfileUseCase.GetProfile(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while getting profile: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.UserResponse]{Message: "Profile successfully retrieved", Data: result})
+}
+
+func (c *ProfileController) UpdateProfile(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.UpdateUserRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	result, err := c.ProfileUseCase.UpdateProfile(ctx.Context(), userID, body)
+	if err != nil {
+		fmt.Println("Error while updating profile: ", err)
+		//keeps the errors details, like the broken email domain rule
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return e
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.UserResponse]{Message: "Profile successfully updated", Data: result})
+}
+
+// ChangesEmail matches the profile updates that change the email, for middleware.When
+func (c *ProfileController) ChangesEmail(ctx *fiber.Ctx) bool {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.UpdateUserRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		//UpdateProfile refuses the body anyway
+		return false
+	}
+
+	return c.ProfileUseCase.ChangesEmail(ctx.Context(), userID, body.Email)
+}
+
+func (c *ProfileController) ChangePassword(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.ChangePasswordRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	err = c.ProfileUseCase.ChangePassword(ctx.Context(), userID, body)
+	if err != nil {
+		fmt.Println("Error while changing password: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			//the error handler renders the detailed errors as well
+			return e
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[any]{Message: "Password successfully changed"})
+}
+
+func (c *ProfileController) DeleteAccount(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	result, err := c.ProfileUseCase.DeleteAccount(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while deleting account: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	ctx.ClearCookie("refresh_token")
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.AccountDeletionResponse]{Message: "Account deletion requested", Data: result})
+}
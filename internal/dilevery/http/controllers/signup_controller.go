@@ -29,8 +29,9 @@ func (c *UserController) Register(ctx *fiber.Ctx) error {
 
 	if err != nil {
 		fmt.Println("Error while creating user: ", err)
-		if e := err.(*models.ErrorResponse); e != nil {
-			return fiber.NewError(e.Code, e.Message)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			//the error handler renders the detailed errors as well
+			return e
 		}
 
 		return fiber.NewError(500, "Something wrong with our server!")
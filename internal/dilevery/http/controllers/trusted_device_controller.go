@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/middleware"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"time"
+)
+
+type TrustedDeviceController struct {
+	TrustedDeviceUseCase *usecase.TrustedDeviceUseCase
+}
+
+func NewTrustedDeviceController(trustedDeviceUseCase *usecase.TrustedDeviceUseCase) *TrustedDeviceController {
+	return &TrustedDeviceController{
+		TrustedDeviceUseCase: trustedDeviceUseCase,
+	}
+}
+
+func (c *TrustedDeviceController) GetDevices(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	result, err := c.TrustedDeviceUseCase.GetDevices(ctx.Context(), userID, ctx.Cookies(usecase.TrustedDeviceCookie, ""))
+	if err != nil {
+		fmt.Println("Error while getting trusted devices: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[[]models.TrustedDeviceResponse]{Message: "Trusted devices successfully retrieved", Data: result})
+}
+
+func (c *TrustedDeviceController) RevokeDevice(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+	deviceID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(400, "id must be a number")
+	}
+
+	err = c.TrustedDeviceUseCase.RevokeDevice(ctx.Context(), userID, deviceID)
+	if err != nil {
+		fmt.Println("Error while revoking trusted device: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[any]{Message: "Trusted device successfully revoked"})
+}
+
+func (c *TrustedDeviceController) RevokeAllDevices(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	err := c.TrustedDeviceUseCase.RevokeAllDevices(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while revoking trusted devices: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	//the cookie of this device is useless now
+	ctx.Cookie(&fiber.Cookie{
+		Name:     usecase.TrustedDeviceCookie,
+		Expires:  time.Now().Add(-time.Hour),
+		HTTPOnly: true,
+	})
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[any]{Message: "Trusted devices successfully revoked"})
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/middleware"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type UserImportController struct {
+	UserImportUseCase *usecase.UserImportUseCase
+}
+
+func NewUserImportController(userImportUseCase *usecase.UserImportUseCase) *UserImportController {
+	return &UserImportController{
+		UserImportUseCase: userImportUseCase,
+	}
+}
+
+// CreateImport accepts the file either as the "file" field of a multipart form or as the raw request body
+func (c *UserImportController) CreateImport(ctx *fiber.Ctx) error {
+	adminID := ctx.Locals(middleware.UserIDKey).(int)
+	format := ctx.Query("format")
+
+	var source io.Reader
+	fileHeader, err := ctx.FormFile("file")
+	if err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			fmt.Println("Error while opening uploaded file: ", err)
+			return fiber.NewError(500, "Something wrong")
+		}
+		defer file.Close()
+
+		source = file
+		if format == "" {
+			format = strings.TrimPrefix(filepath.Ext(fileHeader.Filename), ".")
+		}
+	} else {
+		source = bytes.NewReader(ctx.Body())
+		if format == "" {
+			format = importFormatFromContentType(ctx.Get(fiber.HeaderContentType))
+		}
+	}
+
+	result, err := c.UserImportUseCase.CreateImport(ctx.Context(), format, source, &adminID)
+	if err != nil {
+		fmt.Println("Error while creating user import: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(models.Response[*models.UserImportJobResponse]{Message: "User import queued", Data: result})
+}
+
+func (c *UserImportController) GetImport(ctx *fiber.Ctx) error {
+	jobID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(400, "id must be a number")
+	}
+
+	page := models.PageRequest{}
+	err = ctx.QueryParser(&page)
+	if err != nil {
+		return fiber.NewError(400, "page and size must be numbers")
+	}
+	page.Normalize()
+
+	result, total, err := c.UserImportUseCase.GetImport(ctx.Context(), jobID, page)
+	if err != nil {
+		fmt.Println("Error while getting user import: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.UserImportJobResponse]{
+		Message:    "User import successfully retrieved",
+		Data:       result,
+		Pagination: helpers.NewPaginationMetaData(ctx.Path(), page, total),
+	})
+}
+
+func importFormatFromContentType(contentType string) string {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return entity.UserImportFormatCSV
+	case "application/jsonl", "application/x-ndjson", "application/x-jsonlines":
+		return entity.UserImportFormatJSONL
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/middleware"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+)
+
+type WebAuthnController struct {
+	WebAuthnUseCase *usecase.WebAuthnUseCase
+}
+
+func NewWebAuthnController(webAuthnUseCase *usecase.WebAuthnUseCase) *WebAuthnController {
+	return &WebAuthnController{
+		WebAuthnUseCase: webAuthnUseCase,
+	}
+}
+
+func (c *WebAuthnController) BeginRegistration(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	result, err := c.WebAuthnUseCase.BeginRegistration(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while starting passkey registration: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.WebAuthnCreationOptions]{Message: "Create the passkey with these options", Data: result})
+}
+
+func (c *WebAuthnController) FinishRegistration(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	body := new(models.WebAuthnRegistrationRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	result, err := c.WebAuthnUseCase.FinishRegistration(ctx.Context(), userID, body)
+	if err != nil {
+		fmt.Println("Error while registering passkey: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(models.Response[*models.WebAuthnRegistrationResponse]{Message: "Passkey successfully registered", Data: result})
+}
+
+func (c *WebAuthnController) GetCredentials(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+
+	result, err := c.WebAuthnUseCase.GetCredentials(ctx.Context(), userID)
+	if err != nil {
+		fmt.Println("Error while getting passkeys: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[[]models.WebAuthnCredentialResponse]{Message: "Passkeys successfully retrieved", Data: result})
+}
+
+func (c *WebAuthnController) DeleteCredential(ctx *fiber.Ctx) error {
+	userID := ctx.Locals(middleware.UserIDKey).(int)
+	credentialID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(400, "id must be a number")
+	}
+
+	err = c.WebAuthnUseCase.DeleteCredential(ctx.Context(), userID, credentialID)
+	if err != nil {
+		fmt.Println("Error while deleting passkey: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[any]{Message: "Passkey successfully deleted"})
+}
+
+func (c *WebAuthnController) BeginSignIn(ctx *fiber.Ctx) error {
+	body := new(models.WebAuthnSignInOptionsRequest)
+	//the body is optional, a passwordless sign in sends none
+	if len(ctx.Body()) > 0 {
+		err := ctx.BodyParser(body)
+		if err != nil {
+			fmt.Println("Error parsing body ", err)
+			return fiber.NewError(500, "Something wrong")
+		}
+	}
+
+	result, err := c.WebAuthnUseCase.BeginSignIn(ctx.Context(), body)
+	if err != nil {
+		fmt.Println("Error while starting passkey sign in: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.WebAuthnRequestOptions]{Message: "Sign in with a passkey using these options", Data: result})
+}
+
+func (c *WebAuthnController) SignIn(ctx *fiber.Ctx) error {
+	body := new(models.WebAuthnSignInRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	body.ClientInfo = clientInfo(ctx)
+
+	result, err := c.WebAuthnUseCase.SignIn(ctx.Context(), body)
+	if err != nil {
+		fmt.Println("Error while sign in with passkey: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return signInResponse(ctx, result)
+}
+
+func (c *WebAuthnController) BeginReauthentication(ctx *fiber.Ctx) error {
+	claims := ctx.Locals(middleware.AccessClaimsKey).(*usecase.AccessClaims)
+
+	result, err := c.WebAuthnUseCase.BeginReauthentication(ctx.Context(), claims)
+	if err != nil {
+		fmt.Println("Error while starting passkey re-authentication: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.WebAuthnRequestOptions]{Message: "Authenticate with a passkey using these options", Data: result})
+}
+
+func (c *WebAuthnController) Reauthenticate(ctx *fiber.Ctx) error {
+	claims := ctx.Locals(middleware.AccessClaimsKey).(*usecase.AccessClaims)
+
+	body := new(models.WebAuthnReauthenticateRequest)
+	err := ctx.BodyParser(body)
+	if err != nil {
+		fmt.Println("Error parsing body ", err)
+		return fiber.NewError(500, "Something wrong")
+	}
+
+	body.ClientInfo = clientInfo(ctx)
+
+	result, err := c.WebAuthnUseCase.Reauthenticate(ctx.Context(), claims, body)
+	if err != nil {
+		fmt.Println("Error while re-authenticating with passkey: ", err)
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+
+		return fiber.NewError(500, "Something wrong with our server!")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.GetTokenResponse]{Message: "Authenticated again", Data: result})
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"strings"
+	"time"
+)
+
+const UserIDKey = "userId"
+
+// AccessClaimsKey holds the *usecase.AccessClaims of the access token in ctx.Locals
+const AccessClaimsKey = "accessClaims"
+
+type AuthMiddleware struct {
+	AuthUseCase *usecase.AuthUseCase
+}
+
+func NewAuthMiddleware(authUseCase *usecase.AuthUseCase) *AuthMiddleware {
+	return &AuthMiddleware{
+		AuthUseCase: authUseCase,
+	}
+}
+
+// Authenticate verifies the bearer access token, its session and the status of its user, and stores the user id and
+// the claims in ctx.Locals
+func (m *AuthMiddleware) Authenticate(ctx *fiber.Ctx) error {
+	claims, err := m.AuthUseCase.VerifyAccessClaims(bearerToken(ctx))
+	if err == nil {
+		err = m.AuthUseCase.CheckSession(ctx.Context(), claims)
+	}
+	if err != nil {
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+		return fiber.NewError(401, "Invalid token")
+	}
+
+	ctx.Locals(UserIDKey, claims.UserId)
+	ctx.Locals(AccessClaimsKey, claims)
+	return ctx.Next()
+}
+
+// AuthenticatePasswordChange works like Authenticate, but also accepts the password change token returned by sign in.
+// That token has no session, only the status of its user is checked
+func (m *AuthMiddleware) AuthenticatePasswordChange(ctx *fiber.Ctx) error {
+	userID, err := m.AuthUseCase.VerifyPasswordChangeAccess(ctx.Context(), bearerToken(ctx))
+	if err != nil {
+		if e, ok := err.(*models.ErrorResponse); ok {
+			return fiber.NewError(e.Code, e.Message)
+		}
+		return fiber.NewError(401, "Invalid token")
+	}
+
+	ctx.Locals(UserIDKey, userID)
+	return ctx.Next()
+}
+
+func bearerToken(ctx *fiber.Ctx) string {
+	authorization := ctx.Get(fiber.HeaderAuthorization)
+	if strings.HasPrefix(authorization, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	}
+	return ""
+}
+
+// RequireRole must be registered after Authenticate
+func (m *AuthMiddleware) RequireRole(role string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		userID := ctx.Locals(UserIDKey).(int)
+
+		err := m.AuthUseCase.Authorize(ctx.Context(), userID, role)
+		if err != nil {
+			if e, ok := err.(*models.ErrorResponse); ok {
+				return fiber.NewError(e.Code, e.Message)
+			}
+			return fiber.NewError(500, "Something wrong")
+		}
+
+		return ctx.Next()
+	}
+}
+
+// RequireStepUp must be registered after Authenticate. It sends the client to POST /auth/reauthenticate when the
+// session authenticated more than maxAge ago, or below the acr level. A zero maxAge or an empty acr is not checked
+func (m *AuthMiddleware) RequireStepUp(maxAge time.Duration, acr string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		claims := ctx.Locals(AccessClaimsKey).(*usecase.AccessClaims)
+
+		err := m.AuthUseCase.CheckStepUp(claims, maxAge, acr)
+		if err != nil {
+			return stepUpError(ctx, err, maxAge, acr)
+		}
+
+		return ctx.Next()
+	}
+}
+
+// RequireMfaStepUp must be registered after Authenticate. Once the user has turned two-factor authentication on, it
+// sends a single factor session to POST /auth/reauthenticate
+func (m *AuthMiddleware) RequireMfaStepUp(ctx *fiber.Ctx) error {
+	claims := ctx.Locals(AccessClaimsKey).(*usecase.AccessClaims)
+
+	err := m.AuthUseCase.CheckMfaStepUp(ctx.Context(), claims)
+	if err != nil {
+		return stepUpError(ctx, err, 0, usecase.AcrMultiFactor)
+	}
+
+	return ctx.Next()
+}
+
+func stepUpError(ctx *fiber.Ctx, err error, maxAge time.Duration, acr string) error {
+	//the challenge of RFC 9470, so OAuth clients know what to ask for
+	challenge := `Bearer error="insufficient_user_authentication"`
+	if maxAge > 0 {
+		challenge += fmt.Sprintf(`, max_age=%d`, int(maxAge.Seconds()))
+	}
+	if acr != "" {
+		challenge += fmt.Sprintf(`, acr_values="%s"`, acr)
+	}
+	ctx.Set(fiber.HeaderWWWAuthenticate, challenge)
+
+	if e, ok := err.(*models.ErrorResponse); ok {
+		return fiber.NewError(e.Code, e.Message)
+	}
+	return fiber.NewError(500, "Something wrong")
+}
+
+// When runs handler only for the requests matching condition, the others go straight to the next handler
+func When(condition func(ctx *fiber.Ctx) bool, handler fiber.Handler) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if !condition(ctx) {
+			return ctx.Next()
+		}
+		return handler(ctx)
+	}
+}
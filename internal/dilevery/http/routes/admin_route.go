@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/controllers"
+	"golang-authentication/internal/dilevery/http/middleware"
+	"golang-authentication/internal/entity"
+)
+
+type AdminRoute struct {
+	App                    *fiber.App
+	AdminController        *controllers.AdminController
+	UserImportController   *controllers.UserImportController
+	LoginHistoryController *controllers.LoginHistoryController
+	InvitationController   *controllers.InvitationController
+	AuthMiddleware         *middleware.AuthMiddleware
+}
+
+func NewAdminRoute(app *fiber.App, controller *controllers.AdminController, userImportController *controllers.UserImportController, loginHistoryController *controllers.LoginHistoryController, invitationController *controllers.InvitationController, authMiddleware *middleware.AuthMiddleware) *AdminRoute {
+	return &AdminRoute{
+		App:                    app,
+		AdminController:        controller,
+		UserImportController:   userImportController,
+		LoginHistoryController: loginHistoryController,
+		InvitationController:   invitationController,
+		AuthMiddleware:         authMiddleware,
+	}
+}
+
+func (r *AdminRoute) Setup() {
+	admin := r.App.Group("/admin", r.AuthMiddleware.Authenticate, r.AuthMiddleware.RequireRole(entity.RoleAdmin))
+	admin.Post("/users/:id/restore", r.AdminController.RestoreUser)
+	admin.Post("/users/:id/unlock", r.AdminController.UnlockUser)
+	admin.Patch("/users/:id/status", r.AdminController.UpdateUserStatus)
+	admin.Post("/users/:id/require-password-change", r.AdminController.RequirePasswordChange)
+	admin.Post("/users/import", r.UserImportController.CreateImport)
+	admin.Get("/users/imports/:id", r.UserImportController.GetImport)
+	admin.Get("/login-history", r.LoginHistoryController.SearchLoginHistory)
+	admin.Post("/invitations", r.InvitationController.CreateInvitation)
+	admin.Get("/invitations", r.InvitationController.GetInvitations)
+	admin.Delete("/invitations/:id", r.InvitationController.RevokeInvitation)
+}
@@ -6,18 +6,26 @@ import (
 )
 
 type AuthRoute struct {
-	App            *fiber.App
-	AuthController *controllers.AuthController
+	App                 *fiber.App
+	AuthController      *controllers.AuthController
+	MagicLinkController *controllers.MagicLinkController
+	EmailCodeController *controllers.EmailCodeController
 }
 
-func NewAuthRoute(app *fiber.App, controller *controllers.AuthController) *AuthRoute {
+func NewAuthRoute(app *fiber.App, controller *controllers.AuthController, magicLinkController *controllers.MagicLinkController, emailCodeController *controllers.EmailCodeController) *AuthRoute {
 	return &AuthRoute{
-		App:            app,
-		AuthController: controller,
+		App:                 app,
+		AuthController:      controller,
+		MagicLinkController: magicLinkController,
+		EmailCodeController: emailCodeController,
 	}
 }
 
 func (r *AuthRoute) Setup() {
 	r.App.Post("/auth", r.AuthController.SignIn)
 	r.App.Get("/auth/token", r.AuthController.GetToken)
+	r.App.Post("/auth/magic-link", r.MagicLinkController.RequestMagicLink)
+	r.App.Get("/auth/magic-link/verify", r.MagicLinkController.RedeemMagicLink)
+	r.App.Post("/auth/email-code", r.EmailCodeController.RequestCode)
+	r.App.Post("/auth/email-code/verify", r.EmailCodeController.SignIn)
 }
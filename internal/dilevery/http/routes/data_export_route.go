@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/controllers"
+	"golang-authentication/internal/dilevery/http/middleware"
+)
+
+type DataExportRoute struct {
+	App                  *fiber.App
+	DataExportController *controllers.DataExportController
+	AuthMiddleware       *middleware.AuthMiddleware
+}
+
+func NewDataExportRoute(app *fiber.App, controller *controllers.DataExportController, authMiddleware *middleware.AuthMiddleware) *DataExportRoute {
+	return &DataExportRoute{
+		App:                  app,
+		DataExportController: controller,
+		AuthMiddleware:       authMiddleware,
+	}
+}
+
+func (r *DataExportRoute) Setup() {
+	r.App.Post("/me/exports", r.AuthMiddleware.Authenticate, r.DataExportController.RequestExport)
+	r.App.Get("/me/exports/:id", r.AuthMiddleware.Authenticate, r.DataExportController.GetExport)
+	r.App.Get("/exports/:id/download", r.DataExportController.Download)
+}
@@ -0,0 +1,53 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/controllers"
+	"golang-authentication/internal/dilevery/http/middleware"
+)
+
+type MfaRoute struct {
+	App                     *fiber.App
+	MfaController           *controllers.MfaController
+	WebAuthnController      *controllers.WebAuthnController
+	TrustedDeviceController *controllers.TrustedDeviceController
+	AuthMiddleware          *middleware.AuthMiddleware
+	// StepUp guards registering and deleting a passkey, see AuthMiddleware.RequireStepUp
+	StepUp fiber.Handler
+}
+
+func NewMfaRoute(app *fiber.App, controller *controllers.MfaController, webAuthnController *controllers.WebAuthnController, trustedDeviceController *controllers.TrustedDeviceController, authMiddleware *middleware.AuthMiddleware, stepUp fiber.Handler) *MfaRoute {
+	return &MfaRoute{
+		App:                     app,
+		MfaController:           controller,
+		WebAuthnController:      webAuthnController,
+		TrustedDeviceController: trustedDeviceController,
+		AuthMiddleware:          authMiddleware,
+		StepUp:                  stepUp,
+	}
+}
+
+func (r *MfaRoute) Setup() {
+	r.App.Post("/auth/mfa", r.MfaController.SignIn)
+	r.App.Post("/me/mfa/totp", r.AuthMiddleware.Authenticate, r.MfaController.EnrollTotp)
+	r.App.Post("/me/mfa/totp/confirm", r.AuthMiddleware.Authenticate, r.MfaController.ConfirmTotp)
+	r.App.Delete("/me/mfa/totp", r.AuthMiddleware.Authenticate, r.MfaController.DisableTotp)
+	r.App.Post("/me/mfa/recovery-codes", r.AuthMiddleware.Authenticate, r.MfaController.RegenerateRecoveryCodes)
+	r.App.Post("/auth/mfa/sms", r.MfaController.SendSignInCode)
+	r.App.Post("/auth/reauthenticate", r.AuthMiddleware.Authenticate, r.MfaController.Reauthenticate)
+	r.App.Post("/auth/reauthenticate/sms", r.AuthMiddleware.Authenticate, r.MfaController.SendReauthenticationCode)
+	r.App.Post("/auth/reauthenticate/webauthn", r.AuthMiddleware.Authenticate, r.WebAuthnController.BeginReauthentication)
+	r.App.Post("/auth/reauthenticate/webauthn/verify", r.AuthMiddleware.Authenticate, r.WebAuthnController.Reauthenticate)
+	r.App.Post("/me/mfa/sms", r.AuthMiddleware.Authenticate, r.MfaController.EnrollPhone)
+	r.App.Post("/me/mfa/sms/confirm", r.AuthMiddleware.Authenticate, r.MfaController.ConfirmPhone)
+	r.App.Delete("/me/mfa/sms", r.AuthMiddleware.Authenticate, r.MfaController.RemovePhone)
+	r.App.Post("/auth/webauthn", r.WebAuthnController.BeginSignIn)
+	r.App.Post("/auth/webauthn/verify", r.WebAuthnController.SignIn)
+	r.App.Post("/me/webauthn/registration", r.AuthMiddleware.Authenticate, r.StepUp, r.AuthMiddleware.RequireMfaStepUp, r.WebAuthnController.BeginRegistration)
+	r.App.Post("/me/webauthn/registration/verify", r.AuthMiddleware.Authenticate, r.StepUp, r.AuthMiddleware.RequireMfaStepUp, r.WebAuthnController.FinishRegistration)
+	r.App.Get("/me/webauthn/credentials", r.AuthMiddleware.Authenticate, r.WebAuthnController.GetCredentials)
+	r.App.Delete("/me/webauthn/credentials/:id", r.AuthMiddleware.Authenticate, r.StepUp, r.WebAuthnController.DeleteCredential)
+	r.App.Get("/me/trusted-devices", r.AuthMiddleware.Authenticate, r.TrustedDeviceController.GetDevices)
+	r.App.Delete("/me/trusted-devices/:id", r.AuthMiddleware.Authenticate, r.TrustedDeviceController.RevokeDevice)
+	r.App.Delete("/me/trusted-devices", r.AuthMiddleware.Authenticate, r.TrustedDeviceController.RevokeAllDevices)
+}
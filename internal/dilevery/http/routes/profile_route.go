@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"golang-authentication/internal/dilevery/http/controllers"
+	"golang-authentication/internal/dilevery/http/middleware"
+)
+
+type ProfileRoute struct {
+	App                    *fiber.App
+	ProfileController      *controllers.ProfileController
+	LoginHistoryController *controllers.LoginHistoryController
+	AuthMiddleware         *middleware.AuthMiddleware
+	// StepUp guards deleting the account and changing the email, see AuthMiddleware.RequireStepUp
+	StepUp fiber.Handler
+}
+
+func NewProfileRoute(app *fiber.App, controller *controllers.ProfileController, loginHistoryController *controllers.LoginHistoryController, authMiddleware *middleware.AuthMiddleware, stepUp fiber.Handler) *ProfileRoute {
+	return &ProfileRoute{
+		App:                    app,
+		ProfileController:      controller,
+		LoginHistoryController: loginHistoryController,
+		AuthMiddleware:         authMiddleware,
+		StepUp:                 stepUp,
+	}
+}
+
+func (r *ProfileRoute) Setup() {
+	r.App.Get("/me", r.AuthMiddleware.Authenticate, r.ProfileController.GetProfile)
+	r.App.Patch("/me", r.AuthMiddleware.Authenticate, middleware.When(r.ProfileController.ChangesEmail, r.StepUp), r.ProfileController.UpdateProfile)
+	r.App.Delete("/me", r.AuthMiddleware.Authenticate, r.StepUp, r.ProfileController.DeleteAccount)
+	r.App.Put("/me/password", r.AuthMiddleware.AuthenticatePasswordChange, r.ProfileController.ChangePassword)
+	r.App.Get("/me/login-history", r.AuthMiddleware.Authenticate, r.LoginHistoryController.GetLoginHistory)
+}
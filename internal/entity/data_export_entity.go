@@ -0,0 +1,30 @@
+package entity
+
+import "time"
+
+const (
+	DataExportStatusPending   = "pending"
+	DataExportStatusRunning   = "running"
+	DataExportStatusCompleted = "completed"
+	DataExportStatusFailed    = "failed"
+	DataExportStatusExpired   = "expired"
+)
+
+// DataExport is a copy of everything stored about a user, generated in the background as a JSON file
+type DataExport struct {
+	Id             int        `gorm:"column:id;primaryKey"`
+	UserId         int        `gorm:"column:user_id"`
+	Status         string     `gorm:"column:status;default:pending"`
+	FilePath       string     `gorm:"column:file_path"`
+	ErrorMessage   string     `gorm:"column:error_message"`
+	LeaseExpiresAt *time.Time `gorm:"column:lease_expires_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	CompletedAt    *time.Time `gorm:"column:completed_at"`
+	// ExpiresAt is when the file is deleted
+	ExpiresAt *time.Time `gorm:"column:expires_at"`
+}
+
+func (e *DataExport) IsDownloadable(now time.Time) bool {
+	return e.Status == DataExportStatusCompleted && e.ExpiresAt != nil && e.ExpiresAt.After(now)
+}
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// Invitation lets someone sign up while signup.mode is invite_only. Only the hash of its code is stored. An
+// invitation sent by email only signs up that email, and is used once
+type Invitation struct {
+	Id        int        `gorm:"column:id;primaryKey"`
+	CodeHash  string     `gorm:"column:code_hash"`
+	Email     *string    `gorm:"column:email"`
+	Role      string     `gorm:"column:role"`
+	MaxUses   int        `gorm:"column:max_uses"`
+	Uses      int        `gorm:"column:uses"`
+	CreatedBy *int       `gorm:"column:created_by"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime"`
+	ExpiresAt time.Time  `gorm:"column:expires_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+}
+
+func (i *Invitation) IsUsable(now time.Time) bool {
+	return i.RevokedAt == nil && i.ExpiresAt.After(now) && i.Uses < i.MaxUses
+}
@@ -0,0 +1,30 @@
+package entity
+
+import "time"
+
+const (
+	LoginOutcomeSuccess                = "success"
+	LoginOutcomeUnknownUser            = "unknown_user"
+	LoginOutcomeInvalidPassword        = "invalid_password"
+	LoginOutcomeLocked                 = "locked"
+	LoginOutcomeSuspended              = "suspended"
+	LoginOutcomeDisabled               = "disabled"
+	LoginOutcomePasswordChangeRequired = "password_change_required"
+	LoginOutcomeInvalidLink            = "invalid_link"
+	LoginOutcomeInvalidCode            = "invalid_code"
+	LoginOutcomeMfaRequired            = "mfa_required"
+	LoginOutcomeInvalidMfaCode         = "invalid_mfa_code"
+	LoginOutcomeInvalidPasskey         = "invalid_passkey"
+	LoginOutcomeError                  = "error"
+)
+
+// LoginAttempt is one sign in attempt. UserId is nil when the identifier matched no user
+type LoginAttempt struct {
+	Id         int64     `gorm:"column:id;primaryKey"`
+	UserId     *int      `gorm:"column:user_id"`
+	Identifier string    `gorm:"column:identifier"`
+	IpAddress  string    `gorm:"column:ip_address"`
+	UserAgent  string    `gorm:"column:user_agent"`
+	Outcome    string    `gorm:"column:outcome"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime"`
+}
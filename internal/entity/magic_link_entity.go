@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// MagicLink signs a user in without a password. Only hashes of the emailed token and of the secret kept in the
+// cookie of the requesting browser are stored
+type MagicLink struct {
+	Id          int        `gorm:"column:id;primaryKey"`
+	UserId      int        `gorm:"column:user_id"`
+	TokenHash   string     `gorm:"column:token_hash"`
+	BrowserHash string     `gorm:"column:browser_hash"`
+	IpAddress   string     `gorm:"column:ip_address"`
+	UserAgent   string     `gorm:"column:user_agent"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at"`
+	UsedAt      *time.Time `gorm:"column:used_at"`
+}
+
+func (m *MagicLink) IsUsable(now time.Time) bool {
+	return m.UsedAt == nil && m.ExpiresAt.After(now)
+}
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// TotpCredential is the authenticator app of a user. Secret is encrypted, and the credential only protects the
+// account once it is confirmed with a first code
+type TotpCredential struct {
+	UserId       int        `gorm:"column:user_id;primaryKey"`
+	Secret       string     `gorm:"column:secret"`
+	LastUsedStep int64      `gorm:"column:last_used_step"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime"`
+	ConfirmedAt  *time.Time `gorm:"column:confirmed_at"`
+}
+
+// RecoveryCode signs in once when the second factor is lost. Only its keyed hash is stored
+type RecoveryCode struct {
+	Id        int        `gorm:"column:id;primaryKey"`
+	UserId    int        `gorm:"column:user_id"`
+	CodeHash  string     `gorm:"column:code_hash"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+}
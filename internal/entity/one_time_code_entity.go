@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+const (
+	OneTimeCodePurposeEmailLogin    = "email_login"
+	OneTimeCodePurposeSmsEnrollment = "sms_enrollment"
+	OneTimeCodePurposeSmsLogin      = "sms_login"
+)
+
+// OneTimeCode is a short numeric code sent to the user. Only its keyed hash is stored
+type OneTimeCode struct {
+	Id          int        `gorm:"column:id;primaryKey"`
+	UserId      int        `gorm:"column:user_id"`
+	Purpose     string     `gorm:"column:purpose"`
+	Destination string     `gorm:"column:destination"`
+	CodeHash    string     `gorm:"column:code_hash"`
+	Attempts    int        `gorm:"column:attempts"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at"`
+	UsedAt      *time.Time `gorm:"column:used_at"`
+}
+
+func (c *OneTimeCode) IsUsable(now time.Time, maxAttempts int) bool {
+	return c.UsedAt == nil && c.ExpiresAt.After(now) && c.Attempts < maxAttempts
+}
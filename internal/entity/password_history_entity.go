@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+type PasswordHistory struct {
+	Id        int       `gorm:"column:id;primaryKey"`
+	UserId    int       `gorm:"column:user_id"`
+	Password  string    `gorm:"column:password"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+}
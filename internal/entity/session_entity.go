@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+type Session struct {
+	Id        string `gorm:"column:id;primaryKey"`
+	UserId    int    `gorm:"column:user_id"`
+	UserAgent string `gorm:"column:user_agent"`
+	IpAddress string `gorm:"column:ip_address"`
+	// AuthTime is when the user last proved who they are in the session, at sign in or re-authentication
+	AuthTime time.Time `gorm:"column:auth_time"`
+	// Amr holds the space separated authentication methods used at AuthTime
+	Amr       string     `gorm:"column:amr"`
+	Acr       string     `gorm:"column:acr"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime"`
+	ExpiresAt time.Time  `gorm:"column:expires_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+}
+
+func (s *Session) AuthenticationMethods() []string {
+	return strings.Fields(s.Amr)
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(now)
+}
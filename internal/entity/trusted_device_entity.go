@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// TrustedDevice is a browser where the user gave the second factor and asked not to be asked again. Only the hash
+// of the token in its cookie is stored, and deleting the row revokes it
+type TrustedDevice struct {
+	Id         int        `gorm:"column:id;primaryKey"`
+	UserId     int        `gorm:"column:user_id"`
+	TokenHash  string     `gorm:"column:token_hash"`
+	UserAgent  string     `gorm:"column:user_agent"`
+	IpAddress  string     `gorm:"column:ip_address"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at"`
+}
+
+func (d *TrustedDevice) IsActive(now time.Time) bool {
+	return d.ExpiresAt.After(now)
+}
@@ -1,11 +1,87 @@
 package entity
 
+import (
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusDisabled  = "disabled"
+)
+
 type User struct {
-	Id        int       `gorm:"column:id;primaryKey"`
-	Name      string    `gorm:"column:name"`
-	Email     string    `gorm:"column:email;unique"`
-	Password  string    `gorm:"column:password"`
-	CreatedAt uint8     `gorm:"column:created_at;autoCreateTime:milli"`
-	UpdatedAt uint8     `gorm:"column:updated_at;autCreateTime:milli;autoUpdateTime:milli"`
-	Products  []Product `gorm:"foreignKey:user_id;references:id"`
+	Id             int            `gorm:"column:id;primaryKey"`
+	Name           string         `gorm:"column:name"`
+	Username       *string        `gorm:"column:username;unique"`
+	Email          string         `gorm:"column:email;unique"`
+	EmailCanonical string         `gorm:"column:email_canonical;index"`
+	Password       string         `gorm:"column:password"`
+	Role           string         `gorm:"column:role;default:user"`
+	CreatedAt      time.Time      `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time      `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	DeletedAt      gorm.DeletedAt `gorm:"column:deleted_at;index"`
+	Products       []Product      `gorm:"foreignKey:user_id;references:id"`
+
+	FailedSignInAttempts int        `gorm:"column:failed_sign_in_attempts"`
+	LockoutCount         int        `gorm:"column:lockout_count"`
+	LockedUntil          *time.Time `gorm:"column:locked_until"`
+
+	Status          string     `gorm:"column:status;default:active"`
+	StatusReason    string     `gorm:"column:status_reason"`
+	StatusExpiresAt *time.Time `gorm:"column:status_expires_at"`
+
+	PasswordChangeRequired bool       `gorm:"column:password_change_required"`
+	PasswordChangedAt      *time.Time `gorm:"column:password_changed_at"`
+
+	LastLoginAt *time.Time `gorm:"column:last_login_at"`
+
+	MfaEnabled bool `gorm:"column:mfa_enabled"`
+	// PhoneNumber is only set once verified, and receives the SMS codes of the second factor
+	PhoneNumber *string `gorm:"column:phone_number"`
+}
+
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(now)
+}
+
+// IsPasswordExpired reports whether the password is older than maxAge. Users that never changed their
+// password count from their creation. A maxAge of 0 never expires
+func (u *User) IsPasswordExpired(now time.Time, maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		return false
+	}
+
+	changedAt := u.CreatedAt
+	if u.PasswordChangedAt != nil {
+		changedAt = *u.PasswordChangedAt
+	}
+	return !changedAt.Add(maxAge).After(now)
+}
+
+// IsActive treats a suspension whose expiry has passed as active again
+func (u *User) IsActive(now time.Time) bool {
+	switch u.Status {
+	case UserStatusActive, "":
+		return true
+	case UserStatusSuspended:
+		return u.StatusExpiresAt != nil && !u.StatusExpiresAt.After(now)
+	default:
+		return false
+	}
+}
+
+// BeforeSave falls back to a lowercase email for users saved without a canonical email
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	if u.EmailCanonical == "" {
+		u.EmailCanonical = strings.ToLower(strings.TrimSpace(u.Email))
+	}
+	return nil
 }
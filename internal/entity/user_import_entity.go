@@ -0,0 +1,45 @@
+package entity
+
+import "time"
+
+const (
+	UserImportFormatCSV   = "csv"
+	UserImportFormatJSONL = "jsonl"
+)
+
+const (
+	UserImportStatusPending   = "pending"
+	UserImportStatusRunning   = "running"
+	UserImportStatusCompleted = "completed"
+	UserImportStatusFailed    = "failed"
+)
+
+// UserImportJob tracks a bulk import. ProcessedRows is the resume point, rows before it are never read again
+type UserImportJob struct {
+	Id             int        `gorm:"column:id;primaryKey"`
+	Format         string     `gorm:"column:format"`
+	FilePath       string     `gorm:"column:file_path"`
+	Status         string     `gorm:"column:status;default:pending"`
+	ProcessedRows  int        `gorm:"column:processed_rows"`
+	ImportedRows   int        `gorm:"column:imported_rows"`
+	FailedRows     int        `gorm:"column:failed_rows"`
+	ErrorMessage   string     `gorm:"column:error_message"`
+	CreatedBy      *int       `gorm:"column:created_by"`
+	LeaseExpiresAt *time.Time `gorm:"column:lease_expires_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	FinishedAt     *time.Time `gorm:"column:finished_at"`
+}
+
+func (j *UserImportJob) IsFinished() bool {
+	return j.Status == UserImportStatusCompleted || j.Status == UserImportStatusFailed
+}
+
+type UserImportError struct {
+	Id        int       `gorm:"column:id;primaryKey"`
+	JobId     int       `gorm:"column:job_id"`
+	RowNumber int       `gorm:"column:row_no"`
+	Email     string    `gorm:"column:email"`
+	Message   string    `gorm:"column:message"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+}
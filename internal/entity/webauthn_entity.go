@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	WebAuthnChallengePurposeRegistration   = "registration"
+	WebAuthnChallengePurposeAuthentication = "authentication"
+	// WebAuthnChallengePurposeReauthentication is a step up of a session, see WebAuthnUseCase.Reauthenticate
+	WebAuthnChallengePurposeReauthentication = "reauthentication"
+)
+
+// WebAuthnCredential is a passkey or security key of a user. CredentialId is base64url encoded and PublicKey is
+// the COSE_Key given at registration
+type WebAuthnCredential struct {
+	Id               int        `gorm:"column:id;primaryKey"`
+	UserId           int        `gorm:"column:user_id"`
+	CredentialId     string     `gorm:"column:credential_id"`
+	CredentialIdHash string     `gorm:"column:credential_id_hash"`
+	PublicKey        []byte     `gorm:"column:public_key"`
+	SignCount        int64      `gorm:"column:sign_count"`
+	Transports       string     `gorm:"column:transports"`
+	Name             string     `gorm:"column:name"`
+	CreatedAt        time.Time  `gorm:"column:created_at;autoCreateTime"`
+	LastUsedAt       *time.Time `gorm:"column:last_used_at"`
+}
+
+// TableName keeps gorm from splitting WebAuthn into web_authn
+func (c *WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+func (c *WebAuthnCredential) TransportList() []string {
+	if c.Transports == "" {
+		return []string{}
+	}
+	return strings.Split(c.Transports, ",")
+}
+
+// WebAuthnChallenge is a single use challenge of a ceremony. UserId is empty for a passwordless sign in, where the
+// user is only known from the credential
+type WebAuthnChallenge struct {
+	Id            int        `gorm:"column:id;primaryKey"`
+	UserId        *int       `gorm:"column:user_id"`
+	Purpose       string     `gorm:"column:purpose"`
+	ChallengeHash string     `gorm:"column:challenge_hash"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime"`
+	ExpiresAt     time.Time  `gorm:"column:expires_at"`
+	UsedAt        *time.Time `gorm:"column:used_at"`
+}
+
+func (c *WebAuthnChallenge) TableName() string {
+	return "webauthn_challenges"
+}
+
+func (c *WebAuthnChallenge) IsUsable(now time.Time) bool {
+	return c.UsedAt == nil && c.ExpiresAt.After(now)
+}
@@ -0,0 +1,86 @@
+package helpers
+
+import (
+	"github.com/spf13/viper"
+	"strings"
+)
+
+type EmailProviderRule struct {
+	Domains         []string `mapstructure:"domains"`
+	CanonicalDomain string   `mapstructure:"canonical_domain"`
+	RemoveDots      bool     `mapstructure:"remove_dots"`
+	StripPlusTag    bool     `mapstructure:"strip_plus_tag"`
+}
+
+type EmailNormalizer struct {
+	LowercaseLocalPart bool
+	Providers          []EmailProviderRule
+}
+
+func NewEmailNormalizer(viper *viper.Viper) *EmailNormalizer {
+	normalizer := &EmailNormalizer{
+		LowercaseLocalPart: viper.GetBool("email.normalization.lowercase_local_part"),
+	}
+
+	err := viper.UnmarshalKey("email.normalization.providers", &normalizer.Providers)
+	if err != nil {
+		panic(err)
+	}
+
+	return normalizer
+}
+
+// Normalize trims the email and lowercases its domain. The result is what we store and send emails to
+func (n *EmailNormalizer) Normalize(email string) string {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+
+	return email[:at] + "@" + strings.ToLower(email[at+1:])
+}
+
+// Canonicalize returns the form used to decide whether two emails belong to the same mailbox.
+// On top of Normalize it lowercases the local part and applies the provider specific rules
+func (n *EmailNormalizer) Canonicalize(email string) string {
+	email = n.Normalize(email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return strings.ToLower(email)
+	}
+
+	local, domain := email[:at], email[at+1:]
+	if n.LowercaseLocalPart {
+		local = strings.ToLower(local)
+	}
+
+	for _, provider := range n.Providers {
+		if !containsFold(provider.Domains, domain) {
+			continue
+		}
+		if provider.StripPlusTag {
+			if plus := strings.Index(local, "+"); plus > 0 {
+				local = local[:plus]
+			}
+		}
+		if provider.RemoveDots {
+			local = strings.ReplaceAll(local, ".", "")
+		}
+		if provider.CanonicalDomain != "" {
+			domain = strings.ToLower(provider.CanonicalDomain)
+		}
+		break
+	}
+
+	return local + "@" + domain
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,16 @@
 package helpers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/models"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 func GetFirstValidationErrorsAndConvert(validationErrors error) string {
@@ -22,3 +29,66 @@ func GetFirstValidationErrorsAndConvert(validationErrors error) string {
 	message := fmt.Sprintf("%s must be %s %s", field, tag, param)
 	return strings.TrimSpace(message)
 }
+
+// FormatTime renders t as an RFC 3339 string in UTC. The zero time is rendered as an empty string.
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// GenerateRandomToken returns n cryptographically random bytes encoded as hex
+func GenerateRandomToken(n int) (string, error) {
+	bytes := make([]byte, n)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// HashToken hashes a random token before it is stored, so a leaked table can't be used to sign in.
+// It is only suitable for tokens with enough entropy to make guessing pointless
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Truncate cuts s to at most max bytes, so it fits into a VARCHAR column
+func Truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
+
+// ResolveConfigPath resolves a relative path from the config against the directory of the config file
+func ResolveConfigPath(viper *viper.Viper, path string) string {
+	if path == "" || filepath.IsAbs(path) || viper.ConfigFileUsed() == "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(viper.ConfigFileUsed()), path)
+}
+
+// NewPaginationMetaData links the previous and next page to path, keeping the page size. path may already
+// carry a query string, like the filters of a search
+func NewPaginationMetaData(path string, page models.PageRequest, totalItem int64) *models.PaginationMetaData {
+	totalPage := int((totalItem + int64(page.Size) - 1) / int64(page.Size))
+	pagination := &models.PaginationMetaData{
+		CurrentPage: page.Page,
+		TotalPage:   totalPage,
+		TotalItem:   int(totalItem),
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	if page.Page > 1 {
+		pagination.Previous = fmt.Sprintf("%s%spage=%d&size=%d", path, separator, page.Page-1, page.Size)
+	}
+	if page.Page < totalPage {
+		pagination.Next = fmt.Sprintf("%s%spage=%d&size=%d", path, separator, page.Page+1, page.Size)
+	}
+	return pagination
+}
@@ -0,0 +1,29 @@
+package helpers
+
+import (
+	"regexp"
+	"strings"
+)
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// NormalizePhoneNumber drops the separators people type in phone numbers and returns the number in E.164 format.
+// It returns false when what is left isn't an international number
+func NormalizePhoneNumber(phoneNumber string) (string, bool) {
+	phoneNumber = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(strings.TrimSpace(phoneNumber))
+	if strings.HasPrefix(phoneNumber, "00") {
+		phoneNumber = "+" + phoneNumber[2:]
+	}
+	if !e164.MatchString(phoneNumber) {
+		return "", false
+	}
+	return phoneNumber, true
+}
+
+// MaskPhoneNumber only keeps the country code side and the last 2 digits, enough for users to recognize their number
+func MaskPhoneNumber(phoneNumber string) string {
+	if len(phoneNumber) < 6 {
+		return phoneNumber
+	}
+	return phoneNumber[:3] + strings.Repeat("*", len(phoneNumber)-5) + phoneNumber[len(phoneNumber)-2:]
+}
@@ -1,19 +1,30 @@
 package injector
 
 import (
+	"context"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/spf13/viper"
 	"golang-authentication/internal/dilevery/http/controllers"
+	"golang-authentication/internal/dilevery/http/middleware"
 	"golang-authentication/internal/dilevery/http/routes"
+	"golang-authentication/internal/mail"
 	"golang-authentication/internal/repository"
+	"golang-authentication/internal/security"
+	"golang-authentication/internal/sms"
 	"golang-authentication/internal/usecase"
+	"golang-authentication/internal/worker"
 	"gorm.io/gorm"
+	"log"
+	"time"
 )
 
-func InjectSignUpRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate) *routes.SignUpRoute {
+func InjectSignUpRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper) *routes.SignUpRoute {
 	userRepository := repository.NewUserRepository(database)
-	userUseCase := usecase.NewSignupUseCase(userRepository, validator)
+	invitationRepository := repository.NewInvitationRepository(database)
+	emailDomainPolicy := injectEmailDomainPolicy(viper)
+	userUseCase := usecase.NewSignupUseCase(userRepository, invitationRepository, emailDomainPolicy, validator, viper)
 	userController := controllers.NewUserController(userUseCase)
 	userRoute := routes.NewUserRoute(app, userController)
 	return userRoute
@@ -21,9 +32,199 @@ func InjectSignUpRoute(app *fiber.App, database *gorm.DB, validator *validator.V
 
 func InjectAuthRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper) *routes.AuthRoute {
 	userRepository := repository.NewUserRepository(database)
-	authUseCase := usecase.NewAuthUseCase(userRepository, validator, viper)
+	sessionRepository := repository.NewSessionRepository(database)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(database)
+	trustedDeviceRepository := repository.NewTrustedDeviceRepository(database)
+	authUseCase := usecase.NewAuthUseCase(userRepository, sessionRepository, loginAttemptRepository, trustedDeviceRepository, validator, viper)
 	authController := controllers.NewAuthController(authUseCase)
-	authRoute := routes.NewAuthRoute(app, authController)
+	magicLinkRepository := repository.NewMagicLinkRepository(database)
+	mailer := mail.NewMailer(viper)
+	magicLinkUseCase := usecase.NewMagicLinkUseCase(authUseCase, userRepository, magicLinkRepository, mailer, validator, viper)
+	magicLinkController := controllers.NewMagicLinkController(magicLinkUseCase)
+	oneTimeCodeRepository := repository.NewOneTimeCodeRepository(database)
+	emailCodeUseCase := usecase.NewEmailCodeUseCase(authUseCase, userRepository, oneTimeCodeRepository, mailer, validator, viper)
+	emailCodeController := controllers.NewEmailCodeController(emailCodeUseCase)
+	authRoute := routes.NewAuthRoute(app, authController, magicLinkController, emailCodeController)
 
 	return authRoute
 }
+
+func InjectProfileRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper) *routes.ProfileRoute {
+	userRepository := repository.NewUserRepository(database)
+	sessionRepository := repository.NewSessionRepository(database)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(database)
+	trustedDeviceRepository := repository.NewTrustedDeviceRepository(database)
+	authUseCase := usecase.NewAuthUseCase(userRepository, sessionRepository, loginAttemptRepository, trustedDeviceRepository, validator, viper)
+	authMiddleware := middleware.NewAuthMiddleware(authUseCase)
+	passwordHistoryRepository := repository.NewPasswordHistoryRepository(database)
+	emailDomainPolicy := injectEmailDomainPolicy(viper)
+	profileUseCase := usecase.NewProfileUseCase(userRepository, passwordHistoryRepository, trustedDeviceRepository, emailDomainPolicy, validator, viper)
+	profileController := controllers.NewProfileController(profileUseCase)
+	loginHistoryUseCase := usecase.NewLoginHistoryUseCase(loginAttemptRepository)
+	loginHistoryController := controllers.NewLoginHistoryController(loginHistoryUseCase)
+	stepUp := authMiddleware.RequireStepUp(time.Duration(viper.GetInt("step_up.max_age_minutes"))*time.Minute, viper.GetString("step_up.acr"))
+	profileRoute := routes.NewProfileRoute(app, profileController, loginHistoryController, authMiddleware, stepUp)
+
+	return profileRoute
+}
+
+func InjectAdminRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper) *routes.AdminRoute {
+	userRepository := repository.NewUserRepository(database)
+	sessionRepository := repository.NewSessionRepository(database)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(database)
+	trustedDeviceRepository := repository.NewTrustedDeviceRepository(database)
+	authUseCase := usecase.NewAuthUseCase(userRepository, sessionRepository, loginAttemptRepository, trustedDeviceRepository, validator, viper)
+	authMiddleware := middleware.NewAuthMiddleware(authUseCase)
+	adminUseCase := usecase.NewAdminUseCase(userRepository, sessionRepository, validator, viper)
+	adminController := controllers.NewAdminController(adminUseCase)
+	userImportRepository := repository.NewUserImportRepository(database)
+	userImportUseCase := usecase.NewUserImportUseCase(userRepository, userImportRepository, validator, viper)
+	userImportController := controllers.NewUserImportController(userImportUseCase)
+	loginHistoryUseCase := usecase.NewLoginHistoryUseCase(loginAttemptRepository)
+	loginHistoryController := controllers.NewLoginHistoryController(loginHistoryUseCase)
+	invitationRepository := repository.NewInvitationRepository(database)
+	invitationUseCase := usecase.NewInvitationUseCase(invitationRepository, mail.NewMailer(viper), validator, viper)
+	invitationController := controllers.NewInvitationController(invitationUseCase)
+	adminRoute := routes.NewAdminRoute(app, adminController, userImportController, loginHistoryController, invitationController, authMiddleware)
+
+	return adminRoute
+}
+
+func InjectDataExportRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper) *routes.DataExportRoute {
+	userRepository := repository.NewUserRepository(database)
+	sessionRepository := repository.NewSessionRepository(database)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(database)
+	trustedDeviceRepository := repository.NewTrustedDeviceRepository(database)
+	authUseCase := usecase.NewAuthUseCase(userRepository, sessionRepository, loginAttemptRepository, trustedDeviceRepository, validator, viper)
+	authMiddleware := middleware.NewAuthMiddleware(authUseCase)
+	productRepository := repository.NewProductRepository(database)
+	dataExportRepository := repository.NewDataExportRepository(database)
+	dataExportUseCase := usecase.NewDataExportUseCase(userRepository, sessionRepository, productRepository, loginAttemptRepository, dataExportRepository, viper)
+	dataExportController := controllers.NewDataExportController(dataExportUseCase)
+	dataExportRoute := routes.NewDataExportRoute(app, dataExportController, authMiddleware)
+
+	return dataExportRoute
+}
+
+func InjectMfaRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper) *routes.MfaRoute {
+	userRepository := repository.NewUserRepository(database)
+	sessionRepository := repository.NewSessionRepository(database)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(database)
+	trustedDeviceRepository := repository.NewTrustedDeviceRepository(database)
+	authUseCase := usecase.NewAuthUseCase(userRepository, sessionRepository, loginAttemptRepository, trustedDeviceRepository, validator, viper)
+	authMiddleware := middleware.NewAuthMiddleware(authUseCase)
+	totpCredentialRepository := repository.NewTotpCredentialRepository(database)
+	recoveryCodeRepository := repository.NewRecoveryCodeRepository(database)
+	webAuthnCredentialRepository := repository.NewWebAuthnCredentialRepository(database)
+	oneTimeCodeRepository := repository.NewOneTimeCodeRepository(database)
+	smsSender := sms.NewSMSSender(viper)
+	mfaUseCase := usecase.NewMfaUseCase(authUseCase, userRepository, totpCredentialRepository, recoveryCodeRepository, webAuthnCredentialRepository, oneTimeCodeRepository, smsSender, validator, viper)
+	mfaController := controllers.NewMfaController(mfaUseCase)
+	webAuthnChallengeRepository := repository.NewWebAuthnChallengeRepository(database)
+	webAuthnUseCase := usecase.NewWebAuthnUseCase(authUseCase, mfaUseCase, userRepository, webAuthnCredentialRepository, webAuthnChallengeRepository, validator, viper)
+	webAuthnController := controllers.NewWebAuthnController(webAuthnUseCase)
+	trustedDeviceUseCase := usecase.NewTrustedDeviceUseCase(authUseCase, trustedDeviceRepository)
+	trustedDeviceController := controllers.NewTrustedDeviceController(trustedDeviceUseCase)
+	stepUp := authMiddleware.RequireStepUp(time.Duration(viper.GetInt("step_up.max_age_minutes"))*time.Minute, viper.GetString("step_up.acr"))
+	mfaRoute := routes.NewMfaRoute(app, mfaController, webAuthnController, trustedDeviceController, authMiddleware, stepUp)
+
+	return mfaRoute
+}
+
+func InjectScheduler(database *gorm.DB, validator *validator.Validate, viper *viper.Viper) *worker.Scheduler {
+	userRepository := repository.NewUserRepository(database)
+	sessionRepository := repository.NewSessionRepository(database)
+	adminUseCase := usecase.NewAdminUseCase(userRepository, sessionRepository, validator, viper)
+	userImportRepository := repository.NewUserImportRepository(database)
+	userImportUseCase := usecase.NewUserImportUseCase(userRepository, userImportRepository, validator, viper)
+	productRepository := repository.NewProductRepository(database)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(database)
+	dataExportRepository := repository.NewDataExportRepository(database)
+	dataExportUseCase := usecase.NewDataExportUseCase(userRepository, sessionRepository, productRepository, loginAttemptRepository, dataExportRepository, viper)
+	trustedDeviceRepository := repository.NewTrustedDeviceRepository(database)
+	authUseCase := usecase.NewAuthUseCase(userRepository, sessionRepository, loginAttemptRepository, trustedDeviceRepository, validator, viper)
+	magicLinkRepository := repository.NewMagicLinkRepository(database)
+	mailer := mail.NewMailer(viper)
+	magicLinkUseCase := usecase.NewMagicLinkUseCase(authUseCase, userRepository, magicLinkRepository, mailer, validator, viper)
+	oneTimeCodeRepository := repository.NewOneTimeCodeRepository(database)
+	emailCodeUseCase := usecase.NewEmailCodeUseCase(authUseCase, userRepository, oneTimeCodeRepository, mailer, validator, viper)
+	totpCredentialRepository := repository.NewTotpCredentialRepository(database)
+	recoveryCodeRepository := repository.NewRecoveryCodeRepository(database)
+	webAuthnCredentialRepository := repository.NewWebAuthnCredentialRepository(database)
+	smsSender := sms.NewSMSSender(viper)
+	mfaUseCase := usecase.NewMfaUseCase(authUseCase, userRepository, totpCredentialRepository, recoveryCodeRepository, webAuthnCredentialRepository, oneTimeCodeRepository, smsSender, validator, viper)
+	webAuthnChallengeRepository := repository.NewWebAuthnChallengeRepository(database)
+	webAuthnUseCase := usecase.NewWebAuthnUseCase(authUseCase, mfaUseCase, userRepository, webAuthnCredentialRepository, webAuthnChallengeRepository, validator, viper)
+	trustedDeviceUseCase := usecase.NewTrustedDeviceUseCase(authUseCase, trustedDeviceRepository)
+
+	scheduler := worker.NewScheduler()
+	purgeInterval := time.Duration(viper.GetInt("account.deletion.purge_interval_minutes")) * time.Minute
+	scheduler.Add("purge deleted users", purgeInterval, func(ctx context.Context) error {
+		purged, err := adminUseCase.PurgeDeletedUsers(ctx)
+		if err != nil {
+			return err
+		}
+		if purged > 0 {
+			fmt.Printf("Purged %d deleted users\n", purged)
+		}
+		return nil
+	})
+
+	importInterval := time.Duration(viper.GetInt("import.poll_interval_seconds")) * time.Second
+	scheduler.Add("process user imports", importInterval, func(ctx context.Context) error {
+		processed, err := userImportUseCase.ProcessPendingImports(ctx)
+		if processed > 0 {
+			fmt.Printf("Processed %d user imports\n", processed)
+		}
+		return err
+	})
+
+	exportInterval := time.Duration(viper.GetInt("export.poll_interval_seconds")) * time.Second
+	scheduler.Add("generate data exports", exportInterval, func(ctx context.Context) error {
+		processed, err := dataExportUseCase.ProcessPendingExports(ctx)
+		if processed > 0 {
+			fmt.Printf("Generated %d data exports\n", processed)
+		}
+		return err
+	})
+
+	scheduler.Add("purge expired data exports", time.Hour, func(ctx context.Context) error {
+		purged, err := dataExportUseCase.PurgeExpiredExports(ctx)
+		if purged > 0 {
+			fmt.Printf("Purged %d expired data exports\n", purged)
+		}
+		return err
+	})
+
+	scheduler.Add("purge expired magic links", time.Hour, func(ctx context.Context) error {
+		_, err := magicLinkUseCase.PurgeExpiredMagicLinks(ctx)
+		return err
+	})
+
+	scheduler.Add("purge expired one-time codes", time.Hour, func(ctx context.Context) error {
+		_, err := emailCodeUseCase.PurgeExpiredCodes(ctx)
+		return err
+	})
+
+	scheduler.Add("purge expired passkey challenges", time.Hour, func(ctx context.Context) error {
+		_, err := webAuthnUseCase.PurgeExpiredChallenges(ctx)
+		return err
+	})
+
+	scheduler.Add("purge expired trusted devices", time.Hour, func(ctx context.Context) error {
+		_, err := trustedDeviceUseCase.PurgeExpiredDevices(ctx)
+		return err
+	})
+
+	return scheduler
+}
+
+// injectEmailDomainPolicy stops the server when the disposable domain list can't be read, rather than letting
+// disposable emails through
+func injectEmailDomainPolicy(viper *viper.Viper) *security.EmailDomainPolicy {
+	emailDomainPolicy, err := security.NewEmailDomainPolicy(viper)
+	if err != nil {
+		log.Fatalf("Error while loading the email domain policy %v", err)
+	}
+	return emailDomainPolicy
+}
@@ -0,0 +1,121 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/helpers"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type MailerInterface interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// NewMailer builds the mailer chosen by mail.driver: "smtp", "file" or "console". Unknown drivers fall back to
+// the console so a development setup never fails to start because of mail
+func NewMailer(viper *viper.Viper) MailerInterface {
+	from := viper.GetString("mail.from")
+	switch viper.GetString("mail.driver") {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     viper.GetString("mail.smtp.host"),
+			Port:     viper.GetInt("mail.smtp.port"),
+			Username: viper.GetString("mail.smtp.username"),
+			Password: viper.GetString("mail.smtp.password"),
+			From:     from,
+		}
+	case "file":
+		return &FileMailer{Directory: helpers.ResolveConfigPath(viper, viper.GetString("mail.directory")), From: from}
+	default:
+		return &ConsoleMailer{From: from}
+	}
+}
+
+// ConsoleMailer prints the messages instead of sending them, for development
+type ConsoleMailer struct {
+	From string
+}
+
+func (m *ConsoleMailer) Send(ctx context.Context, message *Message) error {
+	fmt.Print(format(m.From, message))
+	return nil
+}
+
+// FileMailer appends the messages to one file per recipient, so tests and local setups can read them back
+type FileMailer struct {
+	Directory string
+	From      string
+	mutex     sync.Mutex
+}
+
+func (m *FileMailer) Send(ctx context.Context, message *Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	err := os.MkdirAll(m.Directory, 0o700)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(m.Path(message.To), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(format(m.From, message))
+	return err
+}
+
+// Path is the file holding the messages sent to an address
+func (m *FileMailer) Path(to string) string {
+	return filepath.Join(m.Directory, strings.NewReplacer("/", "_", "\\", "_").Replace(strings.ToLower(to))+".eml")
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message *Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	//net/smtp has no context support, so the deadline is only checked before sending
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	address := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	return smtp.SendMail(address, auth, m.From, []string{message.To}, []byte(format(m.From, message)))
+}
+
+func format(from string, message *Message) string {
+	var builder strings.Builder
+	builder.WriteString("From: " + from + "\r\n")
+	builder.WriteString("To: " + message.To + "\r\n")
+	builder.WriteString("Subject: " + message.Subject + "\r\n")
+	builder.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	builder.WriteString(message.Body + "\r\n\r\n")
+	return builder.String()
+}
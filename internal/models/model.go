@@ -7,9 +7,16 @@ type Response[T any] struct {
 }
 
 type ErrorResponse struct {
-	Code    int    `json:"code,omitempty"`
-	Message string `json:"message,omitempty"`
-	Status  string `json:"status,omitempty"`
+	Code    int           `json:"code,omitempty"`
+	Message string        `json:"message,omitempty"`
+	Status  string        `json:"status,omitempty"`
+	Errors  []ErrorDetail `json:"errors,omitempty"`
+}
+
+// ErrorDetail describes a single failure with a machine-readable code
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 func (e ErrorResponse) Error() string {
@@ -23,3 +30,31 @@ type PaginationMetaData struct {
 	TotalPage   int    `json:"total_page"`
 	TotalItem   int    `json:"total_item"`
 }
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageRequest is read from the page and size query parameters. Page starts at 1
+type PageRequest struct {
+	Page int `query:"page"`
+	Size int `query:"size"`
+}
+
+// Normalize replaces a missing or out of range page and size with the defaults
+func (p *PageRequest) Normalize() {
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.Size < 1 {
+		p.Size = DefaultPageSize
+	}
+	if p.Size > MaxPageSize {
+		p.Size = MaxPageSize
+	}
+}
+
+func (p PageRequest) Offset() int {
+	return (p.Page - 1) * p.Size
+}
@@ -1,28 +1,397 @@
 package models
 
+import "time"
+
 type UserResponse struct {
-	Id        int    `json:"id,omitempty"`
-	Name      string `json:"name,omitempty"`
-	Email     string `json:"email,omitempty"`
-	CreatedAt uint8  `json:"created_at,omitempty"`
-	UpdatedAt uint8  `json:"updated_at,omitempty"`
+	Id              int    `json:"id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Username        string `json:"username,omitempty"`
+	Email           string `json:"email,omitempty"`
+	Role            string `json:"role,omitempty"`
+	Status          string `json:"status,omitempty"`
+	StatusReason    string `json:"status_reason,omitempty"`
+	StatusExpiresAt string `json:"status_expires_at,omitempty"`
+	CreatedAt       string `json:"created_at,omitempty"`
+	UpdatedAt       string `json:"updated_at,omitempty"`
+	LastLoginAt     string `json:"last_login_at,omitempty"`
+
+	PasswordChangeRequired bool   `json:"password_change_required,omitempty"`
+	MfaEnabled             bool   `json:"mfa_enabled,omitempty"`
+	PhoneNumber            string `json:"phone_number,omitempty"`
 }
 
+// SignInResponse either carries the access and refresh token, only a password change token when the
+// password has to be changed before the user can sign in, or only an MFA token when a second factor is needed
 type SignInResponse struct {
-	AccessToken  string `json:"access_token,omitempty"`
-	RefreshToken string `json:"refresh_token,omitempty"`
+	AccessToken            string `json:"access_token,omitempty"`
+	RefreshToken           string `json:"refresh_token,omitempty"`
+	PasswordChangeRequired bool   `json:"password_change_required,omitempty"`
+	PasswordChangeReason   string `json:"password_change_reason,omitempty"`
+	PasswordChangeToken    string `json:"password_change_token,omitempty"`
+	MfaRequired            bool   `json:"mfa_required,omitempty"`
+	MfaToken               string `json:"mfa_token,omitempty"`
+	// TrustedDeviceToken is set in the trusted device cookie by the controller, never in the body
+	TrustedDeviceToken     string    `json:"-"`
+	TrustedDeviceExpiresAt time.Time `json:"-"`
+}
+
+type TotpEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningUri string `json:"provisioning_uri"`
+}
+
+type MfaCodeRequest struct {
+	// Code is a code of the authenticator app or a recovery code
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type RecoveryCodesResponse struct {
+	// RecoveryCodes are left out when the authenticator app is confirmed while another second factor is on
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type MfaSignInRequest struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+	// Code is a code of the authenticator app or a recovery code
+	Code string `json:"code" validate:"required,max=32"`
+	// RememberDevice skips the second factor on this device for a while
+	RememberDevice bool `json:"remember_device"`
+
+	ClientInfo `json:"-"`
+}
+
+type PhoneEnrollmentRequest struct {
+	// PhoneNumber is in the international format, starting with + and the country code
+	PhoneNumber string `json:"phone_number" validate:"required,max=32"`
+}
+
+type PhoneEnrollmentResponse struct {
+	PhoneNumber string `json:"phone_number"`
+	// RecoveryCodes are only returned when the phone number turned two-factor authentication on
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// ReauthenticateRequest proves again who the user of a session is, before a sensitive operation
+type ReauthenticateRequest struct {
+	Password string `json:"password" validate:"required"`
+	// Code is a second factor, required when two-factor authentication is enabled
+	Code string `json:"code" validate:"max=32"`
+
+	ClientInfo `json:"-"`
+}
+
+type MfaSmsRequest struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+}
+
+// SmsCodeResponse tells where a code was texted, with the phone number masked
+type SmsCodeResponse struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+// WebAuthnCreationOptions are the PublicKeyCredentialCreationOptions of navigator.credentials.create() in their
+// JSON form, so they can go through PublicKeyCredential.parseCreationOptionsFromJSON as they are
+type WebAuthnCreationOptions struct {
+	Challenge              string                                 `json:"challenge"`
+	Rp                     WebAuthnRelyingParty                   `json:"rp"`
+	User                   WebAuthnUser                           `json:"user"`
+	PubKeyCredParams       []WebAuthnCredentialParameter          `json:"pubKeyCredParams"`
+	Timeout                int                                    `json:"timeout"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor         `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelectionCriteria `json:"authenticatorSelection"`
+	Attestation            string                                 `json:"attestation"`
+}
+
+// WebAuthnRequestOptions are the PublicKeyCredentialRequestOptions of navigator.credentials.get() in their JSON form
+type WebAuthnRequestOptions struct {
+	Challenge        string                         `json:"challenge"`
+	RpId             string                         `json:"rpId"`
+	Timeout          int                            `json:"timeout"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+type WebAuthnRelyingParty struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type WebAuthnUser struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type WebAuthnCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type WebAuthnCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	Id         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type WebAuthnAuthenticatorSelectionCriteria struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// PublicKeyCredentialRequest is a PublicKeyCredential in the JSON form of its toJSON(), binary fields are base64url
+type PublicKeyCredentialRequest struct {
+	Id       string                       `json:"id" validate:"required,max=1366"`
+	Type     string                       `json:"type" validate:"eq=public-key"`
+	Response AuthenticatorResponseRequest `json:"response"`
+}
+
+// AuthenticatorResponseRequest holds either an attestation, at registration, or an assertion, at sign in
+type AuthenticatorResponseRequest struct {
+	ClientDataJSON    string   `json:"clientDataJSON" validate:"required"`
+	AttestationObject string   `json:"attestationObject"`
+	Transports        []string `json:"transports" validate:"max=8,dive,max=32"`
+	AuthenticatorData string   `json:"authenticatorData"`
+	Signature         string   `json:"signature"`
+	UserHandle        string   `json:"userHandle"`
+}
+
+type WebAuthnRegistrationRequest struct {
+	// Name tells the passkeys of a user apart, like the device holding it
+	Name string `json:"name" validate:"max=64"`
+	PublicKeyCredentialRequest
+}
+
+type WebAuthnRegistrationResponse struct {
+	Credential *WebAuthnCredentialResponse `json:"credential"`
+	// RecoveryCodes are only returned when the passkey turned two-factor authentication on
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type WebAuthnCredentialResponse struct {
+	Id         int      `json:"id"`
+	Name       string   `json:"name"`
+	Transports []string `json:"transports"`
+	CreatedAt  string   `json:"created_at"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+}
+
+type TrustedDeviceResponse struct {
+	Id         int    `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IpAddress  string `json:"ip_address"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+	ExpiresAt  string `json:"expires_at"`
+	// Current is the device making the request
+	Current bool `json:"current"`
+}
+
+type WebAuthnSignInOptionsRequest struct {
+	// MfaToken asks for a passkey of that user as the second factor, without it any passkey signs in on its own
+	MfaToken string `json:"mfa_token"`
+}
+
+type WebAuthnSignInRequest struct {
+	MfaToken string `json:"mfa_token"`
+	// RememberDevice skips the second factor on this device for a while, when the passkey is the second factor
+	RememberDevice bool `json:"remember_device"`
+	PublicKeyCredentialRequest
+
+	ClientInfo `json:"-"`
 }
+
+// WebAuthnReauthenticateRequest is the assertion of a passkey for POST /auth/reauthenticate/webauthn/verify
+type WebAuthnReauthenticateRequest struct {
+	PublicKeyCredentialRequest
+
+	ClientInfo `json:"-"`
+}
+
 type SignUpRequest struct {
 	Name     string `json:"name" validate:"required,max=255"`
+	Username string `json:"username" validate:"omitempty,min=3,max=32"`
 	Email    string `json:"email" validate:"required,max=255,email"`
-	Password string `json:"password" validate:"required,min=8"`
+	// Password rules are configured in password.policy, see security.PasswordPolicy
+	Password string `json:"password" validate:"required"`
+	// InvitationCode is required while signup.mode is invite_only
+	InvitationCode string `json:"invitation_code" validate:"max=64"`
+}
+
+type CreateInvitationRequest struct {
+	// Email sends the invitation there, and only lets that email sign up with it
+	Email string `json:"email" validate:"omitempty,email,max=255"`
+	// Role is given to the users signing up with the invitation, user by default
+	Role          string `json:"role" validate:"omitempty,oneof=user admin"`
+	MaxUses       int    `json:"max_uses" validate:"omitempty,min=1,max=1000"`
+	ExpiresInDays int    `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+type InvitationResponse struct {
+	Id int `json:"id"`
+	// Code is only returned when the invitation is created
+	Code      string `json:"code,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Role      string `json:"role"`
+	MaxUses   int    `json:"max_uses"`
+	Uses      int    `json:"uses"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	RevokedAt string `json:"revoked_at,omitempty"`
 }
 
 type SignInRequest struct {
-	Email    string `json:"email" validate:"required,email"`
+	// Identifier is either the email or the username
+	Identifier string `json:"identifier" validate:"required_without=Email,max=255"`
+	// Deprecated: use Identifier
+	Email    string `json:"email" validate:"omitempty,email"`
 	Password string `json:"password" validate:"required"`
+
+	ClientInfo `json:"-"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+
+	ClientInfo `json:"-"`
+}
+
+type EmailCodeRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type EmailCodeSignInRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+	Code  string `json:"code" validate:"required,len=6,numeric"`
+
+	ClientInfo `json:"-"`
+}
+
+// ClientInfo describes the client making the request. It is filled by the controller, not by the request body
+type ClientInfo struct {
+	IpAddress string
+	UserAgent string
+	// TrustedDeviceToken is the trusted device cookie sent by the client, if any
+	TrustedDeviceToken string
 }
 
 type GetTokenResponse struct {
 	AccessToken string `json:"access_token,omitempty"`
 }
+
+type UpdateUserRequest struct {
+	Name     string `json:"name" validate:"omitempty,max=255"`
+	Username string `json:"username" validate:"omitempty,min=3,max=32"`
+	Email    string `json:"email" validate:"omitempty,max=255,email"`
+}
+
+type AccountDeletionResponse struct {
+	PurgeAt string `json:"purge_at,omitempty"`
+}
+
+type UpdateUserStatusRequest struct {
+	Status    string     `json:"status" validate:"required,oneof=active suspended disabled"`
+	Reason    string     `json:"reason" validate:"max=255"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type EmailCollision struct {
+	EmailCanonical string         `json:"email_canonical"`
+	Users          []UserResponse `json:"users"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// UserImportRow is one user of an import file. PasswordHash is kept as is and must use a supported format
+type UserImportRow struct {
+	Name         string `json:"name" validate:"required,max=255"`
+	Username     string `json:"username" validate:"omitempty,min=3,max=32"`
+	Email        string `json:"email" validate:"required,max=255,email"`
+	PasswordHash string `json:"password_hash" validate:"required,max=255"`
+}
+
+type UserImportJobResponse struct {
+	Id            int                       `json:"id"`
+	Format        string                    `json:"format"`
+	Status        string                    `json:"status"`
+	ProcessedRows int                       `json:"processed_rows"`
+	ImportedRows  int                       `json:"imported_rows"`
+	FailedRows    int                       `json:"failed_rows"`
+	ErrorMessage  string                    `json:"error_message,omitempty"`
+	CreatedAt     string                    `json:"created_at,omitempty"`
+	UpdatedAt     string                    `json:"updated_at,omitempty"`
+	FinishedAt    string                    `json:"finished_at,omitempty"`
+	Errors        []UserImportErrorResponse `json:"errors,omitempty"`
+}
+
+type UserImportErrorResponse struct {
+	Row     int    `json:"row"`
+	Email   string `json:"email,omitempty"`
+	Message string `json:"message"`
+}
+
+type DataExportResponse struct {
+	Id           int    `json:"id"`
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	CreatedAt    string `json:"created_at,omitempty"`
+	CompletedAt  string `json:"completed_at,omitempty"`
+	// ExpiresAt is when the archive is deleted
+	ExpiresAt string `json:"expires_at,omitempty"`
+	// DownloadUrl is a signed link that works without the access token until DownloadExpiresAt
+	DownloadUrl       string `json:"download_url,omitempty"`
+	DownloadExpiresAt string `json:"download_expires_at,omitempty"`
+}
+
+// DataExportArchive is the content of the exported JSON file
+type DataExportArchive struct {
+	GeneratedAt string              `json:"generated_at"`
+	Profile     *DataExportProfile  `json:"profile"`
+	Sessions    []DataExportSession `json:"sessions"`
+	Products    []DataExportProduct `json:"products"`
+	// LoginHistory holds every sign in attempt on the account, newest first
+	LoginHistory []LoginAttemptResponse `json:"login_history"`
+}
+
+type DataExportProfile struct {
+	UserResponse
+	PasswordChangedAt string `json:"password_changed_at,omitempty"`
+	LockedUntil       string `json:"locked_until,omitempty"`
+}
+
+type DataExportSession struct {
+	IpAddress string `json:"ip_address"`
+	UserAgent string `json:"user_agent"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	RevokedAt string `json:"revoked_at,omitempty"`
+}
+
+type DataExportProduct struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Stock int32  `json:"stock"`
+	Price int32  `json:"price"`
+}
+
+type LoginAttemptResponse struct {
+	Id         int64  `json:"id"`
+	UserId     *int   `json:"user_id,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	IpAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	Outcome    string `json:"outcome"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// LoginHistoryQuery is read from the query parameters of the admin login history search
+type LoginHistoryQuery struct {
+	UserId    int    `query:"user_id"`
+	IpAddress string `query:"ip_address"`
+	Outcome   string `query:"outcome"`
+	// From and To are RFC 3339 times, To is exclusive
+	From string `query:"from"`
+	To   string `query:"to"`
+}
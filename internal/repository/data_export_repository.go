@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type DataExportRepositoryInterface interface {
+	Save(ctx context.Context, export *entity.DataExport) (*entity.DataExport, error)
+	FindOneById(ctx context.Context, id int) (*entity.DataExport, error)
+	FindUnfinishedByUserId(ctx context.Context, userID int) (*entity.DataExport, error)
+	ClaimNext(ctx context.Context, leaseExpiresAt time.Time) (*entity.DataExport, error)
+	Update(ctx context.Context, export *entity.DataExport) error
+	FindExpiredBefore(ctx context.Context, before time.Time, limit int) ([]*entity.DataExport, error)
+	ExistsByFilePath(ctx context.Context, filePath string) (bool, error)
+}
+
+type DataExportRepository struct {
+	Database *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) *DataExportRepository {
+	return &DataExportRepository{
+		Database: db,
+	}
+}
+
+func (r *DataExportRepository) Save(ctx context.Context, export *entity.DataExport) (*entity.DataExport, error) {
+	err := r.Database.Model(&entity.DataExport{}).WithContext(ctx).Create(export).Error
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (r *DataExportRepository) FindOneById(ctx context.Context, id int) (*entity.DataExport, error) {
+	var export *entity.DataExport
+	err := r.Database.Model(&entity.DataExport{}).WithContext(ctx).First(&export, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// FindUnfinishedByUserId returns the pending or running export of the user, if any
+func (r *DataExportRepository) FindUnfinishedByUserId(ctx context.Context, userID int) (*entity.DataExport, error) {
+	var export *entity.DataExport
+	err := r.Database.Model(&entity.DataExport{}).WithContext(ctx).
+		Where("user_id = ? AND status IN ?", userID, []string{entity.DataExportStatusPending, entity.DataExportStatusRunning}).
+		Order("id").First(&export).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// ClaimNext takes the oldest unfinished export that no worker holds a lease on, or returns nil when there is none
+func (r *DataExportRepository) ClaimNext(ctx context.Context, leaseExpiresAt time.Time) (*entity.DataExport, error) {
+	unfinished := []string{entity.DataExportStatusPending, entity.DataExportStatusRunning}
+	for {
+		var export *entity.DataExport
+		err := r.Database.Model(&entity.DataExport{}).WithContext(ctx).
+			Where("status IN ?", unfinished).
+			Where("lease_expires_at IS NULL OR lease_expires_at < ?", time.Now()).
+			Order("id").First(&export).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		result := r.Database.Model(&entity.DataExport{}).WithContext(ctx).
+			Where("id = ? AND status IN ?", export.Id, unfinished).
+			Where("lease_expires_at IS NULL OR lease_expires_at < ?", time.Now()).
+			Updates(map[string]interface{}{"status": entity.DataExportStatusRunning, "lease_expires_at": leaseExpiresAt})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		//another worker was faster, look for the next one
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		export.Status = entity.DataExportStatusRunning
+		export.LeaseExpiresAt = &leaseExpiresAt
+		return export, nil
+	}
+}
+
+func (r *DataExportRepository) Update(ctx context.Context, export *entity.DataExport) error {
+	err := r.Database.Model(export).WithContext(ctx).
+		Select("status", "file_path", "error_message", "lease_expires_at", "completed_at", "expires_at").
+		Updates(export).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindExpiredBefore returns completed exports whose file should be deleted
+func (r *DataExportRepository) FindExpiredBefore(ctx context.Context, before time.Time, limit int) ([]*entity.DataExport, error) {
+	var exports []*entity.DataExport
+	err := r.Database.Model(&entity.DataExport{}).WithContext(ctx).
+		Where("status = ? AND expires_at < ?", entity.DataExportStatusCompleted, before).
+		Order("id").Limit(limit).Find(&exports).Error
+	if err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// ExistsByFilePath reports whether an export still refers to the archive
+func (r *DataExportRepository) ExistsByFilePath(ctx context.Context, filePath string) (bool, error) {
+	var count int64
+	err := r.Database.Model(&entity.DataExport{}).WithContext(ctx).Where("file_path = ?", filePath).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
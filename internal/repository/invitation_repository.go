@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type InvitationRepositoryInterface interface {
+	Save(ctx context.Context, invitation *entity.Invitation) error
+	FindOneByCodeHash(ctx context.Context, codeHash string) (*entity.Invitation, error)
+	FindAll(ctx context.Context, offset int, limit int) ([]*entity.Invitation, int64, error)
+	Use(ctx context.Context, id int, now time.Time) (bool, error)
+	Release(ctx context.Context, id int) error
+	Revoke(ctx context.Context, id int, revokedAt time.Time) (bool, error)
+}
+
+type InvitationRepository struct {
+	Database *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{
+		Database: db,
+	}
+}
+
+func (r *InvitationRepository) Save(ctx context.Context, invitation *entity.Invitation) error {
+	return r.Database.WithContext(ctx).Create(invitation).Error
+}
+
+func (r *InvitationRepository) FindOneByCodeHash(ctx context.Context, codeHash string) (*entity.Invitation, error) {
+	invitation := new(entity.Invitation)
+	err := r.Database.WithContext(ctx).Where("code_hash = ?", codeHash).Take(invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// FindAll returns one page of the invitations, newest first, with the total number of invitations
+func (r *InvitationRepository) FindAll(ctx context.Context, offset int, limit int) ([]*entity.Invitation, int64, error) {
+	var total int64
+	err := r.Database.Model(&entity.Invitation{}).WithContext(ctx).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var invitations []*entity.Invitation
+	err = r.Database.WithContext(ctx).Order("id DESC").Offset(offset).Limit(limit).Find(&invitations).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return invitations, total, nil
+}
+
+// Use counts a signup with the invitation. It fails once the invitation is used up, expired or revoked, even when
+// signups arrive at the same time
+func (r *InvitationRepository) Use(ctx context.Context, id int, now time.Time) (bool, error) {
+	result := r.Database.Model(&entity.Invitation{}).WithContext(ctx).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ? AND uses < max_uses", id, now).
+		UpdateColumn("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Release gives back a use taken by a signup that failed afterwards
+func (r *InvitationRepository) Release(ctx context.Context, id int) error {
+	return r.Database.Model(&entity.Invitation{}).WithContext(ctx).
+		Where("id = ? AND uses > 0", id).
+		UpdateColumn("uses", gorm.Expr("uses - 1")).Error
+}
+
+func (r *InvitationRepository) Revoke(ctx context.Context, id int, revokedAt time.Time) (bool, error) {
+	result := r.Database.Model(&entity.Invitation{}).WithContext(ctx).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumn("revoked_at", revokedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
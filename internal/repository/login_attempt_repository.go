@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+// LoginAttemptFilter narrows the login history an admin searches. Zero fields match everything
+type LoginAttemptFilter struct {
+	UserId    int
+	IpAddress string
+	Outcome   string
+	From      *time.Time
+	To        *time.Time
+}
+
+type LoginAttemptRepositoryInterface interface {
+	Save(ctx context.Context, attempt *entity.LoginAttempt) error
+	FindAllByUserId(ctx context.Context, userID int, offset int, limit int) ([]*entity.LoginAttempt, int64, error)
+	Search(ctx context.Context, filter LoginAttemptFilter, offset int, limit int) ([]*entity.LoginAttempt, int64, error)
+}
+
+type LoginAttemptRepository struct {
+	Database *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		Database: db,
+	}
+}
+
+func (r *LoginAttemptRepository) Save(ctx context.Context, attempt *entity.LoginAttempt) error {
+	return r.Database.WithContext(ctx).Create(attempt).Error
+}
+
+// FindAllByUserId returns one page of the attempts of a user, newest first, with the total number of attempts
+func (r *LoginAttemptRepository) FindAllByUserId(ctx context.Context, userID int, offset int, limit int) ([]*entity.LoginAttempt, int64, error) {
+	return r.Search(ctx, LoginAttemptFilter{UserId: userID}, offset, limit)
+}
+
+func (r *LoginAttemptRepository) Search(ctx context.Context, filter LoginAttemptFilter, offset int, limit int) ([]*entity.LoginAttempt, int64, error) {
+	var total int64
+	err := r.filtered(ctx, filter).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var attempts []*entity.LoginAttempt
+	err = r.filtered(ctx, filter).Order("id DESC").Offset(offset).Limit(limit).Find(&attempts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return attempts, total, nil
+}
+
+func (r *LoginAttemptRepository) filtered(ctx context.Context, filter LoginAttemptFilter) *gorm.DB {
+	query := r.Database.Model(&entity.LoginAttempt{}).WithContext(ctx)
+	if filter.UserId != 0 {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if filter.IpAddress != "" {
+		query = query.Where("ip_address = ?", filter.IpAddress)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type MagicLinkRepositoryInterface interface {
+	Save(ctx context.Context, magicLink *entity.MagicLink) error
+	FindOneByTokenHash(ctx context.Context, tokenHash string) (*entity.MagicLink, error)
+	FindLatest(ctx context.Context, userID int) (*entity.MagicLink, error)
+	CountSince(ctx context.Context, userID int, since time.Time) (int64, error)
+	MarkUsed(ctx context.Context, id int, usedAt time.Time) (bool, error)
+	DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type MagicLinkRepository struct {
+	Database *gorm.DB
+}
+
+func NewMagicLinkRepository(db *gorm.DB) *MagicLinkRepository {
+	return &MagicLinkRepository{
+		Database: db,
+	}
+}
+
+func (r *MagicLinkRepository) Save(ctx context.Context, magicLink *entity.MagicLink) error {
+	return r.Database.WithContext(ctx).Create(magicLink).Error
+}
+
+func (r *MagicLinkRepository) FindOneByTokenHash(ctx context.Context, tokenHash string) (*entity.MagicLink, error) {
+	magicLink := new(entity.MagicLink)
+	err := r.Database.WithContext(ctx).Where("token_hash = ?", tokenHash).Take(magicLink).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return magicLink, nil
+}
+
+// FindLatest returns the last link sent to the user
+func (r *MagicLinkRepository) FindLatest(ctx context.Context, userID int) (*entity.MagicLink, error) {
+	magicLink := new(entity.MagicLink)
+	err := r.Database.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Take(magicLink).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return magicLink, nil
+}
+
+func (r *MagicLinkRepository) CountSince(ctx context.Context, userID int, since time.Time) (int64, error) {
+	var count int64
+	err := r.Database.Model(&entity.MagicLink{}).WithContext(ctx).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkUsed only succeeds for the first caller, so a link redeemed twice at the same time still signs in once
+func (r *MagicLinkRepository) MarkUsed(ctx context.Context, id int, usedAt time.Time) (bool, error) {
+	result := r.Database.Model(&entity.MagicLink{}).WithContext(ctx).
+		Where("id = ? AND used_at IS NULL", id).
+		UpdateColumn("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *MagicLinkRepository) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.Database.WithContext(ctx).Where("expires_at < ?", before).Delete(&entity.MagicLink{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type TotpCredentialRepositoryInterface interface {
+	FindOneByUserId(ctx context.Context, userID int) (*entity.TotpCredential, error)
+	Save(ctx context.Context, credential *entity.TotpCredential) error
+	Confirm(ctx context.Context, userID int, confirmedAt time.Time, step int64) error
+	UseStep(ctx context.Context, userID int, step int64) (bool, error)
+	DeleteByUserId(ctx context.Context, userID int) error
+}
+
+type TotpCredentialRepository struct {
+	Database *gorm.DB
+}
+
+func NewTotpCredentialRepository(db *gorm.DB) *TotpCredentialRepository {
+	return &TotpCredentialRepository{
+		Database: db,
+	}
+}
+
+func (r *TotpCredentialRepository) FindOneByUserId(ctx context.Context, userID int) (*entity.TotpCredential, error) {
+	credential := new(entity.TotpCredential)
+	err := r.Database.WithContext(ctx).Where("user_id = ?", userID).Take(credential).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return credential, nil
+}
+
+// Save replaces the credential of the user, so enrolling again starts over with a new secret
+func (r *TotpCredentialRepository) Save(ctx context.Context, credential *entity.TotpCredential) error {
+	return r.Database.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(credential).Error
+}
+
+func (r *TotpCredentialRepository) Confirm(ctx context.Context, userID int, confirmedAt time.Time, step int64) error {
+	return r.Database.Model(&entity.TotpCredential{}).WithContext(ctx).Where("user_id = ?", userID).
+		UpdateColumns(map[string]interface{}{"confirmed_at": confirmedAt, "last_used_step": step}).Error
+}
+
+// UseStep records the period of an accepted code. It fails when that period or a later one was already used,
+// so a code can't be replayed
+func (r *TotpCredentialRepository) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	result := r.Database.Model(&entity.TotpCredential{}).WithContext(ctx).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		UpdateColumn("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *TotpCredentialRepository) DeleteByUserId(ctx context.Context, userID int) error {
+	return r.Database.WithContext(ctx).Where("user_id = ?", userID).Delete(&entity.TotpCredential{}).Error
+}
+
+type RecoveryCodeRepositoryInterface interface {
+	ReplaceAll(ctx context.Context, userID int, codeHashes []string) error
+	Use(ctx context.Context, userID int, codeHash string, usedAt time.Time) (bool, error)
+	CountUnused(ctx context.Context, userID int) (int64, error)
+	DeleteAllByUserId(ctx context.Context, userID int) error
+}
+
+type RecoveryCodeRepository struct {
+	Database *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{
+		Database: db,
+	}
+}
+
+// ReplaceAll drops the previous codes of the user, used or not, and saves the new ones
+func (r *RecoveryCodeRepository) ReplaceAll(ctx context.Context, userID int, codeHashes []string) error {
+	return r.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+
+		codes := make([]*entity.RecoveryCode, 0, len(codeHashes))
+		for _, codeHash := range codeHashes {
+			codes = append(codes, &entity.RecoveryCode{UserId: userID, CodeHash: codeHash})
+		}
+		return tx.Create(codes).Error
+	})
+}
+
+// Use marks an unused code as used and reports whether there was one
+func (r *RecoveryCodeRepository) Use(ctx context.Context, userID int, codeHash string, usedAt time.Time) (bool, error) {
+	result := r.Database.Model(&entity.RecoveryCode{}).WithContext(ctx).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		UpdateColumn("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *RecoveryCodeRepository) CountUnused(ctx context.Context, userID int) (int64, error) {
+	var count int64
+	err := r.Database.Model(&entity.RecoveryCode{}).WithContext(ctx).
+		Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *RecoveryCodeRepository) DeleteAllByUserId(ctx context.Context, userID int) error {
+	return r.Database.WithContext(ctx).Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type OneTimeCodeRepositoryInterface interface {
+	Save(ctx context.Context, code *entity.OneTimeCode) error
+	FindLatest(ctx context.Context, userID int, purpose string) (*entity.OneTimeCode, error)
+	CountSince(ctx context.Context, userID int, purpose string, since time.Time) (int64, error)
+	CountByDestinationSince(ctx context.Context, destination string, since time.Time) (int64, error)
+	RegisterAttempt(ctx context.Context, id int, maxAttempts int) (bool, error)
+	MarkUsed(ctx context.Context, id int, usedAt time.Time) (bool, error)
+	DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OneTimeCodeRepository struct {
+	Database *gorm.DB
+}
+
+func NewOneTimeCodeRepository(db *gorm.DB) *OneTimeCodeRepository {
+	return &OneTimeCodeRepository{
+		Database: db,
+	}
+}
+
+func (r *OneTimeCodeRepository) Save(ctx context.Context, code *entity.OneTimeCode) error {
+	return r.Database.WithContext(ctx).Create(code).Error
+}
+
+// FindLatest returns the last code sent for a purpose. Sending a new code makes the older ones useless
+func (r *OneTimeCodeRepository) FindLatest(ctx context.Context, userID int, purpose string) (*entity.OneTimeCode, error) {
+	code := new(entity.OneTimeCode)
+	err := r.Database.WithContext(ctx).Where("user_id = ? AND purpose = ?", userID, purpose).Order("id DESC").Take(code).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return code, nil
+}
+
+func (r *OneTimeCodeRepository) CountSince(ctx context.Context, userID int, purpose string, since time.Time) (int64, error) {
+	var count int64
+	err := r.Database.Model(&entity.OneTimeCode{}).WithContext(ctx).
+		Where("user_id = ? AND purpose = ? AND created_at >= ?", userID, purpose, since).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByDestinationSince counts the codes sent to an address or phone number, whatever the user and purpose
+func (r *OneTimeCodeRepository) CountByDestinationSince(ctx context.Context, destination string, since time.Time) (int64, error) {
+	var count int64
+	err := r.Database.Model(&entity.OneTimeCode{}).WithContext(ctx).
+		Where("destination = ? AND created_at >= ?", destination, since).
+		Count(&count).Error
+	return count, err
+}
+
+// RegisterAttempt counts a guess before the code is compared. It fails once the code has no attempts left,
+// even when guesses arrive at the same time
+func (r *OneTimeCodeRepository) RegisterAttempt(ctx context.Context, id int, maxAttempts int) (bool, error) {
+	result := r.Database.Model(&entity.OneTimeCode{}).WithContext(ctx).
+		Where("id = ? AND used_at IS NULL AND attempts < ?", id, maxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *OneTimeCodeRepository) MarkUsed(ctx context.Context, id int, usedAt time.Time) (bool, error) {
+	result := r.Database.Model(&entity.OneTimeCode{}).WithContext(ctx).
+		Where("id = ? AND used_at IS NULL", id).
+		UpdateColumn("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *OneTimeCodeRepository) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.Database.WithContext(ctx).Where("expires_at < ?", before).Delete(&entity.OneTimeCode{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepositoryInterface interface {
+	Save(ctx context.Context, history *entity.PasswordHistory) (*entity.PasswordHistory, error)
+	FindLatestByUserId(ctx context.Context, userID int, limit int) ([]*entity.PasswordHistory, error)
+	DeleteAllByUserIdExceptLatest(ctx context.Context, userID int, keep int) error
+}
+
+type PasswordHistoryRepository struct {
+	Database *gorm.DB
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{
+		Database: db,
+	}
+}
+
+func (r *PasswordHistoryRepository) Save(ctx context.Context, history *entity.PasswordHistory) (*entity.PasswordHistory, error) {
+	err := r.Database.Model(&entity.PasswordHistory{}).WithContext(ctx).Create(history).Error
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *PasswordHistoryRepository) FindLatestByUserId(ctx context.Context, userID int, limit int) ([]*entity.PasswordHistory, error) {
+	var histories []*entity.PasswordHistory
+	err := r.Database.Model(&entity.PasswordHistory{}).WithContext(ctx).
+		Where("user_id = ?", userID).Order("id DESC").Limit(limit).
+		Find(&histories).Error
+	if err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
+
+func (r *PasswordHistoryRepository) DeleteAllByUserIdExceptLatest(ctx context.Context, userID int, keep int) error {
+	var latest []int
+	err := r.Database.Model(&entity.PasswordHistory{}).WithContext(ctx).
+		Where("user_id = ?", userID).Order("id DESC").Limit(keep).
+		Pluck("id", &latest).Error
+	if err != nil {
+		return err
+	}
+
+	query := r.Database.WithContext(ctx).Where("user_id = ?", userID)
+	if len(latest) > 0 {
+		query = query.Where("id NOT IN ?", latest)
+	}
+	return query.Delete(&entity.PasswordHistory{}).Error
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+)
+
+type ProductRepositoryInterface interface {
+	FindAllByUserId(ctx context.Context, userID int) ([]*entity.Product, error)
+}
+
+type ProductRepository struct {
+	Database *gorm.DB
+}
+
+func NewProductRepository(db *gorm.DB) *ProductRepository {
+	return &ProductRepository{
+		Database: db,
+	}
+}
+
+func (r *ProductRepository) FindAllByUserId(ctx context.Context, userID int) ([]*entity.Product, error) {
+	var products []*entity.Product
+	err := r.Database.Model(&entity.Product{}).WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type SessionRepositoryInterface interface {
+	Save(ctx context.Context, session *entity.Session) (*entity.Session, error)
+	FindOneById(ctx context.Context, id string) (*entity.Session, error)
+	RevokeAllByUserId(ctx context.Context, userID int) error
+	FindAllByUserId(ctx context.Context, userID int) ([]*entity.Session, error)
+	UpdateAuthentication(ctx context.Context, session *entity.Session) error
+}
+
+type SessionRepository struct {
+	Database *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{
+		Database: db,
+	}
+}
+
+func (r *SessionRepository) Save(ctx context.Context, session *entity.Session) (*entity.Session, error) {
+	err := r.Database.Model(&entity.Session{}).WithContext(ctx).Create(session).Error
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *SessionRepository) FindOneById(ctx context.Context, id string) (*entity.Session, error) {
+	var session *entity.Session
+	err := r.Database.Model(&entity.Session{}).WithContext(ctx).First(&session, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			return nil, err
+		}
+
+	}
+
+	return session, nil
+}
+
+func (r *SessionRepository) RevokeAllByUserId(ctx context.Context, userID int) error {
+	err := r.Database.Model(&entity.Session{}).WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *SessionRepository) FindAllByUserId(ctx context.Context, userID int) ([]*entity.Session, error) {
+	var sessions []*entity.Session
+	err := r.Database.Model(&entity.Session{}).WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// UpdateAuthentication saves a re-authentication of the session
+func (r *SessionRepository) UpdateAuthentication(ctx context.Context, session *entity.Session) error {
+	return r.Database.Model(&entity.Session{}).WithContext(ctx).Where("id = ?", session.Id).Updates(map[string]interface{}{
+		"auth_time": session.AuthTime,
+		"amr":       session.Amr,
+		"acr":       session.Acr,
+	}).Error
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type TrustedDeviceRepositoryInterface interface {
+	Save(ctx context.Context, device *entity.TrustedDevice) error
+	FindOneByTokenHash(ctx context.Context, tokenHash string) (*entity.TrustedDevice, error)
+	FindAllActiveByUserId(ctx context.Context, userID int, now time.Time) ([]entity.TrustedDevice, error)
+	UpdateLastUsedAt(ctx context.Context, id int, usedAt time.Time) error
+	Delete(ctx context.Context, userID int, id int) (bool, error)
+	DeleteAllByUserId(ctx context.Context, userID int) error
+	DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type TrustedDeviceRepository struct {
+	Database *gorm.DB
+}
+
+func NewTrustedDeviceRepository(db *gorm.DB) *TrustedDeviceRepository {
+	return &TrustedDeviceRepository{
+		Database: db,
+	}
+}
+
+func (r *TrustedDeviceRepository) Save(ctx context.Context, device *entity.TrustedDevice) error {
+	return r.Database.WithContext(ctx).Create(device).Error
+}
+
+func (r *TrustedDeviceRepository) FindOneByTokenHash(ctx context.Context, tokenHash string) (*entity.TrustedDevice, error) {
+	device := new(entity.TrustedDevice)
+	err := r.Database.WithContext(ctx).Where("token_hash = ?", tokenHash).Take(device).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return device, nil
+}
+
+func (r *TrustedDeviceRepository) FindAllActiveByUserId(ctx context.Context, userID int, now time.Time) ([]entity.TrustedDevice, error) {
+	var devices []entity.TrustedDevice
+	err := r.Database.WithContext(ctx).Where("user_id = ? AND expires_at > ?", userID, now).Order("id").Find(&devices).Error
+	return devices, err
+}
+
+func (r *TrustedDeviceRepository) UpdateLastUsedAt(ctx context.Context, id int, usedAt time.Time) error {
+	return r.Database.Model(&entity.TrustedDevice{}).WithContext(ctx).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
+
+func (r *TrustedDeviceRepository) Delete(ctx context.Context, userID int, id int) (bool, error) {
+	result := r.Database.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&entity.TrustedDevice{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *TrustedDeviceRepository) DeleteAllByUserId(ctx context.Context, userID int) error {
+	return r.Database.WithContext(ctx).Where("user_id = ?", userID).Delete(&entity.TrustedDevice{}).Error
+}
+
+func (r *TrustedDeviceRepository) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.Database.WithContext(ctx).Where("expires_at < ?", before).Delete(&entity.TrustedDevice{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type UserImportRepositoryInterface interface {
+	SaveJob(ctx context.Context, job *entity.UserImportJob) (*entity.UserImportJob, error)
+	FindJobById(ctx context.Context, id int) (*entity.UserImportJob, error)
+	ClaimNextJob(ctx context.Context, leaseExpiresAt time.Time) (*entity.UserImportJob, error)
+	ClaimJob(ctx context.Context, id int, leaseExpiresAt time.Time) (bool, error)
+	UpdateJobProgress(ctx context.Context, job *entity.UserImportJob) error
+	SaveError(ctx context.Context, importError *entity.UserImportError) error
+	FindErrorsByJobId(ctx context.Context, jobID int, offset int, limit int) ([]*entity.UserImportError, int64, error)
+}
+
+type UserImportRepository struct {
+	Database *gorm.DB
+}
+
+func NewUserImportRepository(db *gorm.DB) *UserImportRepository {
+	return &UserImportRepository{
+		Database: db,
+	}
+}
+
+func (r *UserImportRepository) SaveJob(ctx context.Context, job *entity.UserImportJob) (*entity.UserImportJob, error) {
+	err := r.Database.Model(&entity.UserImportJob{}).WithContext(ctx).Create(job).Error
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *UserImportRepository) FindJobById(ctx context.Context, id int) (*entity.UserImportJob, error) {
+	var job *entity.UserImportJob
+	err := r.Database.Model(&entity.UserImportJob{}).WithContext(ctx).First(&job, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// ClaimNextJob takes the oldest unfinished job that no worker holds a lease on, or returns nil when there is none
+func (r *UserImportRepository) ClaimNextJob(ctx context.Context, leaseExpiresAt time.Time) (*entity.UserImportJob, error) {
+	for {
+		var job *entity.UserImportJob
+		err := r.Database.Model(&entity.UserImportJob{}).WithContext(ctx).
+			Where("status IN ?", []string{entity.UserImportStatusPending, entity.UserImportStatusRunning}).
+			Where("lease_expires_at IS NULL OR lease_expires_at < ?", time.Now()).
+			Order("id").First(&job).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		claimed, err := r.ClaimJob(ctx, job.Id, leaseExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		//another worker was faster, look for the next one
+		if !claimed {
+			continue
+		}
+
+		job.Status = entity.UserImportStatusRunning
+		job.LeaseExpiresAt = &leaseExpiresAt
+		return job, nil
+	}
+}
+
+// ClaimJob marks an unfinished job as running under a lease, unless another worker holds an active lease
+func (r *UserImportRepository) ClaimJob(ctx context.Context, id int, leaseExpiresAt time.Time) (bool, error) {
+	result := r.Database.Model(&entity.UserImportJob{}).WithContext(ctx).
+		Where("id = ? AND status IN ?", id, []string{entity.UserImportStatusPending, entity.UserImportStatusRunning}).
+		Where("lease_expires_at IS NULL OR lease_expires_at < ?", time.Now()).
+		Updates(map[string]interface{}{"status": entity.UserImportStatusRunning, "lease_expires_at": leaseExpiresAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UpdateJobProgress writes the counters, status and lease of the job
+func (r *UserImportRepository) UpdateJobProgress(ctx context.Context, job *entity.UserImportJob) error {
+	err := r.Database.Model(job).WithContext(ctx).
+		Select("status", "processed_rows", "imported_rows", "failed_rows", "error_message", "lease_expires_at", "finished_at").
+		Updates(job).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserImportRepository) SaveError(ctx context.Context, importError *entity.UserImportError) error {
+	err := r.Database.Model(&entity.UserImportError{}).WithContext(ctx).Create(importError).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserImportRepository) FindErrorsByJobId(ctx context.Context, jobID int, offset int, limit int) ([]*entity.UserImportError, int64, error) {
+	var total int64
+	err := r.Database.Model(&entity.UserImportError{}).WithContext(ctx).Where("job_id = ?", jobID).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var importErrors []*entity.UserImportError
+	err = r.Database.Model(&entity.UserImportError{}).WithContext(ctx).
+		Where("job_id = ?", jobID).Order("row_no").Offset(offset).Limit(limit).
+		Find(&importErrors).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return importErrors, total, nil
+}
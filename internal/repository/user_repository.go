@@ -5,12 +5,36 @@ import (
 	"errors"
 	"golang-authentication/internal/entity"
 	"gorm.io/gorm"
+	"time"
 )
 
 type UserRepositoryInterface interface {
 	Save(ctx context.Context, user *entity.User) (*entity.User, error)
 	DeleteById(ctx context.Context, id int) error
 	FindOneByEmail(ctx context.Context, email string) (*entity.User, error)
+	FindOneByExactEmail(ctx context.Context, email string) (*entity.User, error)
+	FindOneById(ctx context.Context, id int) (*entity.User, error)
+	Update(ctx context.Context, user *entity.User) (*entity.User, error)
+	FindOneByEmailWithDeleted(ctx context.Context, email string) (*entity.User, error)
+	FindOneByIdWithDeleted(ctx context.Context, id int) (*entity.User, error)
+	SoftDeleteById(ctx context.Context, id int) error
+	RestoreById(ctx context.Context, id int) error
+	DeleteSoftDeletedBefore(ctx context.Context, before time.Time) (int64, error)
+	UpdateSignInAttempts(ctx context.Context, user *entity.User) error
+	IncrementFailedSignInAttempts(ctx context.Context, user *entity.User) error
+	Lock(ctx context.Context, id int, maxAttempts int, lockedUntil time.Time) (bool, error)
+	UpdateStatus(ctx context.Context, user *entity.User) error
+	UpdateProfile(ctx context.Context, user *entity.User) error
+	FindOneByUsername(ctx context.Context, username string) (*entity.User, error)
+	FindOneByUsernameWithDeleted(ctx context.Context, username string) (*entity.User, error)
+	FindAllInBatches(ctx context.Context, batchSize int, process func(users []*entity.User) error) error
+	UpdateEmailCanonical(ctx context.Context, id int, emailCanonical string) error
+	UpdatePasswordChangeRequired(ctx context.Context, id int, required bool) error
+	UpdatePassword(ctx context.Context, id int, password string) error
+	ChangePassword(ctx context.Context, user *entity.User) error
+	UpdateLastLoginAt(ctx context.Context, id int, lastLoginAt time.Time) error
+	UpdateMfaEnabled(ctx context.Context, id int, enabled bool) error
+	UpdatePhoneNumber(ctx context.Context, id int, phoneNumber *string) error
 }
 
 type UserRepository struct {
@@ -32,17 +56,190 @@ func (r *UserRepository) Save(ctx context.Context, user *entity.User) (*entity.U
 
 }
 
+// DeleteById permanently removes the user, including a soft-deleted one
 func (r *UserRepository) DeleteById(ctx context.Context, id int) error {
-	err := r.Database.WithContext(ctx).Delete(&entity.User{}, id).Error
+	err := r.Database.WithContext(ctx).Unscoped().Delete(&entity.User{}, id).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// FindOneByEmail expects the canonical email, see helpers.EmailNormalizer
 func (r *UserRepository) FindOneByEmail(ctx context.Context, email string) (*entity.User, error) {
+	var user *entity.User
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).First(&user, "email_canonical = ?", email).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			return nil, err
+		}
+
+	}
+
+	return user, nil
+}
+
+// FindOneByExactEmail matches the email as stored rather than its canonical form
+func (r *UserRepository) FindOneByExactEmail(ctx context.Context, email string) (*entity.User, error) {
 	var user *entity.User
 	err := r.Database.Model(&entity.User{}).WithContext(ctx).First(&user, "email = ?", email).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *UserRepository) FindOneById(ctx context.Context, id int) (*entity.User, error) {
+	var user *entity.User
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).First(&user, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			return nil, err
+		}
+
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *entity.User) (*entity.User, error) {
+	err := r.Database.WithContext(ctx).Save(user).Error
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// FindOneByEmailWithDeleted expects the canonical email and also returns users that are soft-deleted and waiting to be purged
+func (r *UserRepository) FindOneByEmailWithDeleted(ctx context.Context, email string) (*entity.User, error) {
+	var user *entity.User
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Unscoped().First(&user, "email_canonical = ?", email).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			return nil, err
+		}
+
+	}
+
+	return user, nil
+}
+
+// FindOneByIdWithDeleted also returns a user that is soft-deleted and waiting to be purged
+func (r *UserRepository) FindOneByIdWithDeleted(ctx context.Context, id int) (*entity.User, error) {
+	var user *entity.User
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Unscoped().First(&user, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			return nil, err
+		}
+
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) SoftDeleteById(ctx context.Context, id int) error {
+	err := r.Database.WithContext(ctx).Delete(&entity.User{}, id).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserRepository) RestoreById(ctx context.Context, id int) error {
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Unscoped().Where("id = ?", id).Update("deleted_at", nil).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteSoftDeletedBefore permanently removes every user that was soft-deleted before the given time
+func (r *UserRepository) DeleteSoftDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.Database.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&entity.User{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// UpdateSignInAttempts only writes the lockout columns, so it never overwrites a concurrent profile change
+func (r *UserRepository) UpdateSignInAttempts(ctx context.Context, user *entity.User) error {
+	err := r.Database.Model(user).WithContext(ctx).
+		Select("failed_sign_in_attempts", "lockout_count", "locked_until").
+		Updates(user).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// IncrementFailedSignInAttempts counts a failed attempt in the database, so concurrent attempts are all counted, and
+// reads the lockout columns back into user
+func (r *UserRepository) IncrementFailedSignInAttempts(ctx context.Context, user *entity.User) error {
+	return r.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.User{}).Where("id = ?", user.Id).
+			UpdateColumn("failed_sign_in_attempts", gorm.Expr("failed_sign_in_attempts + 1")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&entity.User{}).Select("failed_sign_in_attempts", "lockout_count").
+			Where("id = ?", user.Id).Take(user).Error
+	})
+}
+
+// Lock starts a new lockout until lockedUntil when the user still has maxAttempts failed attempts or more. It returns
+// false when a concurrent attempt already locked the account
+func (r *UserRepository) Lock(ctx context.Context, id int, maxAttempts int, lockedUntil time.Time) (bool, error) {
+	result := r.Database.Model(&entity.User{}).WithContext(ctx).
+		Where("id = ? AND failed_sign_in_attempts >= ?", id, maxAttempts).
+		UpdateColumns(map[string]interface{}{
+			"failed_sign_in_attempts": 0,
+			"lockout_count":           gorm.Expr("lockout_count + 1"),
+			"locked_until":            lockedUntil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UpdateStatus only writes the status columns, so it never overwrites a concurrent profile change
+func (r *UserRepository) UpdateStatus(ctx context.Context, user *entity.User) error {
+	err := r.Database.Model(user).WithContext(ctx).
+		Select("status", "status_reason", "status_expires_at").
+		Updates(user).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateProfile only writes the columns a user edits on the profile, so a concurrent change to the rest is kept
+func (r *UserRepository) UpdateProfile(ctx context.Context, user *entity.User) error {
+	err := r.Database.Model(user).WithContext(ctx).
+		Select("name", "username", "email", "email_canonical").
+		Updates(user).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserRepository) FindOneByUsername(ctx context.Context, username string) (*entity.User, error) {
+	var user *entity.User
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).First(&user, "username = ?", username).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -54,3 +251,98 @@ func (r *UserRepository) FindOneByEmail(ctx context.Context, email string) (*ent
 
 	return user, nil
 }
+
+// FindOneByUsernameWithDeleted also returns users that are soft-deleted and waiting to be purged
+func (r *UserRepository) FindOneByUsernameWithDeleted(ctx context.Context, username string) (*entity.User, error) {
+	var user *entity.User
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Unscoped().First(&user, "username = ?", username).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		} else {
+			return nil, err
+		}
+
+	}
+
+	return user, nil
+}
+
+// FindAllInBatches walks every user, including soft-deleted ones, ordered by id
+func (r *UserRepository) FindAllInBatches(ctx context.Context, batchSize int, process func(users []*entity.User) error) error {
+	var users []*entity.User
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Unscoped().Order("id").
+		FindInBatches(&users, batchSize, func(tx *gorm.DB, batch int) error {
+			return process(users)
+		}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateEmailCanonical(ctx context.Context, id int, emailCanonical string) error {
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Unscoped().Where("id = ?", id).
+		UpdateColumn("email_canonical", emailCanonical).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdatePasswordChangeRequired(ctx context.Context, id int, required bool) error {
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Where("id = ?", id).
+		UpdateColumn("password_change_required", required).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdatePassword only writes the password hash, it is used to upgrade the hash of an unchanged password
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int, password string) error {
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Where("id = ?", id).
+		UpdateColumn("password", password).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ChangePassword only writes the password columns, so a concurrent change to the rest of the user is kept
+func (r *UserRepository) ChangePassword(ctx context.Context, user *entity.User) error {
+	err := r.Database.Model(user).WithContext(ctx).
+		Select("password", "password_changed_at", "password_change_required").
+		Updates(user).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateLastLoginAt(ctx context.Context, id int, lastLoginAt time.Time) error {
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Where("id = ?", id).
+		UpdateColumn("last_login_at", lastLoginAt).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateMfaEnabled(ctx context.Context, id int, enabled bool) error {
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Where("id = ?", id).
+		UpdateColumn("mfa_enabled", enabled).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdatePhoneNumber(ctx context.Context, id int, phoneNumber *string) error {
+	err := r.Database.Model(&entity.User{}).WithContext(ctx).Where("id = ?", id).
+		UpdateColumn("phone_number", phoneNumber).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-authentication/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type WebAuthnCredentialRepositoryInterface interface {
+	Save(ctx context.Context, credential *entity.WebAuthnCredential) error
+	FindAllByUserId(ctx context.Context, userID int) ([]entity.WebAuthnCredential, error)
+	FindOneByCredentialIdHash(ctx context.Context, credentialIdHash string) (*entity.WebAuthnCredential, error)
+	CountByUserId(ctx context.Context, userID int) (int64, error)
+	UpdateSignCount(ctx context.Context, id int, signCount int64, usedAt time.Time) (bool, error)
+	Delete(ctx context.Context, userID int, id int) (bool, error)
+}
+
+type WebAuthnCredentialRepository struct {
+	Database *gorm.DB
+}
+
+func NewWebAuthnCredentialRepository(db *gorm.DB) *WebAuthnCredentialRepository {
+	return &WebAuthnCredentialRepository{
+		Database: db,
+	}
+}
+
+func (r *WebAuthnCredentialRepository) Save(ctx context.Context, credential *entity.WebAuthnCredential) error {
+	return r.Database.WithContext(ctx).Create(credential).Error
+}
+
+func (r *WebAuthnCredentialRepository) FindAllByUserId(ctx context.Context, userID int) ([]entity.WebAuthnCredential, error) {
+	var credentials []entity.WebAuthnCredential
+	err := r.Database.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&credentials).Error
+	return credentials, err
+}
+
+func (r *WebAuthnCredentialRepository) FindOneByCredentialIdHash(ctx context.Context, credentialIdHash string) (*entity.WebAuthnCredential, error) {
+	credential := new(entity.WebAuthnCredential)
+	err := r.Database.WithContext(ctx).Where("credential_id_hash = ?", credentialIdHash).Take(credential).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (r *WebAuthnCredentialRepository) CountByUserId(ctx context.Context, userID int) (int64, error) {
+	var count int64
+	err := r.Database.WithContext(ctx).Model(&entity.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// UpdateSignCount only succeeds while the counter moves forward, or stays at zero for authenticators that don't
+// count. A counter going back means the credential was probably cloned
+func (r *WebAuthnCredentialRepository) UpdateSignCount(ctx context.Context, id int, signCount int64, usedAt time.Time) (bool, error) {
+	result := r.Database.Model(&entity.WebAuthnCredential{}).WithContext(ctx).
+		Where("id = ? AND (sign_count < ? OR (sign_count = 0 AND ? = 0))", id, signCount, signCount).
+		UpdateColumns(map[string]interface{}{"sign_count": signCount, "last_used_at": usedAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *WebAuthnCredentialRepository) Delete(ctx context.Context, userID int, id int) (bool, error) {
+	result := r.Database.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&entity.WebAuthnCredential{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+type WebAuthnChallengeRepositoryInterface interface {
+	Save(ctx context.Context, challenge *entity.WebAuthnChallenge) error
+	FindOneByChallengeHash(ctx context.Context, challengeHash string) (*entity.WebAuthnChallenge, error)
+	MarkUsed(ctx context.Context, id int, usedAt time.Time) (bool, error)
+	DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebAuthnChallengeRepository struct {
+	Database *gorm.DB
+}
+
+func NewWebAuthnChallengeRepository(db *gorm.DB) *WebAuthnChallengeRepository {
+	return &WebAuthnChallengeRepository{
+		Database: db,
+	}
+}
+
+func (r *WebAuthnChallengeRepository) Save(ctx context.Context, challenge *entity.WebAuthnChallenge) error {
+	return r.Database.WithContext(ctx).Create(challenge).Error
+}
+
+func (r *WebAuthnChallengeRepository) FindOneByChallengeHash(ctx context.Context, challengeHash string) (*entity.WebAuthnChallenge, error) {
+	challenge := new(entity.WebAuthnChallenge)
+	err := r.Database.WithContext(ctx).Where("challenge_hash = ?", challengeHash).Take(challenge).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// MarkUsed only succeeds for the first caller, so a ceremony response can't be replayed
+func (r *WebAuthnChallengeRepository) MarkUsed(ctx context.Context, id int, usedAt time.Time) (bool, error) {
+	result := r.Database.Model(&entity.WebAuthnChallenge{}).WithContext(ctx).
+		Where("id = ? AND used_at IS NULL", id).
+		UpdateColumn("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *WebAuthnChallengeRepository) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.Database.WithContext(ctx).Where("expires_at < ?", before).Delete(&entity.WebAuthnChallenge{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,90 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/helpers"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type BreachCheckerInterface interface {
+	IsBreached(password string) (bool, error)
+}
+
+// NewBreachChecker returns a checker for password.breach.dataset_directory, or one that never matches when disabled
+func NewBreachChecker(viper *viper.Viper) BreachCheckerInterface {
+	if !viper.GetBool("password.breach.enabled") {
+		return &NoopBreachChecker{}
+	}
+
+	return &RangeBreachChecker{
+		Directory: helpers.ResolveConfigPath(viper, viper.GetString("password.breach.dataset_directory")),
+		MinCount:  viper.GetInt("password.breach.min_count"),
+	}
+}
+
+type NoopBreachChecker struct{}
+
+func (c *NoopBreachChecker) IsBreached(password string) (bool, error) {
+	return false, nil
+}
+
+// RangeBreachChecker reads a local copy of the Have I Been Pwned range dataset. The directory holds one file
+// per 5 character SHA-1 prefix, named PREFIX or PREFIX.txt, with one SUFFIX:COUNT line per breached hash
+type RangeBreachChecker struct {
+	Directory string
+	MinCount  int
+}
+
+func (c *RangeBreachChecker) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := c.openRange(prefix)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, _ := strings.Cut(line, ":")
+		if !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+
+		occurrences, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil {
+			occurrences = 1
+		}
+		return occurrences >= c.MinCount, nil
+	}
+
+	return false, scanner.Err()
+}
+
+func (c *RangeBreachChecker) openRange(prefix string) (*os.File, error) {
+	for _, name := range []string{prefix + ".txt", prefix, strings.ToLower(prefix) + ".txt", strings.ToLower(prefix)} {
+		file, err := os.Open(filepath.Join(c.Directory, name))
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("open breach range %s: %w", prefix, err)
+		}
+	}
+	return nil, fs.ErrNotExist
+}
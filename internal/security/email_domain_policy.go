@@ -0,0 +1,130 @@
+package security
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"os"
+	"strings"
+)
+
+const (
+	EmailDomainNotAllowed = "email_domain_not_allowed"
+	EmailDomainDenied     = "email_domain_denied"
+	EmailDomainDisposable = "email_domain_disposable"
+)
+
+// EmailDomainPolicy decides which email domains can sign up. A pattern is either a domain, matching only itself,
+// or "*." followed by a domain, matching its subdomains
+type EmailDomainPolicy struct {
+	Allow             []string
+	Deny              []string
+	BlockDisposable   bool
+	DisposableDomains map[string]struct{}
+}
+
+// NewEmailDomainPolicy reads email.domains from the config. The disposable domain list is only loaded when
+// block_disposable is enabled, and it must exist then
+func NewEmailDomainPolicy(viper *viper.Viper) (*EmailDomainPolicy, error) {
+	policy := &EmailDomainPolicy{
+		Allow:             normalizeDomainPatterns(viper.GetStringSlice("email.domains.allow")),
+		Deny:              normalizeDomainPatterns(viper.GetStringSlice("email.domains.deny")),
+		BlockDisposable:   viper.GetBool("email.domains.block_disposable"),
+		DisposableDomains: map[string]struct{}{},
+	}
+	if !policy.BlockDisposable {
+		return policy, nil
+	}
+
+	file := viper.GetString("email.domains.disposable_domains_file")
+	if file == "" {
+		return nil, fmt.Errorf("email.domains.disposable_domains_file is required to block disposable domains")
+	}
+	err := policy.LoadDisposableDomains(helpers.ResolveConfigPath(viper, file))
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// LoadDisposableDomains reads one domain per line, lines starting with # are comments. A listed domain also
+// covers its subdomains, throwaway providers hand them out freely
+func (p *EmailDomainPolicy) LoadDisposableDomains(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open disposable domain list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		domain := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if domain != "" && !strings.HasPrefix(domain, "#") {
+			p.DisposableDomains[domain] = struct{}{}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Check returns the rule the domain of the email breaks, or nil. The deny list wins over the allow list, and a
+// domain on a non empty allow list is trusted even when it is listed as disposable
+func (p *EmailDomainPolicy) Check(email string) *models.ErrorDetail {
+	at := strings.LastIndex(email, "@")
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(email[at+1:])), ".")
+
+	if matchesDomainPattern(p.Deny, domain) {
+		return &models.ErrorDetail{Code: EmailDomainDenied, Message: "Email domain is not allowed"}
+	}
+	if len(p.Allow) > 0 {
+		if matchesDomainPattern(p.Allow, domain) {
+			return nil
+		}
+		return &models.ErrorDetail{Code: EmailDomainNotAllowed, Message: "Email domain is not allowed"}
+	}
+	if p.BlockDisposable && p.isDisposable(domain) {
+		return &models.ErrorDetail{Code: EmailDomainDisposable, Message: "Disposable email addresses are not allowed"}
+	}
+	return nil
+}
+
+func (p *EmailDomainPolicy) isDisposable(domain string) bool {
+	for {
+		if _, ok := p.DisposableDomains[domain]; ok {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+func matchesDomainPattern(patterns []string, domain string) bool {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(domain, pattern[1:]) {
+				return true
+			}
+			continue
+		}
+		if domain == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeDomainPatterns(patterns []string) []string {
+	normalized := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern != "" {
+			normalized = append(normalized, pattern)
+		}
+	}
+	return normalized
+}
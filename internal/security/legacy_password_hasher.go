@@ -0,0 +1,270 @@
+package security
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// ErrVerifyOnly is returned when hashing with an algorithm that is only kept to verify imported hashes
+var ErrVerifyOnly = errors.New("password hasher can only verify existing hashes")
+
+// Limits of the legacy hash parameters, a hash asking for more memory or CPU than this is refused
+const (
+	scryptMaxMemory      = 256 << 20
+	scryptMaxParallelism = 16
+	pbkdf2MaxIterations  = 1000000
+	legacyMinSaltLength  = 8
+	legacyMaxSaltLength  = 64
+)
+
+// ScryptHasher verifies $scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash> hashes, as written by passlib
+type ScryptHasher struct{}
+
+func (h *ScryptHasher) Hash(password string) (string, error) {
+	return "", ErrVerifyOnly
+}
+
+func (h *ScryptHasher) Verify(encodedHash string, password string) (bool, error) {
+	params, err := h.decode(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := scrypt.Key([]byte(password), params.salt, 1<<params.logN, params.r, params.p, len(params.key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+func (h *ScryptHasher) NeedsRehash(encodedHash string) bool {
+	return true
+}
+
+func (h *ScryptHasher) Supports(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$scrypt$")
+}
+
+func (h *ScryptHasher) Check(encodedHash string) error {
+	_, err := h.decode(encodedHash)
+	return err
+}
+
+type scryptParams struct {
+	logN int
+	r    int
+	p    int
+	salt []byte
+	key  []byte
+}
+
+func (h *ScryptHasher) decode(encodedHash string) (*scryptParams, error) {
+	//"", "scrypt", "ln=...,r=...,p=...", salt, hash
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 5 || parts[1] != "scrypt" {
+		return nil, ErrUnknownHashFormat
+	}
+
+	params := &scryptParams{}
+	_, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &params.logN, &params.r, &params.p)
+	if err != nil || params.logN <= 0 || params.logN >= 32 || params.r <= 0 || params.p <= 0 {
+		return nil, fmt.Errorf("invalid scrypt parameters %q", parts[2])
+	}
+	//scrypt needs 128 * r * N bytes
+	if int64(params.r) > scryptMaxMemory/(128<<params.logN) {
+		return nil, fmt.Errorf("scrypt parameters %q need more than %d MiB", parts[2], scryptMaxMemory>>20)
+	}
+	if params.p > scryptMaxParallelism {
+		return nil, fmt.Errorf("scrypt parallelism %d out of range 1-%d", params.p, scryptMaxParallelism)
+	}
+
+	params.salt, err = decodeHashBase64(parts[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid scrypt salt: %w", err)
+	}
+	params.key, err = decodeHashBase64(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid scrypt hash: %w", err)
+	}
+	return params, checkSaltAndKey("scrypt", params.salt, params.key)
+}
+
+// Pbkdf2Hasher verifies $pbkdf2-<digest>$<iterations>$<salt>$<hash> hashes, as written by passlib, where digest is
+// sha1, sha256 or sha512. The PHC form with i=<iterations> is accepted as well
+type Pbkdf2Hasher struct{}
+
+func (h *Pbkdf2Hasher) Hash(password string) (string, error) {
+	return "", ErrVerifyOnly
+}
+
+func (h *Pbkdf2Hasher) Verify(encodedHash string, password string) (bool, error) {
+	params, err := h.decode(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	key := pbkdf2.Key([]byte(password), params.salt, params.iterations, len(params.key), params.digest)
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+func (h *Pbkdf2Hasher) NeedsRehash(encodedHash string) bool {
+	return true
+}
+
+func (h *Pbkdf2Hasher) Supports(encodedHash string) bool {
+	parts := strings.SplitN(encodedHash, "$", 3)
+	return len(parts) == 3 && parts[0] == "" && pbkdf2Digest(parts[1]) != nil
+}
+
+func (h *Pbkdf2Hasher) Check(encodedHash string) error {
+	_, err := h.decode(encodedHash)
+	return err
+}
+
+type pbkdf2Params struct {
+	digest     func() hash.Hash
+	iterations int
+	salt       []byte
+	key        []byte
+}
+
+func (h *Pbkdf2Hasher) decode(encodedHash string) (*pbkdf2Params, error) {
+	//"", "pbkdf2-sha256", "29000", salt, hash
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 5 {
+		return nil, ErrUnknownHashFormat
+	}
+
+	params := &pbkdf2Params{digest: pbkdf2Digest(parts[1])}
+	if params.digest == nil {
+		return nil, ErrUnknownHashFormat
+	}
+
+	var err error
+	params.iterations, err = strconv.Atoi(strings.TrimPrefix(parts[2], "i="))
+	if err != nil || params.iterations <= 0 {
+		return nil, fmt.Errorf("invalid pbkdf2 iterations %q", parts[2])
+	}
+	if params.iterations > pbkdf2MaxIterations {
+		return nil, fmt.Errorf("pbkdf2 iterations %d out of range 1-%d", params.iterations, pbkdf2MaxIterations)
+	}
+
+	params.salt, err = decodeHashBase64(parts[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid pbkdf2 salt: %w", err)
+	}
+	params.key, err = decodeHashBase64(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid pbkdf2 hash: %w", err)
+	}
+	return params, checkSaltAndKey("pbkdf2", params.salt, params.key)
+}
+
+func pbkdf2Digest(identifier string) func() hash.Hash {
+	switch identifier {
+	case "pbkdf2", "pbkdf2-sha1":
+		return sha1.New
+	case "pbkdf2-sha256":
+		return sha256.New
+	case "pbkdf2-sha512":
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+// SaltedShaHasher verifies the LDAP {SSHA}, {SSHA256} and {SSHA512} schemes: base64 of the digest of the password
+// and the salt, followed by the salt
+type SaltedShaHasher struct{}
+
+var saltedShaSchemes = map[string]func() hash.Hash{
+	"{SSHA}":    sha1.New,
+	"{SSHA256}": sha256.New,
+	"{SSHA512}": sha512.New,
+}
+
+func (h *SaltedShaHasher) Hash(password string) (string, error) {
+	return "", ErrVerifyOnly
+}
+
+func (h *SaltedShaHasher) Verify(encodedHash string, password string) (bool, error) {
+	sum, expected, salt, err := h.decode(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	sum.Write([]byte(password))
+	sum.Write(salt)
+	return subtle.ConstantTimeCompare(sum.Sum(nil), expected) == 1, nil
+}
+
+func (h *SaltedShaHasher) NeedsRehash(encodedHash string) bool {
+	return true
+}
+
+func (h *SaltedShaHasher) Supports(encodedHash string) bool {
+	for scheme := range saltedShaSchemes {
+		if strings.HasPrefix(strings.ToUpper(encodedHash), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *SaltedShaHasher) Check(encodedHash string) error {
+	_, _, _, err := h.decode(encodedHash)
+	return err
+}
+
+// decode returns an empty digest of the scheme, the expected digest and the salt
+func (h *SaltedShaHasher) decode(encodedHash string) (hash.Hash, []byte, []byte, error) {
+	for scheme, digest := range saltedShaSchemes {
+		if !strings.HasPrefix(strings.ToUpper(encodedHash), scheme) {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(encodedHash[len(scheme):])
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid %s hash: %w", scheme, err)
+		}
+
+		sum := digest()
+		if len(decoded) <= sum.Size() {
+			return nil, nil, nil, fmt.Errorf("%s hash has no salt", scheme)
+		}
+		if len(decoded)-sum.Size() > legacyMaxSaltLength {
+			return nil, nil, nil, fmt.Errorf("%s salt is longer than %d bytes", scheme, legacyMaxSaltLength)
+		}
+		return sum, decoded[:sum.Size()], decoded[sum.Size():], nil
+	}
+
+	return nil, nil, nil, ErrUnknownHashFormat
+}
+
+// checkSaltAndKey refuses a short salt, and a key too short to be compared or so long it multiplies the work
+func checkSaltAndKey(algorithm string, salt []byte, key []byte) error {
+	if len(salt) < legacyMinSaltLength || len(salt) > legacyMaxSaltLength {
+		return fmt.Errorf("%s salt length %d out of range %d-%d", algorithm, len(salt), legacyMinSaltLength, legacyMaxSaltLength)
+	}
+	if len(key) < minHashKeyLength || len(key) > maxHashKeyLength {
+		return fmt.Errorf("%s hash length %d out of range %d-%d", algorithm, len(key), minHashKeyLength, maxHashKeyLength)
+	}
+	return nil
+}
+
+// decodeHashBase64 accepts standard base64 with or without padding, and the "adapted" alphabet of passlib
+// that uses . instead of +
+func decodeHashBase64(value string) ([]byte, error) {
+	value = strings.TrimRight(strings.ReplaceAll(value, ".", "+"), "=")
+	return base64.RawStdEncoding.DecodeString(value)
+}
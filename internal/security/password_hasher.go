@@ -0,0 +1,366 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Limits of the Argon2id and bcrypt parameters read from a hash, used when password.hashing.*.max_* isn't set. A hash
+// asking for more is refused before any work is done, argon2.IDKey panics on some values and a huge memory or
+// iteration count would exhaust the server
+const (
+	DefaultArgon2idMaxMemory      = 262144
+	DefaultArgon2idMaxIterations  = 16
+	DefaultArgon2idMaxParallelism = 16
+	DefaultBcryptMaxCost          = 14
+)
+
+// Key length bounds of a hash. An empty key would match every password
+const (
+	minHashKeyLength = 16
+	maxHashKeyLength = 128
+)
+
+// PasswordHasherInterface hashes passwords into a self-describing string, so the algorithm and its parameters
+// can change without invalidating the stored hashes
+type PasswordHasherInterface interface {
+	Hash(password string) (string, error)
+	// Verify returns ErrUnknownHashFormat when the hash wasn't produced by this hasher
+	Verify(encodedHash string, password string) (bool, error)
+	// NeedsRehash reports whether the hash was produced with other parameters than the current ones
+	NeedsRehash(encodedHash string) bool
+	Supports(encodedHash string) bool
+	// Check parses the hash and returns an error when it is malformed or its cost parameters are out of bounds,
+	// Verify runs the same check before doing any work
+	Check(encodedHash string) error
+}
+
+// NewPasswordHasher hashes with password.hashing.algorithm and still verifies the hashes of the other algorithms,
+// including the legacy ones accepted by the user import
+func NewPasswordHasher(viper *viper.Viper) *PasswordHasher {
+	argon2idHasher := &Argon2idHasher{
+		Memory:         uint32(positiveOr(viper.GetInt("password.hashing.argon2id.memory_kib"), 19456)),
+		Iterations:     uint32(positiveOr(viper.GetInt("password.hashing.argon2id.iterations"), 2)),
+		Parallelism:    uint8(positiveOr(viper.GetInt("password.hashing.argon2id.parallelism"), 1)),
+		SaltLength:     16,
+		KeyLength:      32,
+		MaxMemory:      uint32(positiveOr(viper.GetInt("password.hashing.argon2id.max_memory_kib"), DefaultArgon2idMaxMemory)),
+		MaxIterations:  uint32(positiveOr(viper.GetInt("password.hashing.argon2id.max_iterations"), DefaultArgon2idMaxIterations)),
+		MaxParallelism: uint8(positiveOr(viper.GetInt("password.hashing.argon2id.max_parallelism"), DefaultArgon2idMaxParallelism)),
+	}
+	bcryptHasher := &BcryptHasher{
+		Cost:    viper.GetInt("password.hashing.bcrypt.cost"),
+		MaxCost: positiveOr(viper.GetInt("password.hashing.bcrypt.max_cost"), DefaultBcryptMaxCost),
+	}
+
+	hashers := []PasswordHasherInterface{argon2idHasher, bcryptHasher, &ScryptHasher{}, &Pbkdf2Hasher{}, &SaltedShaHasher{}}
+	hasher := &PasswordHasher{Hashers: hashers, Pepper: NewPepper(viper)}
+	if viper.GetString("password.hashing.algorithm") == AlgorithmBcrypt {
+		hasher.Current = bcryptHasher
+	} else {
+		hasher.Current = argon2idHasher
+	}
+	return hasher
+}
+
+func positiveOr(value int, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// PasswordHasher hashes new passwords with Current and verifies a hash with whichever hasher supports it.
+// When a Pepper is configured, the password is peppered before it is hashed
+type PasswordHasher struct {
+	Current PasswordHasherInterface
+	Hashers []PasswordHasherInterface
+	Pepper  *Pepper
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.Pepper == nil || h.Pepper.CurrentVersion == 0 {
+		return h.Current.Hash(password)
+	}
+
+	peppered, err := h.Pepper.Apply(h.Pepper.CurrentVersion, password)
+	if err != nil {
+		return "", err
+	}
+	innerHash, err := h.Current.Hash(peppered)
+	if err != nil {
+		return "", err
+	}
+	return h.Pepper.join(h.Pepper.CurrentVersion, innerHash), nil
+}
+
+func (h *PasswordHasher) Verify(encodedHash string, password string) (bool, error) {
+	version, innerHash, err := h.splitPepper(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	if version > 0 {
+		password, err = h.Pepper.Apply(version, password)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	hasher := h.hasherFor(innerHash)
+	if hasher == nil {
+		return false, ErrUnknownHashFormat
+	}
+	return hasher.Verify(innerHash, password)
+}
+
+// NeedsRehash is true when the hash uses another algorithm than Current, outdated parameters or another pepper version
+func (h *PasswordHasher) NeedsRehash(encodedHash string) bool {
+	version, innerHash, err := h.splitPepper(encodedHash)
+	if err != nil {
+		return true
+	}
+
+	currentVersion := 0
+	if h.Pepper != nil {
+		currentVersion = h.Pepper.CurrentVersion
+	}
+	return version != currentVersion || !h.Current.Supports(innerHash) || h.Current.NeedsRehash(innerHash)
+}
+
+func (h *PasswordHasher) Supports(encodedHash string) bool {
+	_, innerHash, err := h.splitPepper(encodedHash)
+	return err == nil && h.hasherFor(innerHash) != nil
+}
+
+// Check is used to refuse imported hashes that couldn't be verified later
+func (h *PasswordHasher) Check(encodedHash string) error {
+	_, innerHash, err := h.splitPepper(encodedHash)
+	if err != nil {
+		return err
+	}
+
+	hasher := h.hasherFor(innerHash)
+	if hasher == nil {
+		return ErrUnknownHashFormat
+	}
+	return hasher.Check(innerHash)
+}
+
+func (h *PasswordHasher) splitPepper(encodedHash string) (int, string, error) {
+	if h.Pepper == nil {
+		return 0, encodedHash, nil
+	}
+	return h.Pepper.split(encodedHash)
+}
+
+func (h *PasswordHasher) hasherFor(encodedHash string) PasswordHasherInterface {
+	for _, hasher := range h.Hashers {
+		if hasher.Supports(encodedHash) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+// Argon2idHasher encodes hashes in the PHC string format: $argon2id$v=19$m=<kib>,t=<iterations>,p=<parallelism>$<salt>$<hash>.
+// A hash with parameters above MaxMemory, MaxIterations or MaxParallelism is refused, the defaults apply when unset.
+// The parameters of new hashes are always allowed
+type Argon2idHasher struct {
+	Memory         uint32
+	Iterations     uint32
+	Parallelism    uint8
+	SaltLength     int
+	KeyLength      uint32
+	MaxMemory      uint32
+	MaxIterations  uint32
+	MaxParallelism uint8
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(encodedHash string, password string) (bool, error) {
+	params, err := h.decode(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, err := h.decode(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.memory != h.Memory || params.iterations != h.Iterations || params.parallelism != h.Parallelism ||
+		uint32(len(params.key)) != h.KeyLength
+}
+
+func (h *Argon2idHasher) Supports(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$argon2id$")
+}
+
+func (h *Argon2idHasher) Check(encodedHash string) error {
+	_, err := h.decode(encodedHash)
+	return err
+}
+
+// decode parses the hash and checks its parameters against the limits, so it is safe to pass them to argon2.IDKey
+func (h *Argon2idHasher) decode(encodedHash string) (*argon2idParams, error) {
+	//"", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2id version %q", parts[2])
+	}
+
+	params := &argon2idParams{}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism)
+	if err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters %q: %w", parts[3], err)
+	}
+
+	params.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	params.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+
+	err = h.checkLimits(params)
+	if err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+func (h *Argon2idHasher) checkLimits(params *argon2idParams) error {
+	maxMemory := limitOf(h.MaxMemory, h.Memory, DefaultArgon2idMaxMemory)
+	maxIterations := limitOf(h.MaxIterations, h.Iterations, DefaultArgon2idMaxIterations)
+	maxParallelism := limitOf(uint32(h.MaxParallelism), uint32(h.Parallelism), DefaultArgon2idMaxParallelism)
+
+	if params.iterations < 1 || params.iterations > maxIterations {
+		return fmt.Errorf("argon2id iterations %d out of range 1-%d", params.iterations, maxIterations)
+	}
+	if params.parallelism < 1 || uint32(params.parallelism) > maxParallelism {
+		return fmt.Errorf("argon2id parallelism %d out of range 1-%d", params.parallelism, maxParallelism)
+	}
+	//argon2 needs at least 8 KiB for each lane
+	if params.memory < 8*uint32(params.parallelism) || params.memory > maxMemory {
+		return fmt.Errorf("argon2id memory %d KiB out of range %d-%d", params.memory, 8*uint32(params.parallelism), maxMemory)
+	}
+	if len(params.key) < minHashKeyLength || len(params.key) > maxHashKeyLength {
+		return fmt.Errorf("argon2id hash length %d out of range %d-%d", len(params.key), minHashKeyLength, maxHashKeyLength)
+	}
+	return nil
+}
+
+// limitOf is the configured limit, or fallback when unset, raised to current so our own hashes always verify
+func limitOf(limit uint32, current uint32, fallback uint32) uint32 {
+	if limit == 0 {
+		limit = fallback
+	}
+	if current > limit {
+		return current
+	}
+	return limit
+}
+
+// BcryptHasher uses the $2a$/$2b$ modular crypt format, which already embeds the cost and the salt. A hash with a
+// cost above MaxCost is refused, DefaultBcryptMaxCost applies when unset
+type BcryptHasher struct {
+	Cost    int
+	MaxCost int
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), positiveOr(h.Cost, bcrypt.DefaultCost))
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+func (h *BcryptHasher) Verify(encodedHash string, password string) (bool, error) {
+	err := h.Check(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost != positiveOr(h.Cost, bcrypt.DefaultCost)
+}
+
+func (h *BcryptHasher) Supports(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") || strings.HasPrefix(encodedHash, "$2b$") || strings.HasPrefix(encodedHash, "$2y$")
+}
+
+func (h *BcryptHasher) Check(encodedHash string) error {
+	//$2a$10$ followed by 22 characters of salt and 31 of hash
+	if len(encodedHash) != 60 {
+		return fmt.Errorf("invalid bcrypt hash length %d", len(encodedHash))
+	}
+
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	if err != nil {
+		return fmt.Errorf("invalid bcrypt hash: %w", err)
+	}
+	maxCost := int(limitOf(uint32(h.MaxCost), uint32(positiveOr(h.Cost, bcrypt.DefaultCost)), DefaultBcryptMaxCost))
+	if cost < bcrypt.MinCost || cost > maxCost {
+		return fmt.Errorf("bcrypt cost %d out of range %d-%d", cost, bcrypt.MinCost, maxCost)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package security
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	PasswordTooShort             = "password_too_short"
+	PasswordTooLong              = "password_too_long"
+	PasswordMissingUppercase     = "password_missing_uppercase"
+	PasswordMissingLowercase     = "password_missing_lowercase"
+	PasswordMissingDigit         = "password_missing_digit"
+	PasswordMissingSymbol        = "password_missing_symbol"
+	PasswordContainsPersonalInfo = "password_contains_personal_info"
+	PasswordTooCommon            = "password_too_common"
+	PasswordTooWeak              = "password_too_weak"
+)
+
+// parts of the personal info shorter than this are too common to ban, like "a" or "id"
+const personalInfoMinimumPartLength = 3
+
+type PasswordPolicy struct {
+	MinLength            int
+	MaxLength            int
+	RequireUppercase     bool
+	RequireLowercase     bool
+	RequireDigit         bool
+	RequireSymbol        bool
+	DisallowPersonalInfo bool
+	MinStrengthScore     int
+	CommonPasswords      map[string]struct{}
+}
+
+// NewPasswordPolicy reads password.policy from the config and loads the common password dictionary
+func NewPasswordPolicy(viper *viper.Viper) *PasswordPolicy {
+	policy := &PasswordPolicy{
+		MinLength:            viper.GetInt("password.policy.min_length"),
+		MaxLength:            viper.GetInt("password.policy.max_length"),
+		RequireUppercase:     viper.GetBool("password.policy.require_uppercase"),
+		RequireLowercase:     viper.GetBool("password.policy.require_lowercase"),
+		RequireDigit:         viper.GetBool("password.policy.require_digit"),
+		RequireSymbol:        viper.GetBool("password.policy.require_symbol"),
+		DisallowPersonalInfo: viper.GetBool("password.policy.disallow_personal_info"),
+		MinStrengthScore:     viper.GetInt("password.policy.min_strength_score"),
+		CommonPasswords:      map[string]struct{}{},
+	}
+
+	file := viper.GetString("password.policy.common_passwords_file")
+	if file != "" {
+		err := policy.LoadCommonPasswords(helpers.ResolveConfigPath(viper, file))
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return policy
+}
+
+// LoadCommonPasswords reads one password per line. Passwords are compared case-insensitively
+func (p *PasswordPolicy) LoadCommonPasswords(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open common password dictionary: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		password := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if password != "" {
+			p.CommonPasswords[password] = struct{}{}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Validate returns every rule the password breaks. personalInfo holds values the password must not contain,
+// like the name, email or username of the user
+func (p *PasswordPolicy) Validate(password string, personalInfo ...string) []models.ErrorDetail {
+	var violations []models.ErrorDetail
+	length := utf8.RuneCountInString(password)
+
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, models.ErrorDetail{Code: PasswordTooShort, Message: fmt.Sprintf("Password must be min %d", p.MinLength)})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, models.ErrorDetail{Code: PasswordTooLong, Message: fmt.Sprintf("Password must be max %d", p.MaxLength)})
+	}
+
+	classes := characterClasses(password)
+	if p.RequireUppercase && !classes.upper {
+		violations = append(violations, models.ErrorDetail{Code: PasswordMissingUppercase, Message: "Password must contain an uppercase letter"})
+	}
+	if p.RequireLowercase && !classes.lower {
+		violations = append(violations, models.ErrorDetail{Code: PasswordMissingLowercase, Message: "Password must contain a lowercase letter"})
+	}
+	if p.RequireDigit && !classes.digit {
+		violations = append(violations, models.ErrorDetail{Code: PasswordMissingDigit, Message: "Password must contain a digit"})
+	}
+	if p.RequireSymbol && !classes.symbol {
+		violations = append(violations, models.ErrorDetail{Code: PasswordMissingSymbol, Message: "Password must contain a symbol"})
+	}
+
+	if p.DisallowPersonalInfo && containsPersonalInfo(password, personalInfo) {
+		violations = append(violations, models.ErrorDetail{Code: PasswordContainsPersonalInfo, Message: "Password must not contain your name, username or email"})
+	}
+
+	if _, ok := p.CommonPasswords[strings.ToLower(password)]; ok {
+		violations = append(violations, models.ErrorDetail{Code: PasswordTooCommon, Message: "Password is too common"})
+	}
+
+	if StrengthScore(password) < p.MinStrengthScore {
+		violations = append(violations, models.ErrorDetail{Code: PasswordTooWeak, Message: "Password is too weak"})
+	}
+
+	return violations
+}
+
+// Entropy estimates the bits of entropy of a password from its length and the character classes it uses
+func Entropy(password string) float64 {
+	classes := characterClasses(password)
+	pool := 0
+	if classes.lower {
+		pool += 26
+	}
+	if classes.upper {
+		pool += 26
+	}
+	if classes.digit {
+		pool += 10
+	}
+	if classes.symbol {
+		pool += 33
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	//repeated characters add little, so only distinct characters count fully
+	distinct := map[rune]struct{}{}
+	for _, r := range password {
+		distinct[r] = struct{}{}
+	}
+	length := float64(len(distinct)) + float64(utf8.RuneCountInString(password)-len(distinct))/4
+
+	return length * math.Log2(float64(pool))
+}
+
+// StrengthScore maps the entropy to a score from 0 (very weak) to 4 (very strong)
+func StrengthScore(password string) int {
+	entropy := Entropy(password)
+	switch {
+	case entropy < 28:
+		return 0
+	case entropy < 36:
+		return 1
+	case entropy < 60:
+		return 2
+	case entropy < 80:
+		return 3
+	default:
+		return 4
+	}
+}
+
+type classes struct {
+	upper, lower, digit, symbol bool
+}
+
+func characterClasses(password string) classes {
+	var c classes
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			c.upper = true
+		case unicode.IsLower(r):
+			c.lower = true
+		case unicode.IsDigit(r):
+			c.digit = true
+		default:
+			c.symbol = true
+		}
+	}
+	return c
+}
+
+// containsPersonalInfo checks every value and every part of it split on spaces and email separators
+func containsPersonalInfo(password string, personalInfo []string) bool {
+	password = strings.ToLower(password)
+	for _, info := range personalInfo {
+		info = strings.ToLower(info)
+		if at := strings.LastIndex(info, "@"); at >= 0 {
+			info = info[:at]
+		}
+
+		parts := strings.FieldsFunc(info, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '.' || r == '_' || r == '-' || r == '+'
+		})
+		parts = append(parts, info)
+		for _, part := range parts {
+			if utf8.RuneCountInString(part) >= personalInfoMinimumPartLength && strings.Contains(password, part) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/spf13/viper"
+	"strconv"
+	"strings"
+)
+
+// pepperPrefix marks a peppered hash: $pepper$v=<version><inner hash>
+const pepperPrefix = "$pepper$v="
+
+// Pepper is a server-side secret mixed into every password with HMAC-SHA256 before hashing. The secret never
+// touches the database, so a leaked users table alone can't be cracked. Each secret has a version, and the hash
+// records the version it was made with, so an old secret keeps verifying until every hash is upgraded
+type Pepper struct {
+	// CurrentVersion is used for new hashes, 0 disables peppering
+	CurrentVersion int
+	Secrets        map[int][]byte
+}
+
+// NewPepper loads key.pepper.secrets, a map of version to secret. Each secret is read on its own so an environment
+// variable like AUTH_KEY_PEPPER_SECRETS_1 replaces the placeholder of config.json
+func NewPepper(viper *viper.Viper) *Pepper {
+	pepper := &Pepper{
+		CurrentVersion: viper.GetInt("key.pepper.current_version"),
+		Secrets:        map[int][]byte{},
+	}
+
+	for version := range viper.GetStringMapString("key.pepper.secrets") {
+		number, err := strconv.Atoi(version)
+		if err != nil || number <= 0 {
+			fmt.Println("Ignoring pepper with invalid version: ", version)
+			continue
+		}
+		pepper.Secrets[number] = []byte(viper.GetString("key.pepper.secrets." + version))
+	}
+
+	return pepper
+}
+
+// Apply returns the HMAC of the password with the secret of the version, encoded so it stays within the bcrypt
+// input limit
+func (p *Pepper) Apply(version int, password string) (string, error) {
+	secret, ok := p.Secrets[version]
+	if !ok {
+		return "", fmt.Errorf("no pepper secret for version %d", version)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// split returns the pepper version of the hash and the hash of the underlying algorithm. Hashes without a pepper
+// have version 0
+func (p *Pepper) split(encodedHash string) (int, string, error) {
+	if !strings.HasPrefix(encodedHash, pepperPrefix) {
+		return 0, encodedHash, nil
+	}
+
+	rest := strings.TrimPrefix(encodedHash, pepperPrefix)
+	end := strings.Index(rest, "$")
+	if end <= 0 {
+		return 0, "", ErrUnknownHashFormat
+	}
+
+	version, err := strconv.Atoi(rest[:end])
+	if err != nil {
+		return 0, "", ErrUnknownHashFormat
+	}
+	return version, rest[end:], nil
+}
+
+func (p *Pepper) join(version int, innerHash string) string {
+	return fmt.Sprintf("%s%d%s", pepperPrefix, version, innerHash)
+}
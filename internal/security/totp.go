@@ -0,0 +1,110 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// base32NoPadding is the encoding authenticator apps expect for TOTP secrets
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP generates and checks RFC 6238 time-based codes with HMAC-SHA1, the only algorithm every authenticator
+// app supports
+type TOTP struct {
+	Digits int
+	Period time.Duration
+	// Skew is how many periods before and after the current one are accepted, for clocks that drift
+	Skew int
+}
+
+func NewTOTP(skew int) *TOTP {
+	return &TOTP{Digits: 6, Period: 30 * time.Second, Skew: skew}
+}
+
+// GenerateTOTPSecret returns a random 160 bit secret encoded in base32
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(secret), nil
+}
+
+// Step is the number of periods since the Unix epoch
+func (t *TOTP) Step(at time.Time) int64 {
+	return at.Unix() / int64(t.Period/time.Second)
+}
+
+// Code returns the code of the period containing at
+func (t *TOTP) Code(secret string, at time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return t.codeAt(key, t.Step(at)), nil
+}
+
+// Verify checks the code against the periods around now and returns the matching step, so the caller can refuse
+// a code that was already used. It returns -1 when nothing matches
+func (t *TOTP) Verify(secret string, code string, now time.Time) (int64, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return -1, err
+	}
+	if len(code) != t.Digits {
+		return -1, nil
+	}
+
+	current := t.Step(now)
+	for offset := -t.Skew; offset <= t.Skew; offset++ {
+		step := current + int64(offset)
+		if subtle.ConstantTimeCompare([]byte(t.codeAt(key, step)), []byte(code)) == 1 {
+			return step, nil
+		}
+	}
+	return -1, nil
+}
+
+// ProvisioningURI is the otpauth:// URI shown as a QR code to add the account to an authenticator app
+func (t *TOTP) ProvisioningURI(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(t.Digits))
+	values.Set("period", fmt.Sprint(int(t.Period/time.Second)))
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+func (t *TOTP) codeAt(key []byte, step int64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	//dynamic truncation of RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < t.Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", t.Digits, value%modulo)
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	return base32NoPadding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+}
@@ -0,0 +1,347 @@
+package security
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrInvalidWebAuthnResponse = errors.New("invalid webauthn response")
+
+// COSE algorithms of the credential public keys
+const (
+	COSEAlgorithmES256 = -7
+	COSEAlgorithmEdDSA = -8
+	COSEAlgorithmRS256 = -257
+)
+
+// WebAuthnAlgorithms are offered at registration, in order of preference
+var WebAuthnAlgorithms = []int{COSEAlgorithmES256, COSEAlgorithmEdDSA, COSEAlgorithmRS256}
+
+const (
+	authenticatorFlagUserPresent   = 0x01
+	authenticatorFlagUserVerified  = 0x04
+	authenticatorFlagAttestedData  = 0x40
+	authenticatorDataMinimumLength = 37
+)
+
+// WebAuthn verifies the registration and authentication ceremonies of passkeys and security keys for one
+// relying party. Attestation statements are checked for consistency only, authenticators are not matched
+// against a trust list
+type WebAuthn struct {
+	// RPID is the domain the credentials are scoped to
+	RPID string
+	// Origins are the origins the ceremonies may run on, like https://example.com
+	Origins []string
+}
+
+func NewWebAuthn(rpID string, origins []string) *WebAuthn {
+	return &WebAuthn{RPID: rpID, Origins: origins}
+}
+
+// ClientData is the collected client data the browser signs over
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func ParseClientData(clientDataJSON []byte) (*ClientData, error) {
+	clientData := new(ClientData)
+	err := json.Unmarshal(clientDataJSON, clientData)
+	if err != nil || clientData.Challenge == "" {
+		return nil, fmt.Errorf("%w: malformed client data", ErrInvalidWebAuthnResponse)
+	}
+	return clientData, nil
+}
+
+// AuthenticatorData is what the authenticator signs. The credential fields are only set at registration
+type AuthenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	// PublicKey is the credential public key as a COSE_Key
+	PublicKey []byte
+}
+
+func (d *AuthenticatorData) UserPresent() bool {
+	return d.Flags&authenticatorFlagUserPresent != 0
+}
+
+func (d *AuthenticatorData) UserVerified() bool {
+	return d.Flags&authenticatorFlagUserVerified != 0
+}
+
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < authenticatorDataMinimumLength {
+		return nil, fmt.Errorf("%w: authenticator data is too short", ErrInvalidWebAuthnResponse)
+	}
+
+	authenticatorData := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if authenticatorData.Flags&authenticatorFlagAttestedData == 0 {
+		return authenticatorData, nil
+	}
+
+	rest := data[authenticatorDataMinimumLength:]
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("%w: attested credential data is too short", ErrInvalidWebAuthnResponse)
+	}
+	authenticatorData.AAGUID = rest[:16]
+	credentialIDLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if credentialIDLength == 0 || credentialIDLength > 1023 || len(rest) < credentialIDLength {
+		return nil, fmt.Errorf("%w: credential id has an invalid length", ErrInvalidWebAuthnResponse)
+	}
+	authenticatorData.CredentialID = rest[:credentialIDLength]
+	rest = rest[credentialIDLength:]
+
+	//extensions may follow the key, so its length is only known after decoding it
+	_, after, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed credential public key", ErrInvalidWebAuthnResponse)
+	}
+	authenticatorData.PublicKey = rest[:len(rest)-len(after)]
+
+	return authenticatorData, nil
+}
+
+// VerifyRegistration checks the response of navigator.credentials.create() for the expected challenge and
+// returns the authenticator data holding the new credential
+func (w *WebAuthn) VerifyRegistration(clientDataJSON []byte, attestationObject []byte, challenge string, requireUserVerification bool) (*AuthenticatorData, error) {
+	err := w.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrInvalidWebAuthnResponse)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrInvalidWebAuthnResponse)
+	}
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[interface{}]interface{})
+	rawAuthenticatorData, _ := attestation["authData"].([]byte)
+	if statement == nil || rawAuthenticatorData == nil {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrInvalidWebAuthnResponse)
+	}
+
+	authenticatorData, err := w.verifyAuthenticatorData(rawAuthenticatorData, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+	if authenticatorData.CredentialID == nil {
+		return nil, fmt.Errorf("%w: attested credential data is missing", ErrInvalidWebAuthnResponse)
+	}
+
+	publicKey, algorithm, err := parseCOSEKey(authenticatorData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), rawAuthenticatorData...), clientDataHash[:]...)
+	switch format {
+	case "none":
+		if len(statement) != 0 {
+			return nil, fmt.Errorf("%w: none attestation has a statement", ErrInvalidWebAuthnResponse)
+		}
+	case "packed":
+		err = verifyPackedAttestation(statement, signed, publicKey, algorithm)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: attestation format %q is not supported", ErrInvalidWebAuthnResponse, format)
+	}
+
+	return authenticatorData, nil
+}
+
+// VerifyAssertion checks the response of navigator.credentials.get() against the stored COSE public key of the
+// credential. The caller compares the returned sign count with the stored one
+func (w *WebAuthn) VerifyAssertion(clientDataJSON []byte, rawAuthenticatorData []byte, signature []byte, publicKey []byte, challenge string, requireUserVerification bool) (*AuthenticatorData, error) {
+	err := w.verifyClientData(clientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticatorData, err := w.verifyAuthenticatorData(rawAuthenticatorData, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+
+	key, algorithm, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), rawAuthenticatorData...), clientDataHash[:]...)
+	err = verifyCOSESignature(key, algorithm, signed, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return authenticatorData, nil
+}
+
+func (w *WebAuthn) verifyClientData(clientDataJSON []byte, ceremony string, challenge string) error {
+	clientData, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return err
+	}
+	if clientData.Type != ceremony {
+		return fmt.Errorf("%w: client data is not for %s", ErrInvalidWebAuthnResponse, ceremony)
+	}
+	if subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(challenge)) != 1 {
+		return fmt.Errorf("%w: challenge does not match", ErrInvalidWebAuthnResponse)
+	}
+	if clientData.CrossOrigin {
+		return fmt.Errorf("%w: cross origin ceremonies are not allowed", ErrInvalidWebAuthnResponse)
+	}
+	for _, origin := range w.Origins {
+		if clientData.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: origin %q is not allowed", ErrInvalidWebAuthnResponse, clientData.Origin)
+}
+
+func (w *WebAuthn) verifyAuthenticatorData(rawAuthenticatorData []byte, requireUserVerification bool) (*AuthenticatorData, error) {
+	authenticatorData, err := ParseAuthenticatorData(rawAuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(w.RPID))
+	if !bytes.Equal(authenticatorData.RPIDHash, rpIDHash[:]) {
+		return nil, fmt.Errorf("%w: credential belongs to another relying party", ErrInvalidWebAuthnResponse)
+	}
+	if !authenticatorData.UserPresent() {
+		return nil, fmt.Errorf("%w: user was not present", ErrInvalidWebAuthnResponse)
+	}
+	if requireUserVerification && !authenticatorData.UserVerified() {
+		return nil, fmt.Errorf("%w: user was not verified", ErrInvalidWebAuthnResponse)
+	}
+	return authenticatorData, nil
+}
+
+// verifyPackedAttestation checks the signature of a packed statement, made either by an attestation certificate
+// or by the credential itself
+func verifyPackedAttestation(statement map[interface{}]interface{}, signed []byte, credentialKey crypto.PublicKey, credentialAlgorithm int) error {
+	algorithm, ok := statement["alg"].(int64)
+	signature, _ := statement["sig"].([]byte)
+	if !ok || signature == nil {
+		return fmt.Errorf("%w: malformed packed attestation", ErrInvalidWebAuthnResponse)
+	}
+
+	certificates, ok := statement["x5c"].([]interface{})
+	if !ok {
+		if int(algorithm) != credentialAlgorithm {
+			return fmt.Errorf("%w: self attestation algorithm does not match the key", ErrInvalidWebAuthnResponse)
+		}
+		return verifyCOSESignature(credentialKey, credentialAlgorithm, signed, signature)
+	}
+
+	if len(certificates) == 0 {
+		return fmt.Errorf("%w: malformed attestation certificate", ErrInvalidWebAuthnResponse)
+	}
+	rawCertificate, _ := certificates[0].([]byte)
+	certificate, err := x509.ParseCertificate(rawCertificate)
+	if err != nil {
+		return fmt.Errorf("%w: malformed attestation certificate", ErrInvalidWebAuthnResponse)
+	}
+	return verifyCOSESignature(certificate.PublicKey, int(algorithm), signed, signature)
+}
+
+// parseCOSEKey reads an EC2 P-256, OKP Ed25519 or RSA COSE_Key and returns it with its algorithm
+func parseCOSEKey(data []byte) (crypto.PublicKey, int, error) {
+	invalidKey := fmt.Errorf("%w: unsupported credential public key", ErrInvalidWebAuthnResponse)
+
+	decoded, _, err := decodeCBOR(data)
+	if err != nil {
+		return nil, 0, invalidKey
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, invalidKey
+	}
+	keyType, _ := key[int64(1)].(int64)
+	algorithm, _ := key[int64(3)].(int64)
+
+	switch {
+	case keyType == 2 && algorithm == COSEAlgorithmES256:
+		curve, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if curve != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, invalidKey
+		}
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, 0, invalidKey
+		}
+		return publicKey, COSEAlgorithmES256, nil
+	case keyType == 1 && algorithm == COSEAlgorithmEdDSA:
+		curve, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		if curve != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, invalidKey
+		}
+		return ed25519.PublicKey(x), COSEAlgorithmEdDSA, nil
+	case keyType == 3 && algorithm == COSEAlgorithmRS256:
+		modulus, _ := key[int64(-1)].([]byte)
+		exponent, _ := key[int64(-2)].([]byte)
+		if len(modulus) < 256 || len(exponent) == 0 || len(exponent) > 4 {
+			return nil, 0, invalidKey
+		}
+		e := 0
+		for _, b := range exponent {
+			e = e<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: e}, COSEAlgorithmRS256, nil
+	}
+	return nil, 0, invalidKey
+}
+
+func verifyCOSESignature(key crypto.PublicKey, algorithm int, signed []byte, signature []byte) error {
+	invalidSignature := fmt.Errorf("%w: signature does not match", ErrInvalidWebAuthnResponse)
+	digest := sha256.Sum256(signed)
+
+	switch publicKey := key.(type) {
+	case *ecdsa.PublicKey:
+		if algorithm == COSEAlgorithmES256 && ecdsa.VerifyASN1(publicKey, digest[:], signature) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if algorithm == COSEAlgorithmEdDSA && ed25519.Verify(publicKey, signed, signature) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if algorithm == COSEAlgorithmRS256 && rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil {
+			return nil
+		}
+	}
+	return invalidSignature
+}
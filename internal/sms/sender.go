@@ -0,0 +1,77 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/helpers"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a text message to a phone number in E.164 format
+type Message struct {
+	To   string
+	Body string
+}
+
+// SMSSender delivers text messages. A provider is plugged in by implementing it and returning it from NewSMSSender
+type SMSSender interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// NewSMSSender builds the sender chosen by sms.driver: "file" or "console". Unknown drivers fall back to the
+// console so a development setup never fails to start because of SMS
+func NewSMSSender(viper *viper.Viper) SMSSender {
+	switch viper.GetString("sms.driver") {
+	case "file":
+		return &FileSender{Directory: helpers.ResolveConfigPath(viper, viper.GetString("sms.directory"))}
+	default:
+		return &ConsoleSender{}
+	}
+}
+
+// ConsoleSender prints the messages instead of sending them, for development
+type ConsoleSender struct{}
+
+func (s *ConsoleSender) Send(ctx context.Context, message *Message) error {
+	fmt.Print(format(message))
+	return nil
+}
+
+// FileSender appends the messages to one file per phone number, so tests and local setups can read them back
+type FileSender struct {
+	Directory string
+	mutex     sync.Mutex
+}
+
+func (s *FileSender) Send(ctx context.Context, message *Message) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := os.MkdirAll(s.Directory, 0o700)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.Path(message.To), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(format(message))
+	return err
+}
+
+// Path is the file holding the messages sent to a phone number
+func (s *FileSender) Path(to string) string {
+	return filepath.Join(s.Directory, strings.TrimPrefix(filepath.Base(to), "+")+".txt")
+}
+
+func format(message *Message) string {
+	return "To: " + message.To + "\nDate: " + time.Now().Format(time.RFC1123Z) + "\n\n" + message.Body + "\n\n"
+}
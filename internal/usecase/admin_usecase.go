@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"time"
+)
+
+type AdminUseCase struct {
+	UserRepository    repository.UserRepositoryInterface
+	SessionRepository repository.SessionRepositoryInterface
+	Validator         *validator.Validate
+	Viper             *viper.Viper
+}
+
+func NewAdminUseCase(userRepository repository.UserRepositoryInterface, sessionRepository repository.SessionRepositoryInterface, validator *validator.Validate, viper *viper.Viper) *AdminUseCase {
+	return &AdminUseCase{UserRepository: userRepository, SessionRepository: sessionRepository, Validator: validator, Viper: viper}
+}
+
+// RestoreUser cancels a pending account deletion
+func (u *AdminUseCase) RestoreUser(ctx context.Context, userID int) (*models.UserResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := u.UserRepository.FindOneByIdWithDeleted(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting user by id: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	if user == nil {
+		return nil, &models.ErrorResponse{Code: 404, Message: "User not found", Status: "Not Found"}
+	}
+
+	if !user.DeletedAt.Valid {
+		return nil, &models.ErrorResponse{Code: 400, Message: "User is not deleted", Status: "Bad Request"}
+	}
+
+	err = u.UserRepository.RestoreById(ctxWithTimeout, user.Id)
+	if err != nil {
+		fmt.Println("Error while restoring user: ", err)
+		return nil, toRepositoryError(err)
+	}
+	user.DeletedAt.Valid = false
+
+	return toUserResponse(user), nil
+}
+
+// UnlockUser lifts a sign in lockout and forgets the failed attempts
+func (u *AdminUseCase) UnlockUser(ctx context.Context, userID int) (*models.UserResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting user by id: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	if user == nil {
+		return nil, &models.ErrorResponse{Code: 404, Message: "User not found", Status: "Not Found"}
+	}
+
+	user.FailedSignInAttempts = 0
+	user.LockoutCount = 0
+	user.LockedUntil = nil
+	err = u.UserRepository.UpdateSignInAttempts(ctxWithTimeout, user)
+	if err != nil {
+		fmt.Println("Error while unlocking user: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	return toUserResponse(user), nil
+}
+
+// RequirePasswordChange makes the user change the password at the next sign in
+func (u *AdminUseCase) RequirePasswordChange(ctx context.Context, userID int) (*models.UserResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting user by id: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	if user == nil {
+		return nil, &models.ErrorResponse{Code: 404, Message: "User not found", Status: "Not Found"}
+	}
+
+	err = u.UserRepository.UpdatePasswordChangeRequired(ctxWithTimeout, user.Id, true)
+	if err != nil {
+		fmt.Println("Error while requiring password change: ", err)
+		return nil, toRepositoryError(err)
+	}
+	user.PasswordChangeRequired = true
+
+	return toUserResponse(user), nil
+}
+
+// UpdateUserStatus suspends, disables or reactivates a user. Every session of a user that is no longer active is revoked
+func (u *AdminUseCase) UpdateUserStatus(ctx context.Context, userID int, request *models.UpdateUserStatusRequest) (*models.UserResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.Validator.Struct(request)
+	if err != nil {
+		if e, ok := err.(validator.ValidationErrors); ok {
+			message := helpers.GetFirstValidationErrorsAndConvert(e)
+			return nil, &models.ErrorResponse{Code: 400, Message: message, Status: "Bad Request"}
+		}
+		fmt.Println("Server error while validating: ", err)
+		return nil, &models.ErrorResponse{Code: 500, Message: "Something wrong", Status: "Internal Server Error"}
+	}
+
+	if request.ExpiresAt != nil {
+		if request.Status != entity.UserStatusSuspended {
+			return nil, &models.ErrorResponse{Code: 400, Message: "ExpiresAt is only allowed for suspended status", Status: "Bad Request"}
+		}
+		if !request.ExpiresAt.After(time.Now()) {
+			return nil, &models.ErrorResponse{Code: 400, Message: "ExpiresAt must be in the future", Status: "Bad Request"}
+		}
+	}
+
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting user by id: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	if user == nil {
+		return nil, &models.ErrorResponse{Code: 404, Message: "User not found", Status: "Not Found"}
+	}
+
+	user.Status = request.Status
+	user.StatusReason = request.Reason
+	user.StatusExpiresAt = request.ExpiresAt
+	err = u.UserRepository.UpdateStatus(ctxWithTimeout, user)
+	if err != nil {
+		fmt.Println("Error while updating user status: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	if user.Status != entity.UserStatusActive {
+		err = u.SessionRepository.RevokeAllByUserId(ctxWithTimeout, user.Id)
+		if err != nil {
+			fmt.Println("Error while revoking sessions: ", err)
+			return nil, toRepositoryError(err)
+		}
+	}
+
+	return toUserResponse(user), nil
+}
+
+// PurgeDeletedUsers permanently removes the users whose deletion grace period is over
+func (u *AdminUseCase) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	before := time.Now().Add(-accountDeletionGracePeriod(u.Viper))
+
+	purged, err := u.UserRepository.DeleteSoftDeletedBefore(ctx, before)
+	if err != nil {
+		fmt.Println("Error while purging deleted users: ", err)
+		return 0, err
+	}
+
+	return purged, nil
+}
@@ -11,31 +11,101 @@ import (
 	"golang-authentication/internal/helpers"
 	"golang-authentication/internal/models"
 	"golang-authentication/internal/repository"
-	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"sync"
 	"time"
 )
 
+const RefreshTokenLifetime = 3 * (24 * time.Hour)
+
+// PasswordChangeTokenLifetime is how long a password change token returned by SignIn stays valid
+const PasswordChangeTokenLifetime = 15 * time.Minute
+
+// PasswordChangeScope is the scope of a token that can only be used to change the password
+const PasswordChangeScope = "password_change"
+
+// MfaChallengeLifetime is how long the second factor can be given after the password
+const MfaChallengeLifetime = 5 * time.Minute
+
+// TrustedDeviceCookie is the cookie remembering a device where the second factor was given
+const TrustedDeviceCookie = "trusted_device"
+
+// MfaChallengeScope is the scope of the token returned by SignIn when the user has MFA enabled. It can only be
+// exchanged for a session together with a second factor
+const MfaChallengeScope = "mfa_challenge"
+
+const (
+	PasswordChangeReasonRequired = "required"
+	PasswordChangeReasonExpired  = "expired"
+)
+
+// Authentication methods of the amr claim, as registered by RFC 8176. AmrEmail is ours, for a magic link
+const (
+	AmrPassword    = "pwd"
+	AmrOtp         = "otp"
+	AmrSms         = "sms"
+	AmrHardwareKey = "hwk"
+	AmrEmail       = "email"
+	AmrMfa         = "mfa"
+)
+
+// Assurance levels of the acr claim. Two factors, or a passkey verifying the user, make AcrMultiFactor
+const (
+	AcrSingleFactor = "aal1"
+	AcrMultiFactor  = "aal2"
+)
+
+var acrLevels = map[string]int{
+	AcrSingleFactor: 1,
+	AcrMultiFactor:  2,
+}
+
+// AccessClaims are the claims of a verified access token. AuthTime, Amr and Acr tell when and how the user last
+// proved who they are in the session, which can be long before the token was issued
+type AccessClaims struct {
+	UserId    int
+	SessionId string
+	AuthTime  time.Time
+	Amr       []string
+	Acr       string
+}
+
 type AuthUseCase struct {
-	UserRepository repository.UserRepositoryInterface
-	Validator      *validator.Validate
-	Viper          *viper.Viper
+	UserRepository         repository.UserRepositoryInterface
+	SessionRepository      repository.SessionRepositoryInterface
+	LoginAttemptRepository repository.LoginAttemptRepositoryInterface
+	TrustedDeviceRepo      repository.TrustedDeviceRepositoryInterface
+	Validator              *validator.Validate
+	Viper                  *viper.Viper
+	EmailNormalizer        *helpers.EmailNormalizer
+	PasswordUseCase        *PasswordUseCase
 }
 
-func NewAuthUseCase(userRepository repository.UserRepositoryInterface, validator *validator.Validate, viper *viper.Viper) *AuthUseCase {
+func NewAuthUseCase(userRepository repository.UserRepositoryInterface, sessionRepository repository.SessionRepositoryInterface, loginAttemptRepository repository.LoginAttemptRepositoryInterface, trustedDeviceRepository repository.TrustedDeviceRepositoryInterface, validator *validator.Validate, viper *viper.Viper) *AuthUseCase {
 	return &AuthUseCase{
-		UserRepository: userRepository,
-		Validator:      validator,
-		Viper:          viper,
+		UserRepository:         userRepository,
+		SessionRepository:      sessionRepository,
+		LoginAttemptRepository: loginAttemptRepository,
+		TrustedDeviceRepo:      trustedDeviceRepository,
+		Validator:              validator,
+		Viper:                  viper,
+		EmailNormalizer:        helpers.NewEmailNormalizer(viper),
+		PasswordUseCase:        NewPasswordUseCase(viper),
 	}
 }
-func (u *AuthUseCase) GenerateAccessToken(userID int) (string, error) {
+
+// GenerateAccessToken generates an access token of the session, carrying its authentication time and methods
+func (u *AuthUseCase) GenerateAccessToken(session *entity.Session) (string, error) {
 	key := u.Viper.GetString("key.token.access")
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"iss": "restful-api",
-		"sub": userID,
-		"exp": time.Now().Add(1 * time.Hour).Unix(),
+		"iss":       "restful-api",
+		"sub":       session.UserId,
+		"sid":       session.Id,
+		"auth_time": session.AuthTime.Unix(),
+		"amr":       session.AuthenticationMethods(),
+		"acr":       session.Acr,
+		"exp":       time.Now().Add(1 * time.Hour).Unix(),
 	})
 
 	token, err := jwtToken.SignedString([]byte(key))
@@ -52,13 +122,55 @@ func (u *AuthUseCase) GenerateAccessToken(userID int) (string, error) {
 
 }
 
-func (u *AuthUseCase) GenerateRefreshToken(userID int) (string, error) {
+// GeneratePasswordChangeToken generates an access token restricted to PasswordChangeScope
+func (u *AuthUseCase) GeneratePasswordChangeToken(userID int) (string, error) {
+	return u.generateScopedToken(userID, PasswordChangeScope, PasswordChangeTokenLifetime)
+}
+
+// GenerateMfaChallengeToken generates the token exchanged for a session once the second factor is verified. It
+// remembers the methods of the first factor for the session
+func (u *AuthUseCase) GenerateMfaChallengeToken(userID int, methods []string) (string, error) {
+	return u.generateScopedToken(userID, MfaChallengeScope, MfaChallengeLifetime, jwt.MapClaims{"amr": methods})
+}
+
+func (u *AuthUseCase) generateScopedToken(userID int, scope string, lifetime time.Duration, extraClaims ...jwt.MapClaims) (string, error) {
+	key := u.Viper.GetString("key.token.access")
+
+	claims := jwt.MapClaims{
+		"iss":   "restful-api",
+		"sub":   userID,
+		"scope": scope,
+		"exp":   time.Now().Add(lifetime).Unix(),
+	}
+	for _, extra := range extraClaims {
+		for name, value := range extra {
+			claims[name] = value
+		}
+	}
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	token, err := jwtToken.SignedString([]byte(key))
+	if err != nil {
+		fmt.Printf("Error while generate %s token : %v\n", scope, err)
+		return "", &models.ErrorResponse{
+			Code:    500,
+			Message: "Error while generate access token",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return token, nil
+}
+
+// GenerateRefreshToken binds the refresh token to a session, so it stops working once the session is revoked
+func (u *AuthUseCase) GenerateRefreshToken(userID int, sessionID string) (string, error) {
 	key := u.Viper.GetString("key.token.refresh")
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"iss": "restful-api",
 		"sub": userID,
-		"exp": time.Now().Add(3 * (24 * time.Hour)).Unix(),
+		"sid": sessionID,
+		"exp": time.Now().Add(RefreshTokenLifetime).Unix(),
 	})
 
 	token, err := jwtToken.SignedString([]byte(key))
@@ -88,27 +200,213 @@ func (u *AuthUseCase) ValidateRequest(request *models.SignInRequest) error {
 
 }
 
+// findUserByIdentifier looks the user up by email when the identifier contains an @, otherwise by username
+func (u *AuthUseCase) findUserByIdentifier(ctx context.Context, credential *models.SignInRequest) (*entity.User, error) {
+	identifier := strings.TrimSpace(credential.Identifier)
+	if identifier == "" {
+		identifier = credential.Email
+	}
+
+	if strings.Contains(identifier, "@") {
+		return u.findUserByEmail(ctx, identifier)
+	}
+	return u.UserRepository.FindOneByUsername(ctx, normalizeUsername(identifier))
+}
+
+// findUserByEmail looks the user up by canonical email. A user that kept the canonical email of older rules, because
+// it collides with another user under the current ones, is still found by the email as stored
+func (u *AuthUseCase) findUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	email = u.EmailNormalizer.Normalize(email)
+	user, err := u.UserRepository.FindOneByEmail(ctx, u.EmailNormalizer.Canonicalize(email))
+	if err != nil || (user != nil && user.Email == email) {
+		return user, err
+	}
+
+	exact, err := u.UserRepository.FindOneByExactEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if exact != nil {
+		return exact, nil
+	}
+	return user, nil
+}
+
 func (u *AuthUseCase) GetAndValidateUser(ctx context.Context, credential *models.SignInRequest) (*entity.User, error) {
-	user, err := u.UserRepository.FindOneByEmail(ctx, credential.Email)
+	user, _, err := u.validateCredential(ctx, credential)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// validateCredential checks the identifier and password. It also returns the outcome of the attempt for the login
+// history, and the user it was made against even when it failed
+func (u *AuthUseCase) validateCredential(ctx context.Context, credential *models.SignInRequest) (*entity.User, string, error) {
+	user, err := u.findUserByIdentifier(ctx, credential)
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
-			return nil, &models.ErrorResponse{Code: 408, Status: "Request Timeout", Message: "Request timeout. Please try again"}
+			return nil, entity.LoginOutcomeError, &models.ErrorResponse{Code: 408, Status: "Request Timeout", Message: "Request timeout. Please try again"}
 		}
-		return nil, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+		return nil, entity.LoginOutcomeError, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
 	}
 
 	if user == nil {
-		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Email or password invalid"}
+		return nil, entity.LoginOutcomeUnknownUser, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Email or password invalid"}
+	}
+
+	//a locked account doesn't even check the password, so it can't be used to keep guessing
+	if user.IsLocked(time.Now()) {
+		return user, entity.LoginOutcomeLocked, u.lockedError()
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(credential.Password))
+	if !u.PasswordUseCase.ComparePassword(user.Password, credential.Password) {
+		err = u.registerFailedSignIn(ctx, user)
+		if err != nil {
+			return user, entity.LoginOutcomeInvalidPassword, err
+		}
+		if user.IsLocked(time.Now()) {
+			return user, entity.LoginOutcomeLocked, u.lockedError()
+		}
+		return user, entity.LoginOutcomeInvalidPassword, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Email or password invalid"}
+	}
+
+	err = u.resetFailedSignIns(ctx, user)
 	if err != nil {
-		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Email or password invalid"}
+		return user, entity.LoginOutcomeError, err
 	}
 
-	return user, nil
+	u.rehashPassword(ctx, user, credential.Password)
+
+	err = u.flagBreachedPassword(ctx, user, credential.Password)
+	if err != nil {
+		return user, entity.LoginOutcomeError, err
+	}
+
+	return user, entity.LoginOutcomeSuccess, nil
+
+}
+
+// resetFailedSignIns clears the lockout counters after a successful sign in
+func (u *AuthUseCase) resetFailedSignIns(ctx context.Context, user *entity.User) error {
+	if user.FailedSignInAttempts == 0 && user.LockoutCount == 0 && user.LockedUntil == nil {
+		return nil
+	}
+
+	user.FailedSignInAttempts = 0
+	user.LockoutCount = 0
+	user.LockedUntil = nil
+	err := u.UserRepository.UpdateSignInAttempts(ctx, user)
+	if err != nil {
+		fmt.Println("Error while resetting sign in attempts: ", err)
+		return toRepositoryError(err)
+	}
+	return nil
+}
+
+// rehashPassword upgrades the hash of a verified password to the configured algorithm and parameters.
+// A failure is only logged, the old hash keeps working
+func (u *AuthUseCase) rehashPassword(ctx context.Context, user *entity.User, password string) {
+	if !u.PasswordUseCase.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashedPassword, err := u.PasswordUseCase.HashPassword(password)
+	if err != nil {
+		return
+	}
+
+	err = u.UserRepository.UpdatePassword(ctx, user.Id, hashedPassword)
+	if err != nil {
+		fmt.Println("Error while rehashing password: ", err)
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// flagBreachedPassword requires a password change when password.breach.flag_on_sign_in is enabled
+// and the password of an existing user turns up in the breach dataset
+func (u *AuthUseCase) flagBreachedPassword(ctx context.Context, user *entity.User, password string) error {
+	if user.PasswordChangeRequired || !u.Viper.GetBool("password.breach.flag_on_sign_in") {
+		return nil
+	}
+
+	breached, err := u.PasswordUseCase.IsBreached(password)
+	if err != nil || !breached {
+		return err
+	}
+
+	err = u.UserRepository.UpdatePasswordChangeRequired(ctx, user.Id, true)
+	if err != nil {
+		fmt.Println("Error while flagging breached password: ", err)
+		return toRepositoryError(err)
+	}
+	user.PasswordChangeRequired = true
+
+	return nil
+}
+
+// registerFailedSignIn locks the account once it reaches the maximum failed attempts.
+// Every lockout in a row doubles the lock duration, up to security.lockout.max_duration_seconds.
+// The counter is incremented in the database, so concurrent attempts can't overwrite each other
+func (u *AuthUseCase) registerFailedSignIn(ctx context.Context, user *entity.User) error {
+	maxAttempts := u.Viper.GetInt("security.lockout.max_attempts")
+	if maxAttempts <= 0 {
+		return nil
+	}
+
+	err := u.UserRepository.IncrementFailedSignInAttempts(ctx, user)
+	if err != nil {
+		fmt.Println("Error while updating sign in attempts: ", err)
+		return toRepositoryError(err)
+	}
+	if user.FailedSignInAttempts < maxAttempts {
+		return nil
+	}
+
+	lockedUntil := time.Now().Add(u.lockoutDuration(user.LockoutCount + 1))
+	locked, err := u.UserRepository.Lock(ctx, user.Id, maxAttempts, lockedUntil)
+	if err != nil {
+		fmt.Println("Error while locking user: ", err)
+		return toRepositoryError(err)
+	}
+	//a concurrent attempt already locked the account
+	if !locked {
+		return nil
+	}
+
+	user.LockoutCount++
+	user.FailedSignInAttempts = 0
+	user.LockedUntil = &lockedUntil
+	return nil
+}
+
+func (u *AuthUseCase) lockoutDuration(lockoutCount int) time.Duration {
+	baseDuration := time.Duration(u.Viper.GetInt("security.lockout.base_duration_seconds")) * time.Second
+	maxDuration := time.Duration(u.Viper.GetInt("security.lockout.max_duration_seconds")) * time.Second
+
+	duration := baseDuration
+	for i := 1; i < lockoutCount; i++ {
+		duration *= 2
+		if maxDuration > 0 && duration >= maxDuration {
+			return maxDuration
+		}
+	}
 
+	if maxDuration > 0 && duration > maxDuration {
+		return maxDuration
+	}
+	return duration
 }
+
+// lockedError only tells the client the account is locked when security.lockout.reveal is enabled
+func (u *AuthUseCase) lockedError() error {
+	if u.Viper.GetBool("security.lockout.reveal") {
+		return &models.ErrorResponse{Code: 423, Status: "Locked", Message: "Account is locked because of too many failed sign in attempts. Please try again later"}
+	}
+	return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Email or password invalid"}
+}
+
 func (u *AuthUseCase) SignIn(ctx context.Context, credential *models.SignInRequest) (*models.SignInResponse, error) {
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -118,10 +416,310 @@ func (u *AuthUseCase) SignIn(ctx context.Context, credential *models.SignInReque
 		return nil, err
 	}
 
-	user, err := u.GetAndValidateUser(ctxWithTimeout, credential)
+	user, outcome, err := u.validateCredential(ctxWithTimeout, credential)
+	if err != nil {
+		u.RecordLoginAttempt(ctxWithTimeout, signInIdentifier(credential), credential.ClientInfo, user, outcome)
+		return nil, err
+	}
+
+	return u.CompleteSignIn(ctxWithTimeout, user, signInIdentifier(credential), credential.ClientInfo, []string{AmrPassword})
+
+}
+
+// CompleteSignIn finishes a sign in once the user proved who they are with methods, a password or any other way.
+// It refuses inactive users, asks for the second factor when MFA is enabled unless the device is trusted, holds
+// back the session while a password change is due and records the attempt
+func (u *AuthUseCase) CompleteSignIn(ctx context.Context, user *entity.User, identifier string, client models.ClientInfo, methods []string) (*models.SignInResponse, error) {
+	err := u.checkSignInStatus(ctx, user, identifier, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MfaEnabled && !u.isTrustedDevice(ctx, user, client) {
+		token, err := u.GenerateMfaChallengeToken(user.Id, methods)
+		if err != nil {
+			u.RecordLoginAttempt(ctx, identifier, client, user, entity.LoginOutcomeError)
+			return nil, err
+		}
+		u.RecordLoginAttempt(ctx, identifier, client, user, entity.LoginOutcomeMfaRequired)
+		return &models.SignInResponse{MfaRequired: true, MfaToken: token}, nil
+	}
+
+	return u.finishSignIn(ctx, user, identifier, client, methods)
+}
+
+// CompleteSecondFactor finishes a sign in that was held back by CompleteSignIn, once the second factor is verified.
+// methods are every method the user authenticated with, see WithSecondFactor. With rememberDevice the response also
+// carries a trusted device token, so the second factor isn't asked again on this device for a while
+func (u *AuthUseCase) CompleteSecondFactor(ctx context.Context, user *entity.User, identifier string, client models.ClientInfo, methods []string, rememberDevice bool) (*models.SignInResponse, error) {
+	err := u.checkSignInStatus(ctx, user, identifier, client)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := u.finishSignIn(ctx, user, identifier, client, methods)
 	if err != nil {
 		return nil, err
 	}
+
+	//a session was started, failing to remember the device only means the second factor is asked next time
+	if rememberDevice && response.AccessToken != "" && u.TrustedDevicesEnabled() {
+		token, expiresAt, err := u.TrustDevice(ctx, user, client)
+		if err != nil {
+			fmt.Println("Error while trusting device: ", err)
+		} else {
+			response.TrustedDeviceToken = token
+			response.TrustedDeviceExpiresAt = expiresAt
+		}
+	}
+
+	return response, nil
+}
+
+// TrustedDevicesEnabled tells whether the second factor can be skipped on a remembered device
+func (u *AuthUseCase) TrustedDevicesEnabled() bool {
+	return u.Viper.GetBool("trusted_devices.enabled")
+}
+
+// TrustedDeviceLifetime is how long a device stays trusted after the second factor was given on it
+func (u *AuthUseCase) TrustedDeviceLifetime() time.Duration {
+	return time.Duration(u.Viper.GetInt("trusted_devices.lifetime_days")) * 24 * time.Hour
+}
+
+// TrustDevice remembers the device of client for the user and returns the token of its cookie. The token is signed
+// with key.trusted_device and carries a random id, only the hash of that id is stored
+func (u *AuthUseCase) TrustDevice(ctx context.Context, user *entity.User, client models.ClientInfo) (string, time.Time, error) {
+	id, err := helpers.GenerateRandomToken(32)
+	if err != nil {
+		fmt.Println("Error while generating trusted device id: ", err)
+		return "", time.Time{}, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	expiresAt := time.Now().Add(u.TrustedDeviceLifetime())
+	err = u.TrustedDeviceRepo.Save(ctx, &entity.TrustedDevice{
+		UserId:    user.Id,
+		TokenHash: helpers.HashToken(id),
+		UserAgent: helpers.Truncate(client.UserAgent, 255),
+		IpAddress: client.IpAddress,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", time.Time{}, toRepositoryError(err)
+	}
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": "restful-api",
+		"sub": user.Id,
+		"jti": id,
+		"exp": expiresAt.Unix(),
+	})
+	token, err := jwtToken.SignedString([]byte(u.Viper.GetString("key.trusted_device")))
+	if err != nil {
+		fmt.Println("Error while generate trusted device token : ", err)
+		return "", time.Time{}, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Error while generate trusted device token"}
+	}
+
+	return token, expiresAt, nil
+}
+
+// FindTrustedDevice returns the device remembered by the token of a trusted device cookie, or nil when the token
+// is invalid, expired or was revoked
+func (u *AuthUseCase) FindTrustedDevice(ctx context.Context, token string) (*entity.TrustedDevice, error) {
+	if token == "" {
+		return nil, nil
+	}
+	claims, err := u.VerifyTokenClaims(token, u.Viper.GetString("key.trusted_device"))
+	if err != nil {
+		return nil, nil
+	}
+	userID, err := accessTokenSubject(claims)
+	if err != nil {
+		return nil, nil
+	}
+	id, ok := claims["jti"].(string)
+	if !ok || id == "" {
+		return nil, nil
+	}
+
+	device, err := u.TrustedDeviceRepo.FindOneByTokenHash(ctx, helpers.HashToken(id))
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+	if device == nil || device.UserId != userID || !device.IsActive(time.Now()) {
+		return nil, nil
+	}
+	return device, nil
+}
+
+// isTrustedDevice tells whether the second factor can be skipped for the user on the device of client. Any error
+// is only logged, the second factor is then asked as usual
+func (u *AuthUseCase) isTrustedDevice(ctx context.Context, user *entity.User, client models.ClientInfo) bool {
+	if !u.TrustedDevicesEnabled() {
+		return false
+	}
+	device, err := u.FindTrustedDevice(ctx, client.TrustedDeviceToken)
+	if err != nil {
+		fmt.Println("Error while getting trusted device: ", err)
+		return false
+	}
+	if device == nil || device.UserId != user.Id {
+		return false
+	}
+
+	err = u.TrustedDeviceRepo.UpdateLastUsedAt(ctx, device.Id, time.Now())
+	if err != nil {
+		fmt.Println("Error while updating trusted device: ", err)
+	}
+	return true
+}
+
+// WithSecondFactor adds the method of the second factor, and AmrMfa, to the methods of the first one
+func WithSecondFactor(methods []string, method string) []string {
+	combined := make([]string, 0, len(methods)+2)
+	for _, first := range methods {
+		if first != AmrMfa {
+			combined = append(combined, first)
+		}
+	}
+	return append(combined, method, AmrMfa)
+}
+
+// AcrOf is the assurance level reached with methods
+func AcrOf(methods []string) string {
+	for _, method := range methods {
+		if method == AmrMfa {
+			return AcrMultiFactor
+		}
+	}
+	return AcrSingleFactor
+}
+
+func (u *AuthUseCase) checkSignInStatus(ctx context.Context, user *entity.User, identifier string, client models.ClientInfo) error {
+	err := u.CheckUserStatus(user)
+	if err != nil {
+		outcome := entity.LoginOutcomeDisabled
+		if user.Status == entity.UserStatusSuspended {
+			outcome = entity.LoginOutcomeSuspended
+		}
+		u.RecordLoginAttempt(ctx, identifier, client, user, outcome)
+		return err
+	}
+	return nil
+}
+
+func (u *AuthUseCase) finishSignIn(ctx context.Context, user *entity.User, identifier string, client models.ClientInfo, methods []string) (*models.SignInResponse, error) {
+	//no session is started until the password is changed
+	if reason := u.passwordChangeReason(user); reason != "" {
+		token, err := u.GeneratePasswordChangeToken(user.Id)
+		if err != nil {
+			u.RecordLoginAttempt(ctx, identifier, client, user, entity.LoginOutcomeError)
+			return nil, err
+		}
+		u.RecordLoginAttempt(ctx, identifier, client, user, entity.LoginOutcomePasswordChangeRequired)
+		return &models.SignInResponse{PasswordChangeRequired: true, PasswordChangeReason: reason, PasswordChangeToken: token}, nil
+	}
+
+	response, err := u.issueTokens(ctx, user, client, methods)
+	if err != nil {
+		u.RecordLoginAttempt(ctx, identifier, client, user, entity.LoginOutcomeError)
+		return nil, err
+	}
+
+	u.RecordLoginAttempt(ctx, identifier, client, user, entity.LoginOutcomeSuccess)
+	now := time.Now()
+	err = u.UserRepository.UpdateLastLoginAt(ctx, user.Id, now)
+	if err != nil {
+		fmt.Println("Error while updating last login: ", err)
+	} else {
+		user.LastLoginAt = &now
+	}
+
+	return response, nil
+}
+
+func signInIdentifier(credential *models.SignInRequest) string {
+	identifier := strings.TrimSpace(credential.Identifier)
+	if identifier == "" {
+		identifier = strings.TrimSpace(credential.Email)
+	}
+	return identifier
+}
+
+// RecordLoginAttempt adds the attempt to the login history. A failure is only logged so it never blocks signing in
+func (u *AuthUseCase) RecordLoginAttempt(ctx context.Context, identifier string, client models.ClientInfo, user *entity.User, outcome string) {
+	attempt := &entity.LoginAttempt{
+		Identifier: helpers.Truncate(identifier, 255),
+		IpAddress:  client.IpAddress,
+		UserAgent:  helpers.Truncate(client.UserAgent, 255),
+		Outcome:    outcome,
+	}
+	if user != nil {
+		userID := user.Id
+		attempt.UserId = &userID
+	}
+
+	err := u.LoginAttemptRepository.Save(ctx, attempt)
+	if err != nil {
+		fmt.Println("Error while saving login attempt: ", err)
+	}
+}
+
+// passwordChangeReason tells why the user has to change the password before signing in, or "" when they don't
+func (u *AuthUseCase) passwordChangeReason(user *entity.User) string {
+	if user.PasswordChangeRequired {
+		return PasswordChangeReasonRequired
+	}
+
+	maxAge := time.Duration(u.Viper.GetInt("password.expiry.max_age_days")) * 24 * time.Hour
+	if user.IsPasswordExpired(time.Now(), maxAge) {
+		return PasswordChangeReasonExpired
+	}
+
+	return ""
+}
+
+// CheckUserStatus refuses suspended and disabled users
+func (u *AuthUseCase) CheckUserStatus(user *entity.User) error {
+	if user.IsActive(time.Now()) {
+		return nil
+	}
+
+	if user.Status == entity.UserStatusSuspended {
+		message := "Account is suspended"
+		if user.StatusExpiresAt != nil {
+			message = fmt.Sprintf("Account is suspended until %s", helpers.FormatTime(*user.StatusExpiresAt))
+		}
+		return &models.ErrorResponse{Code: 403, Status: "Forbidden", Message: message}
+	}
+
+	return &models.ErrorResponse{Code: 403, Status: "Forbidden", Message: "Account is disabled"}
+}
+
+// issueTokens starts a new session for the user, authenticated now with methods, and generates its access and
+// refresh token
+func (u *AuthUseCase) issueTokens(ctx context.Context, user *entity.User, client models.ClientInfo, methods []string) (*models.SignInResponse, error) {
+	sessionID, err := helpers.GenerateRandomToken(32)
+	if err != nil {
+		fmt.Println("Error while generating session id: ", err)
+		return nil, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	now := time.Now()
+	session, err := u.SessionRepository.Save(ctx, &entity.Session{
+		Id:        sessionID,
+		UserId:    user.Id,
+		UserAgent: helpers.Truncate(client.UserAgent, 255),
+		IpAddress: client.IpAddress,
+		AuthTime:  now,
+		Amr:       strings.Join(methods, " "),
+		Acr:       AcrOf(methods),
+		ExpiresAt: now.Add(RefreshTokenLifetime),
+	})
+	if err != nil {
+		fmt.Println("Error while saving session: ", err)
+		return nil, toRepositoryError(err)
+	}
+
 	userID := user.Id
 
 	var accessToken string
@@ -133,23 +731,25 @@ func (u *AuthUseCase) SignIn(ctx context.Context, credential *models.SignInReque
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		accessToken, err = u.GenerateAccessToken(userID)
+		token, err := u.GenerateAccessToken(session)
 		if err != nil {
 			errorChannel <- err
 			return
 		}
 
+		accessToken = token
 		errorChannel <- nil
 
 	}()
 	go func() {
 		defer wg.Done()
-		refreshToken, err = u.GenerateRefreshToken(userID)
+		token, err := u.GenerateRefreshToken(userID, session.Id)
 		if err != nil {
 			errorChannel <- err
 			return
 		}
 
+		refreshToken = token
 		errorChannel <- nil
 
 	}()
@@ -166,16 +766,225 @@ func (u *AuthUseCase) SignIn(ctx context.Context, credential *models.SignInReque
 	close(errorChannel)
 
 	return &models.SignInResponse{AccessToken: accessToken, RefreshToken: refreshToken}, nil
-
 }
 
 func (u *AuthUseCase) VerifyRefreshToken(refreshToken string, key string) (float64, error) {
-	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
+	return u.VerifyToken(refreshToken, key)
+}
+
+func (u *AuthUseCase) VerifyToken(tokenString string, key string) (float64, error) {
+	claims, err := u.VerifyTokenClaims(tokenString, key)
+	if err != nil {
+		return -1, err
+	}
+
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return -1, &models.ErrorResponse{
+			Code:    401,
+			Message: "Invalid token",
+			Status:  "Unauthorized",
+		}
+	}
+
+	return sub, nil
+}
+
+func (u *AuthUseCase) VerifyTokenClaims(tokenString string, key string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		return []byte(key), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		fmt.Println("Error while parsing token, ", err)
+		return nil, &models.ErrorResponse{
+			Code:    401,
+			Message: "Invalid token",
+			Status:  "Unauthorized",
+		}
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !token.Valid || !ok {
+		return nil, &models.ErrorResponse{
+			Code:    401,
+			Message: "Invalid token",
+			Status:  "Unauthorized",
+		}
+	}
+
+	return claims, nil
+}
+
+func (u *AuthUseCase) VerifyAccessToken(accessToken string) (int, error) {
+	claims, err := u.VerifyAccessClaims(accessToken)
+	if err != nil {
+		return -1, err
+	}
+	return claims.UserId, nil
+}
+
+// VerifyAccessClaims verifies a regular access token and returns its claims
+func (u *AuthUseCase) VerifyAccessClaims(accessToken string) (*AccessClaims, error) {
+	if accessToken == "" {
+		return nil, &models.ErrorResponse{
+			Code:    401,
+			Message: "Please sign in first",
+			Status:  "Unauthorized",
+		}
+	}
+
+	claims, err := u.VerifyTokenClaims(accessToken, u.Viper.GetString("key.token.access"))
+	if err != nil {
+		return nil, err
+	}
+
+	//a scoped token is only accepted by the endpoints of its scope
+	if scope, ok := claims["scope"]; ok {
+		if scope == PasswordChangeScope {
+			return nil, &models.ErrorResponse{
+				Code:    403,
+				Message: "Please change your password first",
+				Status:  "Forbidden",
+			}
+		}
+		return nil, &models.ErrorResponse{
+			Code:    401,
+			Message: "Invalid token",
+			Status:  "Unauthorized",
+		}
+	}
+
+	userID, err := accessTokenSubject(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	//tokens issued before sessions recorded their authentication have none, and fail every step up
+	accessClaims := &AccessClaims{UserId: userID, Amr: tokenMethods(claims)}
+	accessClaims.SessionId, _ = claims["sid"].(string)
+	accessClaims.Acr, _ = claims["acr"].(string)
+	if authTime, ok := claims["auth_time"].(float64); ok {
+		accessClaims.AuthTime = time.Unix(int64(authTime), 0)
+	}
+	return accessClaims, nil
+}
+
+// CheckSession refuses an access token whose session was revoked or expired, or whose user is no longer active, so
+// signing out, suspending or disabling takes effect before the token expires
+func (u *AuthUseCase) CheckSession(ctx context.Context, claims *AccessClaims) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := u.findActiveSession(ctxWithTimeout, claims)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, claims.UserId)
+	if err != nil {
+		fmt.Println("Error while getting user by id: ", err)
+		return toRepositoryError(err)
+	}
+	if user == nil {
+		return &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Please sign in first"}
+	}
+	return u.CheckUserStatus(user)
+}
+
+// findActiveSession returns the session of claims while it is active and still belongs to the user of claims
+func (u *AuthUseCase) findActiveSession(ctx context.Context, claims *AccessClaims) (*entity.Session, error) {
+	session, err := u.SessionRepository.FindOneById(ctx, claims.SessionId)
+	if err != nil {
+		fmt.Println("Error while getting session: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if session == nil || !session.IsActive(time.Now()) || session.UserId != claims.UserId {
+		return nil, &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Session expired. Please sign in again"}
+	}
+	return session, nil
+}
+
+// reauthenticateSession records that the user of session authenticated again with methods, and returns an access
+// token saying so
+func (u *AuthUseCase) reauthenticateSession(ctx context.Context, session *entity.Session, methods []string, now time.Time) (*models.GetTokenResponse, error) {
+	session.AuthTime = now
+	session.Amr = strings.Join(methods, " ")
+	session.Acr = AcrOf(methods)
+	err := u.SessionRepository.UpdateAuthentication(ctx, session)
+	if err != nil {
+		fmt.Println("Error while updating session authentication: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	accessToken, err := u.GenerateAccessToken(session)
+	if err != nil {
+		return nil, err
+	}
+	return &models.GetTokenResponse{AccessToken: accessToken}, nil
+}
+
+// CheckStepUp refuses a session that last authenticated more than maxAge ago, or below the acr level. A zero maxAge
+// or an empty acr is not checked
+func (u *AuthUseCase) CheckStepUp(claims *AccessClaims, maxAge time.Duration, acr string) error {
+	recent := maxAge <= 0 || time.Since(claims.AuthTime) <= maxAge
+	strong := acr == "" || acrLevels[claims.Acr] >= acrLevels[acr]
+	if recent && strong {
+		return nil
+	}
+	return &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Please authenticate again to continue"}
+}
+
+// CheckMfaStepUp refuses a session below AcrMultiFactor once the user has turned two-factor authentication on, so a
+// single factor can't add or remove another one
+func (u *AuthUseCase) CheckMfaStepUp(ctx context.Context, claims *AccessClaims) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, claims.UserId)
+	if err != nil {
+		fmt.Println("Error while getting user by id: ", err)
+		return toRepositoryError(err)
+	}
+	if user == nil {
+		return &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Please sign in first"}
+	}
+	if !user.MfaEnabled {
+		return nil
+	}
+	return u.CheckStepUp(claims, 0, AcrMultiFactor)
+}
+
+// VerifyPasswordChangeAccess accepts a regular access token, checked like Authenticate does, as well as a password
+// change token of a user that is still active
+func (u *AuthUseCase) VerifyPasswordChangeAccess(ctx context.Context, accessToken string) (int, error) {
+	if accessToken == "" {
+		return -1, &models.ErrorResponse{
+			Code:    401,
+			Message: "Please sign in first",
+			Status:  "Unauthorized",
+		}
+	}
+
+	claims, err := u.VerifyTokenClaims(accessToken, u.Viper.GetString("key.token.access"))
+	if err != nil {
+		return -1, err
+	}
+
+	scope, ok := claims["scope"]
+	if !ok {
+		accessClaims, err := u.VerifyAccessClaims(accessToken)
+		if err != nil {
+			return -1, err
+		}
+		err = u.CheckSession(ctx, accessClaims)
+		if err != nil {
+			return -1, err
+		}
+		return accessClaims.UserId, nil
+	}
+
+	if scope != PasswordChangeScope {
 		return -1, &models.ErrorResponse{
 			Code:    401,
 			Message: "Invalid token",
@@ -183,22 +992,105 @@ func (u *AuthUseCase) VerifyRefreshToken(refreshToken string, key string) (float
 		}
 	}
 
-	if !token.Valid {
+	userID, err := accessTokenSubject(claims)
+	if err != nil {
+		return -1, err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	//the token has no session, so only the user can be checked
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting user by id: ", err)
+		return -1, toRepositoryError(err)
+	}
+	if user == nil {
+		return -1, &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Please sign in first"}
+	}
+	err = u.CheckUserStatus(user)
+	if err != nil {
+		return -1, err
+	}
+	return userID, nil
+}
+
+// VerifyMfaChallengeToken only accepts a token returned by SignIn for a second factor. It returns the user and the
+// methods of the first factor
+func (u *AuthUseCase) VerifyMfaChallengeToken(mfaToken string) (int, []string, error) {
+	claims, err := u.VerifyTokenClaims(mfaToken, u.Viper.GetString("key.token.access"))
+	if err != nil {
+		return -1, nil, err
+	}
+
+	if scope, ok := claims["scope"]; !ok || scope != MfaChallengeScope {
+		return -1, nil, &models.ErrorResponse{
+			Code:    401,
+			Message: "Invalid token",
+			Status:  "Unauthorized",
+		}
+	}
+
+	userID, err := accessTokenSubject(claims)
+	if err != nil {
+		return -1, nil, err
+	}
+	return userID, tokenMethods(claims), nil
+}
+
+func tokenMethods(claims jwt.MapClaims) []string {
+	values, _ := claims["amr"].([]interface{})
+	methods := make([]string, 0, len(values))
+	for _, value := range values {
+		if method, ok := value.(string); ok {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+func accessTokenSubject(claims jwt.MapClaims) (int, error) {
+	sub, ok := claims["sub"].(float64)
+	if !ok {
 		return -1, &models.ErrorResponse{
 			Code:    401,
 			Message: "Invalid token",
 			Status:  "Unauthorized",
 		}
 	}
-	var sub float64
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		sub = claims["sub"].(float64)
+
+	return int(sub), nil
+}
+
+// Authorize makes sure the user still exists and has the given role
+func (u *AuthUseCase) Authorize(ctx context.Context, userID int, role string) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, userID)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return &models.ErrorResponse{Code: 408, Status: "Request Timeout", Message: "Request timeout. Please try again"}
+		}
+		return &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	if user == nil {
+		return &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Please sign in first"}
 	}
 
-	return sub, nil
+	if user.Role != role {
+		return &models.ErrorResponse{Code: 403, Status: "Forbidden", Message: "You don't have permission to access this resource"}
+	}
+
+	return nil
 }
 
-func (u *AuthUseCase) GetToken(refreshToken string) (*models.GetTokenResponse, error) {
+func (u *AuthUseCase) GetToken(ctx context.Context, refreshToken string) (*models.GetTokenResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
 	if refreshToken == "" {
 		return nil, &models.ErrorResponse{
 			Code:    401,
@@ -207,12 +1099,48 @@ func (u *AuthUseCase) GetToken(refreshToken string) (*models.GetTokenResponse, e
 		}
 	}
 	refreshTokenKey := u.Viper.GetString("key.token.refresh")
-	sub, err := u.VerifyRefreshToken(refreshToken, refreshTokenKey)
+	claims, err := u.VerifyTokenClaims(refreshToken, refreshTokenKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, _ := claims["sub"].(float64)
+	sessionID, _ := claims["sid"].(string)
+	session, err := u.SessionRepository.FindOneById(ctxWithTimeout, sessionID)
+	if err != nil {
+		fmt.Println("Error while getting session: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	if session == nil || !session.IsActive(time.Now()) || session.UserId != int(sub) {
+		return nil, &models.ErrorResponse{
+			Code:    401,
+			Message: "Session expired. Please sign in again",
+			Status:  "Unauthorized",
+		}
+	}
+
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, session.UserId)
+	if err != nil {
+		fmt.Println("Error while getting user by id: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	if user == nil {
+		return nil, &models.ErrorResponse{
+			Code:    401,
+			Message: "Please sign in first",
+			Status:  "Unauthorized",
+		}
+	}
+
+	err = u.CheckUserStatus(user)
 	if err != nil {
 		return nil, err
 	}
 
-	accessToken, err := u.GenerateAccessToken(int(sub))
+	//the session keeps its authentication, refreshing doesn't make it recent
+	accessToken, err := u.GenerateAccessToken(session)
 	if err != nil {
 		return nil, err
 	}
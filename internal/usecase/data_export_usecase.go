@@ -0,0 +1,354 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// dataExportLoginHistoryBatch is how many login attempts are read at once while building the archive
+const dataExportLoginHistoryBatch = 500
+
+// dataExportLease is how long a worker owns an export. An export whose worker died is generated again afterwards
+const dataExportLease = 5 * time.Minute
+
+// DataExportUseCase generates a JSON archive of the data of a user in the background. The archive is downloaded
+// through a signed link and deleted after export.retention_hours
+type DataExportUseCase struct {
+	UserRepository         repository.UserRepositoryInterface
+	SessionRepository      repository.SessionRepositoryInterface
+	ProductRepository      repository.ProductRepositoryInterface
+	LoginAttemptRepository repository.LoginAttemptRepositoryInterface
+	DataExportRepository   repository.DataExportRepositoryInterface
+	Viper                  *viper.Viper
+}
+
+func NewDataExportUseCase(userRepository repository.UserRepositoryInterface, sessionRepository repository.SessionRepositoryInterface, productRepository repository.ProductRepositoryInterface, loginAttemptRepository repository.LoginAttemptRepositoryInterface, dataExportRepository repository.DataExportRepositoryInterface, viper *viper.Viper) *DataExportUseCase {
+	return &DataExportUseCase{
+		UserRepository:         userRepository,
+		SessionRepository:      sessionRepository,
+		ProductRepository:      productRepository,
+		LoginAttemptRepository: loginAttemptRepository,
+		DataExportRepository:   dataExportRepository,
+		Viper:                  viper,
+	}
+}
+
+// RequestExport queues an export. A user only has one export in progress at a time, asking again returns it
+func (u *DataExportUseCase) RequestExport(ctx context.Context, userID int) (*models.DataExportResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	export, err := u.DataExportRepository.FindUnfinishedByUserId(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting data export: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if export != nil {
+		return u.toDataExportResponse(export), nil
+	}
+
+	export, err = u.DataExportRepository.Save(ctxWithTimeout, &entity.DataExport{UserId: userID, Status: entity.DataExportStatusPending})
+	if err != nil {
+		fmt.Println("Error while saving data export: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	return u.toDataExportResponse(export), nil
+}
+
+// GetExport returns an export of the user, with a fresh download link once it is completed
+func (u *DataExportUseCase) GetExport(ctx context.Context, userID int, exportID int) (*models.DataExportResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	export, err := u.DataExportRepository.FindOneById(ctxWithTimeout, exportID)
+	if err != nil {
+		fmt.Println("Error while getting data export: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if export == nil || export.UserId != userID {
+		return nil, &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Export not found"}
+	}
+
+	return u.toDataExportResponse(export), nil
+}
+
+// OpenDownload checks the signed link and returns the path of the archive
+func (u *DataExportUseCase) OpenDownload(ctx context.Context, exportID int, expires int64, signature string) (string, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	expected := u.signDownload(exportID, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", &models.ErrorResponse{Code: 403, Status: "Forbidden", Message: "Invalid download link"}
+	}
+	if time.Unix(expires, 0).Before(now) {
+		return "", &models.ErrorResponse{Code: 403, Status: "Forbidden", Message: "Download link expired"}
+	}
+
+	export, err := u.DataExportRepository.FindOneById(ctxWithTimeout, exportID)
+	if err != nil {
+		fmt.Println("Error while getting data export: ", err)
+		return "", toRepositoryError(err)
+	}
+	if export == nil || !export.IsDownloadable(now) {
+		return "", &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Export not found"}
+	}
+
+	return export.FilePath, nil
+}
+
+// ProcessPendingExports generates the queued exports one after another and returns how many were generated
+func (u *DataExportUseCase) ProcessPendingExports(ctx context.Context) (int, error) {
+	processed := 0
+	for ctx.Err() == nil {
+		export, err := u.DataExportRepository.ClaimNext(ctx, time.Now().Add(dataExportLease))
+		if err != nil {
+			return processed, err
+		}
+		if export == nil {
+			return processed, nil
+		}
+
+		err = u.generate(ctx, export)
+		if err != nil {
+			fmt.Printf("Data export %d failed: %v\n", export.Id, err)
+			export.Status = entity.DataExportStatusFailed
+			export.ErrorMessage = "Export could not be generated. Please request a new one"
+			export.LeaseExpiresAt = nil
+		}
+
+		err = u.DataExportRepository.Update(ctx, export)
+		if err != nil {
+			fmt.Println("Error while saving data export: ", err)
+			return processed, err
+		}
+		processed++
+	}
+	return processed, ctx.Err()
+}
+
+// PurgeExpiredExports deletes the archives whose retention is over, and those no export refers to anymore. It returns
+// how many archives were deleted
+func (u *DataExportUseCase) PurgeExpiredExports(ctx context.Context) (int, error) {
+	exports, err := u.DataExportRepository.FindExpiredBefore(ctx, time.Now(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, export := range exports {
+		err = os.Remove(export.FilePath)
+		if err != nil && !os.IsNotExist(err) {
+			return i, err
+		}
+
+		export.Status = entity.DataExportStatusExpired
+		export.FilePath = ""
+		err = u.DataExportRepository.Update(ctx, export)
+		if err != nil {
+			return i, err
+		}
+	}
+
+	orphans, err := u.purgeOrphanFiles(ctx)
+	return len(exports) + orphans, err
+}
+
+// purgeOrphanFiles deletes the archives left behind when a purged user took its exports along. A recent file is
+// kept, its export may not be saved yet
+func (u *DataExportUseCase) purgeOrphanFiles(ctx context.Context) (int, error) {
+	directory := helpers.ResolveConfigPath(u.Viper, u.Viper.GetString("export.directory"))
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	purged := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < dataExportLease {
+			continue
+		}
+
+		filePath := filepath.Join(directory, entry.Name())
+		exists, err := u.DataExportRepository.ExistsByFilePath(ctx, filePath)
+		if err != nil {
+			fmt.Println("Error while checking data export file: ", err)
+			return purged, err
+		}
+		if exists {
+			continue
+		}
+
+		err = os.Remove(filePath)
+		if err != nil && !os.IsNotExist(err) {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (u *DataExportUseCase) generate(ctx context.Context, export *entity.DataExport) error {
+	archive, err := u.buildArchive(ctx, export.UserId)
+	if err != nil {
+		return err
+	}
+
+	directory := helpers.ResolveConfigPath(u.Viper, u.Viper.GetString("export.directory"))
+	err = os.MkdirAll(directory, 0o700)
+	if err != nil {
+		return err
+	}
+
+	name, err := helpers.GenerateRandomToken(16)
+	if err != nil {
+		return err
+	}
+	filePath := filepath.Join(directory, name+".json")
+
+	content, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filePath, content, 0o600)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(u.Viper.GetInt("export.retention_hours")) * time.Hour)
+	export.Status = entity.DataExportStatusCompleted
+	export.FilePath = filePath
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	export.LeaseExpiresAt = nil
+	return nil
+}
+
+func (u *DataExportUseCase) buildArchive(ctx context.Context, userID int) (*models.DataExportArchive, error) {
+	user, err := u.UserRepository.FindOneById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %d not found", userID)
+	}
+
+	profile := &models.DataExportProfile{UserResponse: *toUserResponse(user)}
+	if user.PasswordChangedAt != nil {
+		profile.PasswordChangedAt = helpers.FormatTime(*user.PasswordChangedAt)
+	}
+	if user.LockedUntil != nil {
+		profile.LockedUntil = helpers.FormatTime(*user.LockedUntil)
+	}
+
+	archive := &models.DataExportArchive{
+		GeneratedAt: helpers.FormatTime(time.Now()),
+		Profile:     profile,
+		Sessions:    []models.DataExportSession{},
+		Products:    []models.DataExportProduct{},
+
+		LoginHistory: []models.LoginAttemptResponse{},
+	}
+
+	sessions, err := u.SessionRepository.FindAllByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		exported := models.DataExportSession{
+			IpAddress: session.IpAddress,
+			UserAgent: session.UserAgent,
+			CreatedAt: helpers.FormatTime(session.CreatedAt),
+			ExpiresAt: helpers.FormatTime(session.ExpiresAt),
+		}
+		if session.RevokedAt != nil {
+			exported.RevokedAt = helpers.FormatTime(*session.RevokedAt)
+		}
+		archive.Sessions = append(archive.Sessions, exported)
+	}
+
+	products, err := u.ProductRepository.FindAllByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range products {
+		archive.Products = append(archive.Products, models.DataExportProduct{
+			Id:    product.Id,
+			Name:  product.Name,
+			Stock: product.Stock,
+			Price: product.Price,
+		})
+	}
+
+	for offset := 0; ; offset += dataExportLoginHistoryBatch {
+		attempts, total, err := u.LoginAttemptRepository.FindAllByUserId(ctx, userID, offset, dataExportLoginHistoryBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, attempt := range toLoginAttemptResponses(attempts) {
+			archive.LoginHistory = append(archive.LoginHistory, *attempt)
+		}
+		if len(attempts) == 0 || int64(offset+len(attempts)) >= total {
+			break
+		}
+	}
+
+	return archive, nil
+}
+
+func (u *DataExportUseCase) signDownload(exportID int, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(u.Viper.GetString("key.export")))
+	mac.Write([]byte(strconv.Itoa(exportID) + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (u *DataExportUseCase) toDataExportResponse(export *entity.DataExport) *models.DataExportResponse {
+	response := &models.DataExportResponse{
+		Id:           export.Id,
+		Status:       export.Status,
+		ErrorMessage: export.ErrorMessage,
+		CreatedAt:    helpers.FormatTime(export.CreatedAt),
+	}
+	if export.CompletedAt != nil {
+		response.CompletedAt = helpers.FormatTime(*export.CompletedAt)
+	}
+	if export.ExpiresAt != nil {
+		response.ExpiresAt = helpers.FormatTime(*export.ExpiresAt)
+	}
+
+	now := time.Now()
+	if export.IsDownloadable(now) {
+		//the link never outlives the archive
+		downloadExpiresAt := now.Add(time.Duration(u.Viper.GetInt("export.link_ttl_minutes")) * time.Minute)
+		if downloadExpiresAt.After(*export.ExpiresAt) {
+			downloadExpiresAt = *export.ExpiresAt
+		}
+		expires := downloadExpiresAt.Unix()
+		response.DownloadUrl = fmt.Sprintf("/exports/%d/download?expires=%d&signature=%s", export.Id, expires, u.signDownload(export.Id, expires))
+		response.DownloadExpiresAt = helpers.FormatTime(time.Unix(expires, 0))
+	}
+
+	return response
+}
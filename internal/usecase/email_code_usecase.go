@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/mail"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"time"
+)
+
+// EmailCodeUseCase signs users in with a 6 digit code sent by email, for clients that can't open a link
+type EmailCodeUseCase struct {
+	AuthUseCase    *AuthUseCase
+	UserRepository repository.UserRepositoryInterface
+	Codes          *oneTimeCodes
+	Mailer         mail.MailerInterface
+	Validator      *validator.Validate
+	Viper          *viper.Viper
+}
+
+func NewEmailCodeUseCase(authUseCase *AuthUseCase, userRepository repository.UserRepositoryInterface, oneTimeCodeRepository repository.OneTimeCodeRepositoryInterface, mailer mail.MailerInterface, validator *validator.Validate, viper *viper.Viper) *EmailCodeUseCase {
+	return &EmailCodeUseCase{
+		AuthUseCase:    authUseCase,
+		UserRepository: userRepository,
+		Codes: &oneTimeCodes{
+			Repository: oneTimeCodeRepository,
+			Viper:      viper,
+			Purpose:    entity.OneTimeCodePurposeEmailLogin,
+			ConfigKey:  "otp.email",
+		},
+		Mailer:    mailer,
+		Validator: validator,
+		Viper:     viper,
+	}
+}
+
+// RequestCode emails a code when the email belongs to an active user. The answer never tells whether it did,
+// a code asked for too early or too often is silently not sent
+func (u *EmailCodeUseCase) RequestCode(ctx context.Context, request *models.EmailCodeRequest) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if !u.Viper.GetBool("otp.email.enabled") {
+		return &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Email code sign in is disabled"}
+	}
+
+	err := u.Validator.Struct(request)
+	if err != nil {
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+
+	user, err := u.AuthUseCase.findUserByEmail(ctxWithTimeout, request.Email)
+	if err != nil {
+		fmt.Println("Error while getting user: ", err)
+		return toRepositoryError(err)
+	}
+	if user == nil || !user.IsActive(time.Now()) {
+		return nil
+	}
+
+	code, err := u.Codes.issue(ctxWithTimeout, user.Id, user.Email)
+	if err != nil {
+		fmt.Println("Error while issuing email code: ", err)
+		return toRepositoryError(err)
+	}
+	if code == "" {
+		return nil
+	}
+
+	err = u.Mailer.Send(ctxWithTimeout, &mail.Message{
+		To:      user.Email,
+		Subject: "Your sign in code",
+		Body: fmt.Sprintf("Your sign in code is %s\n\nIt expires in %d minutes. If you didn't ask for it, you can ignore this email.",
+			code, int(u.Codes.TTL().Minutes())),
+	})
+	if err != nil {
+		fmt.Println("Error while sending email code: ", err)
+		return &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	return nil
+}
+
+// SignIn exchanges a valid code for the same response as AuthUseCase.SignIn
+func (u *EmailCodeUseCase) SignIn(ctx context.Context, request *models.EmailCodeSignInRequest) (*models.SignInResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if !u.Viper.GetBool("otp.email.enabled") {
+		return nil, &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Email code sign in is disabled"}
+	}
+
+	err := u.Validator.Struct(request)
+	if err != nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+
+	invalidCode := &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is invalid or expired"}
+	user, err := u.AuthUseCase.findUserByEmail(ctxWithTimeout, request.Email)
+	if err != nil {
+		fmt.Println("Error while getting user: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if user == nil {
+		u.AuthUseCase.RecordLoginAttempt(ctxWithTimeout, request.Email, request.ClientInfo, nil, entity.LoginOutcomeUnknownUser)
+		return nil, invalidCode
+	}
+
+	matched, err := u.Codes.verify(ctxWithTimeout, user.Id, request.Code)
+	if err != nil {
+		fmt.Println("Error while verifying email code: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if matched == nil {
+		u.AuthUseCase.RecordLoginAttempt(ctxWithTimeout, request.Email, request.ClientInfo, user, entity.LoginOutcomeInvalidCode)
+		return nil, invalidCode
+	}
+
+	return u.AuthUseCase.CompleteSignIn(ctxWithTimeout, user, request.Email, request.ClientInfo, []string{AmrOtp})
+}
+
+// PurgeExpiredCodes deletes the codes that can no longer be used, whatever their purpose. They are kept for an
+// hour after expiring since they still count against max_per_hour
+func (u *EmailCodeUseCase) PurgeExpiredCodes(ctx context.Context) (int64, error) {
+	return u.Codes.Repository.DeleteExpiredBefore(ctx, time.Now().Add(-time.Hour))
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"sort"
+)
+
+type EmailCollisionUseCase struct {
+	UserRepository  repository.UserRepositoryInterface
+	EmailNormalizer *helpers.EmailNormalizer
+}
+
+func NewEmailCollisionUseCase(userRepository repository.UserRepositoryInterface, viper *viper.Viper) *EmailCollisionUseCase {
+	return &EmailCollisionUseCase{
+		UserRepository:  userRepository,
+		EmailNormalizer: helpers.NewEmailNormalizer(viper),
+	}
+}
+
+// FindCollisions canonicalizes the email of every user and returns the canonical emails shared by more than one user.
+// With backfill, the stored canonical email is updated wherever it differs from the current rules, except for the
+// colliding users since the canonical email is unique
+func (u *EmailCollisionUseCase) FindCollisions(ctx context.Context, backfill bool) ([]models.EmailCollision, error) {
+	usersByEmail := make(map[string][]models.UserResponse)
+	outdated := make(map[int]string)
+
+	err := u.UserRepository.FindAllInBatches(ctx, 500, func(users []*entity.User) error {
+		for _, user := range users {
+			emailCanonical := u.EmailNormalizer.Canonicalize(user.Email)
+			usersByEmail[emailCanonical] = append(usersByEmail[emailCanonical], models.UserResponse{
+				Id:        user.Id,
+				Email:     user.Email,
+				CreatedAt: helpers.FormatTime(user.CreatedAt),
+			})
+
+			if user.EmailCanonical != emailCanonical {
+				outdated[user.Id] = emailCanonical
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println("Error while checking email collisions: ", err)
+		return nil, err
+	}
+
+	if backfill {
+		for userID, emailCanonical := range outdated {
+			if len(usersByEmail[emailCanonical]) > 1 {
+				continue
+			}
+			err = u.UserRepository.UpdateEmailCanonical(ctx, userID, emailCanonical)
+			if err != nil {
+				fmt.Println("Error while updating canonical email: ", err)
+				return nil, err
+			}
+		}
+	}
+
+	var collisions []models.EmailCollision
+	for emailCanonical, users := range usersByEmail {
+		if len(users) > 1 {
+			collisions = append(collisions, models.EmailCollision{EmailCanonical: emailCanonical, Users: users})
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].EmailCanonical < collisions[j].EmailCanonical
+	})
+
+	return collisions, nil
+}
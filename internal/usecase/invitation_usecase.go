@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/mail"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"net/url"
+	"time"
+)
+
+// Signup modes of signup.mode. Any other mode is treated as SignUpModeClosed
+const (
+	SignUpModeOpen       = "open"
+	SignUpModeInviteOnly = "invite_only"
+	SignUpModeClosed     = "closed"
+)
+
+const (
+	SignUpClosed       = "signup_closed"
+	InvitationRequired = "invitation_required"
+	InvitationInvalid  = "invitation_invalid"
+)
+
+type InvitationUseCase struct {
+	InvitationRepository repository.InvitationRepositoryInterface
+	Mailer               mail.MailerInterface
+	Validator            *validator.Validate
+	Viper                *viper.Viper
+	EmailNormalizer      *helpers.EmailNormalizer
+}
+
+func NewInvitationUseCase(invitationRepository repository.InvitationRepositoryInterface, mailer mail.MailerInterface, validator *validator.Validate, viper *viper.Viper) *InvitationUseCase {
+	return &InvitationUseCase{
+		InvitationRepository: invitationRepository,
+		Mailer:               mailer,
+		Validator:            validator,
+		Viper:                viper,
+		EmailNormalizer:      helpers.NewEmailNormalizer(viper),
+	}
+}
+
+// CreateInvitation creates an invitation on behalf of the admin and returns its code, which can't be read again.
+// With an email the code is sent there, and only signs up that email
+func (u *InvitationUseCase) CreateInvitation(ctx context.Context, adminID int, request *models.CreateInvitationRequest) (*models.InvitationResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.Validator.Struct(request)
+	if err != nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+
+	invitation := &entity.Invitation{Role: request.Role, MaxUses: request.MaxUses, CreatedBy: &adminID}
+	if invitation.Role == "" {
+		invitation.Role = entity.RoleUser
+	}
+	if invitation.MaxUses == 0 {
+		invitation.MaxUses = 1
+	}
+	if request.Email != "" {
+		if invitation.MaxUses > 1 {
+			return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "An emailed invitation can only be used once"}
+		}
+		email := u.EmailNormalizer.Normalize(request.Email)
+		invitation.Email = &email
+	}
+	days := request.ExpiresInDays
+	if days == 0 {
+		days = u.Viper.GetInt("signup.invitation_ttl_days")
+	}
+	invitation.ExpiresAt = time.Now().Add(time.Duration(days) * 24 * time.Hour)
+
+	code, err := helpers.GenerateRandomToken(16)
+	if err != nil {
+		fmt.Println("Error while generating invitation code: ", err)
+		return nil, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+	invitation.CodeHash = helpers.HashToken(code)
+
+	err = u.InvitationRepository.Save(ctxWithTimeout, invitation)
+	if err != nil {
+		fmt.Println("Error while saving invitation: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	if invitation.Email != nil {
+		link := u.Viper.GetString("signup.invitation_url") + "?invitation_code=" + url.QueryEscape(code)
+		err = u.Mailer.Send(ctxWithTimeout, &mail.Message{
+			To:      *invitation.Email,
+			Subject: "You are invited to sign up",
+			Body: fmt.Sprintf("Open this link to create your account:\n\n%s\n\nOr sign up with the invitation code %s. It expires on %s.",
+				link, code, helpers.FormatTime(invitation.ExpiresAt)),
+		})
+		if err != nil {
+			fmt.Println("Error while sending invitation: ", err)
+			return nil, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+		}
+	}
+
+	response := toInvitationResponse(invitation)
+	response.Code = code
+	return response, nil
+}
+
+// GetInvitations returns one page of the invitations, newest first, with the total
+func (u *InvitationUseCase) GetInvitations(ctx context.Context, page models.PageRequest) ([]*models.InvitationResponse, int64, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	invitations, total, err := u.InvitationRepository.FindAll(ctxWithTimeout, page.Offset(), page.Size)
+	if err != nil {
+		fmt.Println("Error while getting invitations: ", err)
+		return nil, 0, toRepositoryError(err)
+	}
+
+	responses := make([]*models.InvitationResponse, 0, len(invitations))
+	for _, invitation := range invitations {
+		responses = append(responses, toInvitationResponse(invitation))
+	}
+	return responses, total, nil
+}
+
+// RevokeInvitation stops the invitation from signing anyone up. Accounts already created with it are kept
+func (u *InvitationUseCase) RevokeInvitation(ctx context.Context, invitationID int) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	revoked, err := u.InvitationRepository.Revoke(ctxWithTimeout, invitationID, time.Now())
+	if err != nil {
+		fmt.Println("Error while revoking invitation: ", err)
+		return toRepositoryError(err)
+	}
+	if !revoked {
+		return &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Invitation not found"}
+	}
+	return nil
+}
+
+func signUpMode(viper *viper.Viper) string {
+	switch mode := viper.GetString("signup.mode"); mode {
+	case "", SignUpModeOpen:
+		return SignUpModeOpen
+	case SignUpModeInviteOnly:
+		return SignUpModeInviteOnly
+	default:
+		return SignUpModeClosed
+	}
+}
+
+func toInvitationResponse(invitation *entity.Invitation) *models.InvitationResponse {
+	response := &models.InvitationResponse{
+		Id:        invitation.Id,
+		Role:      invitation.Role,
+		MaxUses:   invitation.MaxUses,
+		Uses:      invitation.Uses,
+		CreatedAt: helpers.FormatTime(invitation.CreatedAt),
+		ExpiresAt: helpers.FormatTime(invitation.ExpiresAt),
+	}
+	if invitation.Email != nil {
+		response.Email = *invitation.Email
+	}
+	if invitation.RevokedAt != nil {
+		response.RevokedAt = helpers.FormatTime(*invitation.RevokedAt)
+	}
+	return response
+}
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"time"
+)
+
+var loginOutcomes = map[string]bool{
+	entity.LoginOutcomeSuccess:                true,
+	entity.LoginOutcomeUnknownUser:            true,
+	entity.LoginOutcomeInvalidPassword:        true,
+	entity.LoginOutcomeLocked:                 true,
+	entity.LoginOutcomeSuspended:              true,
+	entity.LoginOutcomeDisabled:               true,
+	entity.LoginOutcomePasswordChangeRequired: true,
+	entity.LoginOutcomeInvalidLink:            true,
+	entity.LoginOutcomeInvalidCode:            true,
+	entity.LoginOutcomeMfaRequired:            true,
+	entity.LoginOutcomeInvalidMfaCode:         true,
+	entity.LoginOutcomeInvalidPasskey:         true,
+	entity.LoginOutcomeError:                  true,
+}
+
+type LoginHistoryUseCase struct {
+	LoginAttemptRepository repository.LoginAttemptRepositoryInterface
+}
+
+func NewLoginHistoryUseCase(loginAttemptRepository repository.LoginAttemptRepositoryInterface) *LoginHistoryUseCase {
+	return &LoginHistoryUseCase{
+		LoginAttemptRepository: loginAttemptRepository,
+	}
+}
+
+// GetLoginHistory returns one page of the sign in attempts on the account of the user, newest first, with the total
+func (u *LoginHistoryUseCase) GetLoginHistory(ctx context.Context, userID int, page models.PageRequest) ([]*models.LoginAttemptResponse, int64, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	attempts, total, err := u.LoginAttemptRepository.FindAllByUserId(ctxWithTimeout, userID, page.Offset(), page.Size)
+	if err != nil {
+		fmt.Println("Error while getting login history: ", err)
+		return nil, 0, toRepositoryError(err)
+	}
+
+	return toLoginAttemptResponses(attempts), total, nil
+}
+
+// SearchLoginHistory lets an admin look through the attempts of every user, including the unknown identifiers
+func (u *LoginHistoryUseCase) SearchLoginHistory(ctx context.Context, query *models.LoginHistoryQuery, page models.PageRequest) ([]*models.LoginAttemptResponse, int64, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter, err := toLoginAttemptFilter(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	attempts, total, err := u.LoginAttemptRepository.Search(ctxWithTimeout, filter, page.Offset(), page.Size)
+	if err != nil {
+		fmt.Println("Error while searching login history: ", err)
+		return nil, 0, toRepositoryError(err)
+	}
+
+	return toLoginAttemptResponses(attempts), total, nil
+}
+
+func toLoginAttemptFilter(query *models.LoginHistoryQuery) (repository.LoginAttemptFilter, error) {
+	filter := repository.LoginAttemptFilter{
+		UserId:    query.UserId,
+		IpAddress: query.IpAddress,
+		Outcome:   query.Outcome,
+	}
+
+	if query.Outcome != "" && !loginOutcomes[query.Outcome] {
+		return filter, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "outcome is not a known outcome"}
+	}
+	if query.From != "" {
+		from, err := time.Parse(time.RFC3339, query.From)
+		if err != nil {
+			return filter, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "from must be an RFC 3339 time"}
+		}
+		filter.From = &from
+	}
+	if query.To != "" {
+		to, err := time.Parse(time.RFC3339, query.To)
+		if err != nil {
+			return filter, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "to must be an RFC 3339 time"}
+		}
+		filter.To = &to
+	}
+
+	return filter, nil
+}
+
+func toLoginAttemptResponses(attempts []*entity.LoginAttempt) []*models.LoginAttemptResponse {
+	responses := make([]*models.LoginAttemptResponse, 0, len(attempts))
+	for _, attempt := range attempts {
+		responses = append(responses, &models.LoginAttemptResponse{
+			Id:         attempt.Id,
+			UserId:     attempt.UserId,
+			Identifier: attempt.Identifier,
+			IpAddress:  attempt.IpAddress,
+			UserAgent:  attempt.UserAgent,
+			Outcome:    attempt.Outcome,
+			CreatedAt:  helpers.FormatTime(attempt.CreatedAt),
+		})
+	}
+	return responses
+}
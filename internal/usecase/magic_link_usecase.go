@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/mail"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"net/url"
+	"time"
+)
+
+// MagicLinkUseCase signs users in with a single use link sent by email. The link is bound to the browser that
+// asked for it through a secret kept in a cookie, so a forwarded or intercepted email is useless elsewhere
+type MagicLinkUseCase struct {
+	AuthUseCase         *AuthUseCase
+	UserRepository      repository.UserRepositoryInterface
+	MagicLinkRepository repository.MagicLinkRepositoryInterface
+	Mailer              mail.MailerInterface
+	Validator           *validator.Validate
+	Viper               *viper.Viper
+}
+
+func NewMagicLinkUseCase(authUseCase *AuthUseCase, userRepository repository.UserRepositoryInterface, magicLinkRepository repository.MagicLinkRepositoryInterface, mailer mail.MailerInterface, validator *validator.Validate, viper *viper.Viper) *MagicLinkUseCase {
+	return &MagicLinkUseCase{
+		AuthUseCase:         authUseCase,
+		UserRepository:      userRepository,
+		MagicLinkRepository: magicLinkRepository,
+		Mailer:              mailer,
+		Validator:           validator,
+		Viper:               viper,
+	}
+}
+
+// TTL is how long a magic link stays usable
+func (u *MagicLinkUseCase) TTL() time.Duration {
+	return time.Duration(u.Viper.GetInt("magic_link.ttl_minutes")) * time.Minute
+}
+
+// RequestMagicLink emails a link when the email belongs to a user, and returns the browser secret to keep in a
+// cookie. Unknown emails get the same answer so the endpoint can't be used to find accounts
+func (u *MagicLinkUseCase) RequestMagicLink(ctx context.Context, request *models.MagicLinkRequest) (string, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if !u.Viper.GetBool("magic_link.enabled") {
+		return "", &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Magic link sign in is disabled"}
+	}
+
+	err := u.Validator.Struct(request)
+	if err != nil {
+		return "", &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+
+	browserSecret, err := helpers.GenerateRandomToken(32)
+	if err != nil {
+		fmt.Println("Error while generating magic link: ", err)
+		return "", &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	user, err := u.AuthUseCase.findUserByEmail(ctxWithTimeout, request.Email)
+	if err != nil {
+		fmt.Println("Error while getting user: ", err)
+		return "", toRepositoryError(err)
+	}
+	if user == nil || !user.IsActive(time.Now()) {
+		return browserSecret, nil
+	}
+
+	//a throttled request gets the same answer too, the link sent before still works
+	allowed, err := u.canSend(ctxWithTimeout, user.Id)
+	if err != nil {
+		fmt.Println("Error while counting magic links: ", err)
+		return "", toRepositoryError(err)
+	}
+	if !allowed {
+		return browserSecret, nil
+	}
+
+	token, err := helpers.GenerateRandomToken(32)
+	if err != nil {
+		fmt.Println("Error while generating magic link: ", err)
+		return "", &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	err = u.MagicLinkRepository.Save(ctxWithTimeout, &entity.MagicLink{
+		UserId:      user.Id,
+		TokenHash:   helpers.HashToken(token),
+		BrowserHash: helpers.HashToken(browserSecret),
+		IpAddress:   request.IpAddress,
+		UserAgent:   helpers.Truncate(request.UserAgent, 255),
+		ExpiresAt:   time.Now().Add(u.TTL()),
+	})
+	if err != nil {
+		fmt.Println("Error while saving magic link: ", err)
+		return "", toRepositoryError(err)
+	}
+
+	link := u.Viper.GetString("magic_link.url") + "?token=" + url.QueryEscape(token)
+	err = u.Mailer.Send(ctxWithTimeout, &mail.Message{
+		To:      user.Email,
+		Subject: "Your sign in link",
+		Body: fmt.Sprintf("Open this link to sign in:\n\n%s\n\nIt expires in %d minutes and only works once, in the browser where you asked for it. "+
+			"If you didn't ask for it, you can ignore this email.", link, int(u.TTL().Minutes())),
+	})
+	if err != nil {
+		fmt.Println("Error while sending magic link: ", err)
+		return "", &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	return browserSecret, nil
+}
+
+// canSend applies magic_link.resend_interval_seconds and magic_link.max_per_hour, like the one-time codes
+func (u *MagicLinkUseCase) canSend(ctx context.Context, userID int) (bool, error) {
+	now := time.Now()
+	latest, err := u.MagicLinkRepository.FindLatest(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	resendInterval := time.Duration(u.Viper.GetInt("magic_link.resend_interval_seconds")) * time.Second
+	if latest != nil && latest.CreatedAt.Add(resendInterval).After(now) {
+		return false, nil
+	}
+
+	maxPerHour := u.Viper.GetInt("magic_link.max_per_hour")
+	if maxPerHour > 0 {
+		count, err := u.MagicLinkRepository.CountSince(ctx, userID, now.Add(-time.Hour))
+		if err != nil {
+			return false, err
+		}
+		if count >= int64(maxPerHour) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RedeemMagicLink signs the user in like AuthUseCase.SignIn. browserSecret is the cookie set by RequestMagicLink
+func (u *MagicLinkUseCase) RedeemMagicLink(ctx context.Context, token string, browserSecret string, client models.ClientInfo) (*models.SignInResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if !u.Viper.GetBool("magic_link.enabled") {
+		return nil, &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Magic link sign in is disabled"}
+	}
+
+	invalidLink := &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Magic link is invalid or expired"}
+	if token == "" {
+		return nil, invalidLink
+	}
+
+	magicLink, err := u.MagicLinkRepository.FindOneByTokenHash(ctxWithTimeout, helpers.HashToken(token))
+	if err != nil {
+		fmt.Println("Error while getting magic link: ", err)
+		return nil, toRepositoryError(err)
+	}
+	now := time.Now()
+	if magicLink == nil || !magicLink.IsUsable(now) {
+		return nil, invalidLink
+	}
+
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, magicLink.UserId)
+	if err != nil {
+		fmt.Println("Error while getting user: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if user == nil {
+		return nil, invalidLink
+	}
+
+	//the link stays usable, so the owner can still open it in the right browser
+	if subtle.ConstantTimeCompare([]byte(helpers.HashToken(browserSecret)), []byte(magicLink.BrowserHash)) != 1 {
+		u.AuthUseCase.RecordLoginAttempt(ctxWithTimeout, user.Email, client, user, entity.LoginOutcomeInvalidLink)
+		return nil, &models.ErrorResponse{Code: 403, Status: "Forbidden", Message: "Magic link must be opened in the browser that requested it"}
+	}
+
+	used, err := u.MagicLinkRepository.MarkUsed(ctxWithTimeout, magicLink.Id, now)
+	if err != nil {
+		fmt.Println("Error while using magic link: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if !used {
+		return nil, invalidLink
+	}
+
+	return u.AuthUseCase.CompleteSignIn(ctxWithTimeout, user, user.Email, client, []string{AmrEmail})
+}
+
+// PurgeExpiredMagicLinks deletes the links that can no longer be used
+func (u *MagicLinkUseCase) PurgeExpiredMagicLinks(ctx context.Context) (int64, error) {
+	return u.MagicLinkRepository.DeleteExpiredBefore(ctx, time.Now())
+}
@@ -0,0 +1,745 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"golang-authentication/internal/security"
+	"golang-authentication/internal/sms"
+	"strings"
+	"time"
+)
+
+// MfaUseCase manages the authenticator app, the phone number and the recovery codes of a user, and exchanges the
+// MFA challenge of AuthUseCase.SignIn for a session
+type MfaUseCase struct {
+	AuthUseCase                  *AuthUseCase
+	UserRepository               repository.UserRepositoryInterface
+	TotpCredentialRepository     repository.TotpCredentialRepositoryInterface
+	RecoveryCodeRepository       repository.RecoveryCodeRepositoryInterface
+	WebAuthnCredentialRepository repository.WebAuthnCredentialRepositoryInterface
+	SMSSender                    sms.SMSSender
+	Validator                    *validator.Validate
+	Viper                        *viper.Viper
+	TOTP                         *security.TOTP
+	SecretBox                    *security.SecretBox
+	SmsEnrollmentCodes           *oneTimeCodes
+	SmsLoginCodes                *oneTimeCodes
+}
+
+func NewMfaUseCase(authUseCase *AuthUseCase, userRepository repository.UserRepositoryInterface, totpCredentialRepository repository.TotpCredentialRepositoryInterface, recoveryCodeRepository repository.RecoveryCodeRepositoryInterface, webAuthnCredentialRepository repository.WebAuthnCredentialRepositoryInterface, oneTimeCodeRepository repository.OneTimeCodeRepositoryInterface, smsSender sms.SMSSender, validator *validator.Validate, viper *viper.Viper) *MfaUseCase {
+	return &MfaUseCase{
+		AuthUseCase:                  authUseCase,
+		UserRepository:               userRepository,
+		TotpCredentialRepository:     totpCredentialRepository,
+		RecoveryCodeRepository:       recoveryCodeRepository,
+		WebAuthnCredentialRepository: webAuthnCredentialRepository,
+		SMSSender:                    smsSender,
+		Validator:                    validator,
+		Viper:                        viper,
+		TOTP:                         security.NewTOTP(viper.GetInt("mfa.totp_skew")),
+		SecretBox:                    security.NewSecretBox(viper.GetString("key.mfa")),
+		SmsEnrollmentCodes: &oneTimeCodes{
+			Repository: oneTimeCodeRepository,
+			Viper:      viper,
+			Purpose:    entity.OneTimeCodePurposeSmsEnrollment,
+			ConfigKey:  "otp.sms",
+		},
+		SmsLoginCodes: &oneTimeCodes{
+			Repository: oneTimeCodeRepository,
+			Viper:      viper,
+			Purpose:    entity.OneTimeCodePurposeSmsLogin,
+			ConfigKey:  "otp.sms",
+		},
+	}
+}
+
+// EnrollTotp starts an enrollment with a new secret. It only protects the account once confirmed with ConfirmTotp
+func (u *MfaUseCase) EnrollTotp(ctx context.Context, userID int) (*models.TotpEnrollmentResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := u.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := u.TotpCredentialRepository.FindOneByUserId(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting totp credential: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if credential != nil && credential.ConfirmedAt != nil {
+		return nil, &models.ErrorResponse{Code: 409, Status: "Conflict", Message: "Authenticator app is already enabled"}
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		fmt.Println("Error while generating totp secret: ", err)
+		return nil, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+	sealed, err := u.SecretBox.Seal(secret)
+	if err != nil {
+		fmt.Println("Error while encrypting totp secret: ", err)
+		return nil, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	err = u.TotpCredentialRepository.Save(ctxWithTimeout, &entity.TotpCredential{UserId: userID, Secret: sealed})
+	if err != nil {
+		fmt.Println("Error while saving totp credential: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	return &models.TotpEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningUri: u.TOTP.ProvisioningURI(u.Viper.GetString("mfa.issuer"), user.Email, secret),
+	}, nil
+}
+
+// ConfirmTotp enables MFA with the first code of the authenticator app. When it is the first second factor of the
+// user, fresh recovery codes are returned. They are only shown this once
+func (u *MfaUseCase) ConfirmTotp(ctx context.Context, userID int, request *models.MfaCodeRequest) (*models.RecoveryCodesResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.validate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := u.TotpCredentialRepository.FindOneByUserId(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting totp credential: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if credential == nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Please enroll an authenticator app first"}
+	}
+	if credential.ConfirmedAt != nil {
+		return nil, &models.ErrorResponse{Code: 409, Status: "Conflict", Message: "Authenticator app is already enabled"}
+	}
+
+	now := time.Now()
+	step, err := u.verifyTotpCode(credential, request.Code, now)
+	if err != nil {
+		return nil, err
+	}
+	if step < 0 {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is invalid"}
+	}
+
+	err = u.TotpCredentialRepository.Confirm(ctxWithTimeout, userID, now, step)
+	if err != nil {
+		fmt.Println("Error while confirming totp credential: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	user, err := u.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+	//the recovery codes of another second factor stay valid
+	if user.MfaEnabled {
+		return &models.RecoveryCodesResponse{}, nil
+	}
+	err = u.updateMfaEnabled(ctxWithTimeout, user, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.replaceRecoveryCodes(ctxWithTimeout, userID)
+}
+
+// DisableTotp removes the authenticator app, and turns MFA off when no other second factor is left. It takes a
+// current code so a stolen session alone can't remove the second factor
+func (u *MfaUseCase) DisableTotp(ctx context.Context, userID int, request *models.MfaCodeRequest) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.validate(request)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return err
+	}
+	credential, err := u.TotpCredentialRepository.FindOneByUserId(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting totp credential: ", err)
+		return toRepositoryError(err)
+	}
+	if credential == nil || credential.ConfirmedAt == nil {
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Authenticator app is not enabled"}
+	}
+
+	method, err := u.verifySecondFactor(ctxWithTimeout, user, request.Code)
+	if err != nil {
+		return err
+	}
+	if method == "" {
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is invalid"}
+	}
+
+	err = u.TotpCredentialRepository.DeleteByUserId(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while deleting totp credential: ", err)
+		return toRepositoryError(err)
+	}
+
+	return u.refreshMfaEnabled(ctxWithTimeout, user)
+}
+
+// EnrollPhone texts a code to the phone number, which becomes the SMS second factor once confirmed with ConfirmPhone.
+// A user who already has a phone number has to remove it first
+func (u *MfaUseCase) EnrollPhone(ctx context.Context, userID int, request *models.PhoneEnrollmentRequest) (*models.SmsCodeResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.checkSmsEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.Validator.Struct(request)
+	if err != nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+	phoneNumber, ok := helpers.NormalizePhoneNumber(request.PhoneNumber)
+	if !ok {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Phone number must start with + and its country code"}
+	}
+
+	user, err := u.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+	//the number is changed by removing it first, which needs a second factor
+	if user.PhoneNumber != nil {
+		return nil, &models.ErrorResponse{Code: 409, Status: "Conflict", Message: "Phone number is already enabled"}
+	}
+
+	return u.sendSmsCode(ctxWithTimeout, u.SmsEnrollmentCodes, userID, phoneNumber, "Your verification code is %s. It expires in %d minutes.")
+}
+
+// ConfirmPhone saves the phone number the code was sent to. When it is the first second factor of the user, MFA
+// is turned on and recovery codes are returned
+func (u *MfaUseCase) ConfirmPhone(ctx context.Context, userID int, request *models.MfaCodeRequest) (*models.PhoneEnrollmentResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.checkSmsEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.validate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PhoneNumber != nil {
+		return nil, &models.ErrorResponse{Code: 409, Status: "Conflict", Message: "Phone number is already enabled"}
+	}
+
+	matched, err := u.SmsEnrollmentCodes.verify(ctxWithTimeout, userID, strings.TrimSpace(request.Code))
+	if err != nil {
+		fmt.Println("Error while verifying sms code: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if matched == nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is invalid"}
+	}
+
+	err = u.UserRepository.UpdatePhoneNumber(ctxWithTimeout, userID, &matched.Destination)
+	if err != nil {
+		fmt.Println("Error while saving phone number: ", err)
+		return nil, toRepositoryError(err)
+	}
+	user.PhoneNumber = &matched.Destination
+
+	response := &models.PhoneEnrollmentResponse{PhoneNumber: matched.Destination}
+	if !user.MfaEnabled {
+		err = u.updateMfaEnabled(ctxWithTimeout, user, true)
+		if err != nil {
+			return nil, err
+		}
+		recoveryCodes, err := u.replaceRecoveryCodes(ctxWithTimeout, userID)
+		if err != nil {
+			return nil, err
+		}
+		response.RecoveryCodes = recoveryCodes.RecoveryCodes
+	}
+
+	return response, nil
+}
+
+// RemovePhone stops sending SMS codes to the user, and turns MFA off when no other second factor is left
+func (u *MfaUseCase) RemovePhone(ctx context.Context, userID int, request *models.MfaCodeRequest) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.validate(request)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return err
+	}
+	if user.PhoneNumber == nil {
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Phone number is not enrolled"}
+	}
+
+	method, err := u.verifySecondFactor(ctxWithTimeout, user, request.Code)
+	if err != nil {
+		return err
+	}
+	if method == "" {
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is invalid"}
+	}
+
+	err = u.UserRepository.UpdatePhoneNumber(ctxWithTimeout, userID, nil)
+	if err != nil {
+		fmt.Println("Error while removing phone number: ", err)
+		return toRepositoryError(err)
+	}
+	user.PhoneNumber = nil
+
+	return u.refreshMfaEnabled(ctxWithTimeout, user)
+}
+
+// SendSignInCode texts a code for the MFA challenge of AuthUseCase.SignIn, to exchange at SignIn
+func (u *MfaUseCase) SendSignInCode(ctx context.Context, request *models.MfaSmsRequest) (*models.SmsCodeResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.checkSmsEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.Validator.Struct(request)
+	if err != nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+
+	userID, _, err := u.AuthUseCase.VerifyMfaChallengeToken(request.MfaToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := u.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsLocked(time.Now()) {
+		return nil, u.AuthUseCase.lockedError()
+	}
+	if user.PhoneNumber == nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Phone number is not enrolled"}
+	}
+
+	return u.sendSmsCode(ctxWithTimeout, u.SmsLoginCodes, userID, *user.PhoneNumber, "Your sign in code is %s. It expires in %d minutes. Don't share it with anyone.")
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user, used or not
+func (u *MfaUseCase) RegenerateRecoveryCodes(ctx context.Context, userID int, request *models.MfaCodeRequest) (*models.RecoveryCodesResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.validate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MfaEnabled {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Two-factor authentication is not enabled"}
+	}
+
+	method, err := u.verifySecondFactor(ctxWithTimeout, user, request.Code)
+	if err != nil {
+		return nil, err
+	}
+	if method == "" {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is invalid"}
+	}
+
+	return u.replaceRecoveryCodes(ctxWithTimeout, userID)
+}
+
+// SignIn exchanges the MFA token of AuthUseCase.SignIn and a second factor for the session. Wrong codes count
+// as failed sign in attempts, so guessing ends in a lockout
+func (u *MfaUseCase) SignIn(ctx context.Context, request *models.MfaSignInRequest) (*models.SignInResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.Validator.Struct(request)
+	if err != nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+
+	userID, methods, err := u.AuthUseCase.VerifyMfaChallengeToken(request.MfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting user: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if user == nil || !user.MfaEnabled {
+		return nil, &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Invalid token"}
+	}
+
+	if user.IsLocked(time.Now()) {
+		return nil, u.AuthUseCase.lockedError()
+	}
+
+	method, err := u.verifySecondFactor(ctxWithTimeout, user, request.Code)
+	if err != nil {
+		return nil, err
+	}
+	if method == "" {
+		u.AuthUseCase.RecordLoginAttempt(ctxWithTimeout, user.Email, request.ClientInfo, user, entity.LoginOutcomeInvalidMfaCode)
+		err = u.AuthUseCase.registerFailedSignIn(ctxWithTimeout, user)
+		if err != nil {
+			return nil, err
+		}
+		if user.IsLocked(time.Now()) {
+			return nil, u.AuthUseCase.lockedError()
+		}
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is invalid"}
+	}
+
+	err = u.AuthUseCase.resetFailedSignIns(ctxWithTimeout, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.AuthUseCase.CompleteSecondFactor(ctxWithTimeout, user, user.Email, request.ClientInfo, WithSecondFactor(methods, method), request.RememberDevice)
+}
+
+// Reauthenticate upgrades the session of claims once the user gave the password again, and a second factor when
+// MFA is enabled. The session then counts as authenticated now, and the new access token says so. Wrong answers
+// count as failed sign in attempts
+func (u *MfaUseCase) Reauthenticate(ctx context.Context, claims *AccessClaims, request *models.ReauthenticateRequest) (*models.GetTokenResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.Validator.Struct(request)
+	if err != nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+
+	session, err := u.AuthUseCase.findActiveSession(ctxWithTimeout, claims)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	user, err := u.findUser(ctxWithTimeout, claims.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsLocked(now) {
+		return nil, u.AuthUseCase.lockedError()
+	}
+
+	if !u.AuthUseCase.PasswordUseCase.ComparePassword(user.Password, request.Password) {
+		u.AuthUseCase.RecordLoginAttempt(ctxWithTimeout, user.Email, request.ClientInfo, user, entity.LoginOutcomeInvalidPassword)
+		return nil, u.failReauthentication(ctxWithTimeout, user, "Password is invalid")
+	}
+
+	methods := []string{AmrPassword}
+	if user.MfaEnabled {
+		if strings.TrimSpace(request.Code) == "" {
+			return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is required"}
+		}
+		method, err := u.verifySecondFactor(ctxWithTimeout, user, request.Code)
+		if err != nil {
+			return nil, err
+		}
+		if method == "" {
+			u.AuthUseCase.RecordLoginAttempt(ctxWithTimeout, user.Email, request.ClientInfo, user, entity.LoginOutcomeInvalidMfaCode)
+			return nil, u.failReauthentication(ctxWithTimeout, user, "Code is invalid")
+		}
+		methods = WithSecondFactor(methods, method)
+	}
+
+	err = u.AuthUseCase.resetFailedSignIns(ctxWithTimeout, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.AuthUseCase.reauthenticateSession(ctxWithTimeout, session, methods, now)
+}
+
+// SendReauthenticationCode texts a code to the phone number of the user, to give as the second factor of
+// Reauthenticate
+func (u *MfaUseCase) SendReauthenticationCode(ctx context.Context, userID int) (*models.SmsCodeResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.checkSmsEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PhoneNumber == nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Phone number is not enrolled"}
+	}
+
+	return u.sendSmsCode(ctxWithTimeout, u.SmsLoginCodes, userID, *user.PhoneNumber, "Your verification code is %s. It expires in %d minutes. Don't share it with anyone.")
+}
+
+func (u *MfaUseCase) failReauthentication(ctx context.Context, user *entity.User, message string) error {
+	err := u.AuthUseCase.registerFailedSignIn(ctx, user)
+	if err != nil {
+		return err
+	}
+	if user.IsLocked(time.Now()) {
+		return u.AuthUseCase.lockedError()
+	}
+	return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: message}
+}
+
+// verifySecondFactor accepts a code of the authenticator app, the last SMS code or an unused recovery code, and
+// uses it up. It returns the authentication method of the code, or "" when the code is invalid
+func (u *MfaUseCase) verifySecondFactor(ctx context.Context, user *entity.User, code string) (string, error) {
+	code = normalizeRecoveryCode(code)
+
+	if isTotpCode(code) {
+		valid, err := u.verifyTotp(ctx, user, code)
+		if err != nil {
+			return "", err
+		}
+		if valid {
+			return AmrOtp, nil
+		}
+		if user.PhoneNumber == nil {
+			return "", nil
+		}
+
+		//SMS codes have 6 digits too
+		matched, err := u.SmsLoginCodes.verify(ctx, user.Id, code)
+		if err != nil {
+			fmt.Println("Error while verifying sms code: ", err)
+			return "", toRepositoryError(err)
+		}
+		if matched == nil {
+			return "", nil
+		}
+		return AmrSms, nil
+	}
+
+	used, err := u.RecoveryCodeRepository.Use(ctx, user.Id, hashOneTimeCode(u.Viper, user.Id, code), time.Now())
+	if err != nil {
+		fmt.Println("Error while using recovery code: ", err)
+		return "", toRepositoryError(err)
+	}
+	if !used {
+		return "", nil
+	}
+	return AmrOtp, nil
+}
+
+func (u *MfaUseCase) verifyTotp(ctx context.Context, user *entity.User, code string) (bool, error) {
+	credential, err := u.TotpCredentialRepository.FindOneByUserId(ctx, user.Id)
+	if err != nil {
+		fmt.Println("Error while getting totp credential: ", err)
+		return false, toRepositoryError(err)
+	}
+	if credential == nil || credential.ConfirmedAt == nil {
+		return false, nil
+	}
+
+	step, err := u.verifyTotpCode(credential, code, time.Now())
+	if err != nil || step < 0 {
+		return false, err
+	}
+
+	used, err := u.TotpCredentialRepository.UseStep(ctx, user.Id, step)
+	if err != nil {
+		fmt.Println("Error while using totp code: ", err)
+		return false, toRepositoryError(err)
+	}
+	return used, nil
+}
+
+// verifyTotpCode returns the step matching the code, or -1
+func (u *MfaUseCase) verifyTotpCode(credential *entity.TotpCredential, code string, now time.Time) (int64, error) {
+	secret, err := u.SecretBox.Open(credential.Secret)
+	if err != nil {
+		fmt.Println("Error while decrypting totp secret: ", err)
+		return -1, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	step, err := u.TOTP.Verify(secret, strings.TrimSpace(code), now)
+	if err != nil {
+		fmt.Println("Error while verifying totp code: ", err)
+		return -1, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+	return step, nil
+}
+
+func (u *MfaUseCase) replaceRecoveryCodes(ctx context.Context, userID int) (*models.RecoveryCodesResponse, error) {
+	count := u.Viper.GetInt("mfa.recovery_codes")
+	codes := make([]string, 0, count)
+	hashes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		code, err := helpers.GenerateRandomToken(5)
+		if err != nil {
+			fmt.Println("Error while generating recovery code: ", err)
+			return nil, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashOneTimeCode(u.Viper, userID, code))
+	}
+
+	err := u.RecoveryCodeRepository.ReplaceAll(ctx, userID, hashes)
+	if err != nil {
+		fmt.Println("Error while saving recovery codes: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// sendSmsCode texts a new code of codes to phoneNumber. body takes the code and its lifetime in minutes
+func (u *MfaUseCase) sendSmsCode(ctx context.Context, codes *oneTimeCodes, userID int, phoneNumber string, body string) (*models.SmsCodeResponse, error) {
+	code, err := codes.issue(ctx, userID, phoneNumber)
+	if err != nil {
+		fmt.Println("Error while issuing sms code: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if code == "" {
+		return nil, &models.ErrorResponse{Code: 429, Status: "Too Many Requests", Message: "Too many codes were sent. Please try again later"}
+	}
+
+	err = u.SMSSender.Send(ctx, &sms.Message{To: phoneNumber, Body: fmt.Sprintf(body, code, int(codes.TTL().Minutes()))})
+	if err != nil {
+		fmt.Println("Error while sending sms code: ", err)
+		return nil, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	return &models.SmsCodeResponse{PhoneNumber: helpers.MaskPhoneNumber(phoneNumber)}, nil
+}
+
+// refreshMfaEnabled is called once a second factor is removed. It revokes the trusted devices, which may have been
+// trusted with that factor, and turns MFA off once the user has neither a phone number, a confirmed authenticator app
+// nor a passkey left
+func (u *MfaUseCase) refreshMfaEnabled(ctx context.Context, user *entity.User) error {
+	err := u.AuthUseCase.TrustedDeviceRepo.DeleteAllByUserId(ctx, user.Id)
+	if err != nil {
+		fmt.Println("Error while deleting trusted devices: ", err)
+		return toRepositoryError(err)
+	}
+
+	if user.PhoneNumber != nil {
+		return u.updateMfaEnabled(ctx, user, true)
+	}
+
+	credential, err := u.TotpCredentialRepository.FindOneByUserId(ctx, user.Id)
+	if err != nil {
+		fmt.Println("Error while getting totp credential: ", err)
+		return toRepositoryError(err)
+	}
+	if credential != nil && credential.ConfirmedAt != nil {
+		return u.updateMfaEnabled(ctx, user, true)
+	}
+
+	passkeys, err := u.WebAuthnCredentialRepository.CountByUserId(ctx, user.Id)
+	if err != nil {
+		fmt.Println("Error while counting passkeys: ", err)
+		return toRepositoryError(err)
+	}
+	return u.updateMfaEnabled(ctx, user, passkeys > 0)
+}
+
+// updateMfaEnabled keeps users.mfa_enabled in line with the second factors left. Once the last one is removed
+// the recovery codes go too
+func (u *MfaUseCase) updateMfaEnabled(ctx context.Context, user *entity.User, enabled bool) error {
+	if !enabled {
+		err := u.RecoveryCodeRepository.DeleteAllByUserId(ctx, user.Id)
+		if err != nil {
+			fmt.Println("Error while deleting recovery codes: ", err)
+			return toRepositoryError(err)
+		}
+	}
+	if user.MfaEnabled == enabled {
+		return nil
+	}
+
+	err := u.UserRepository.UpdateMfaEnabled(ctx, user.Id, enabled)
+	if err != nil {
+		fmt.Println("Error while updating mfa: ", err)
+		return toRepositoryError(err)
+	}
+	user.MfaEnabled = enabled
+	return nil
+}
+
+func (u *MfaUseCase) findUser(ctx context.Context, userID int) (*entity.User, error) {
+	user, err := u.UserRepository.FindOneById(ctx, userID)
+	if err != nil {
+		fmt.Println("Error while getting user: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if user == nil {
+		return nil, &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "User not found"}
+	}
+	return user, nil
+}
+
+func (u *MfaUseCase) checkSmsEnabled() error {
+	if !u.Viper.GetBool("otp.sms.enabled") {
+		return &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "SMS codes are disabled"}
+	}
+	return nil
+}
+
+func (u *MfaUseCase) validate(request *models.MfaCodeRequest) error {
+	err := u.Validator.Struct(request)
+	if err != nil {
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+	return nil
+}
+
+// normalizeRecoveryCode drops the separators and case users add when typing a code
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func isTotpCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, character := range code {
+		if character < '0' || character > '9' {
+			return false
+		}
+	}
+	return true
+}
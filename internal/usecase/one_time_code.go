@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/repository"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// oneTimeCodes issues and checks 6 digit codes for one purpose. Its settings are read under configKey:
+// ttl_minutes, max_attempts, resend_interval_seconds, max_per_hour and max_per_destination_per_hour
+type oneTimeCodes struct {
+	Repository repository.OneTimeCodeRepositoryInterface
+	Viper      *viper.Viper
+	Purpose    string
+	ConfigKey  string
+}
+
+func (c *oneTimeCodes) TTL() time.Duration {
+	return time.Duration(c.Viper.GetInt(c.ConfigKey+".ttl_minutes")) * time.Minute
+}
+
+func (c *oneTimeCodes) maxAttempts() int {
+	return c.Viper.GetInt(c.ConfigKey + ".max_attempts")
+}
+
+// issue saves a new code and returns it, or returns "" when the user asked for codes too often
+func (c *oneTimeCodes) issue(ctx context.Context, userID int, destination string) (string, error) {
+	now := time.Now()
+	latest, err := c.Repository.FindLatest(ctx, userID, c.Purpose)
+	if err != nil {
+		return "", err
+	}
+	resendInterval := time.Duration(c.Viper.GetInt(c.ConfigKey+".resend_interval_seconds")) * time.Second
+	if latest != nil && latest.CreatedAt.Add(resendInterval).After(now) {
+		return "", nil
+	}
+
+	maxPerHour := c.Viper.GetInt(c.ConfigKey + ".max_per_hour")
+	if maxPerHour > 0 {
+		count, err := c.Repository.CountSince(ctx, userID, c.Purpose, now.Add(-time.Hour))
+		if err != nil {
+			return "", err
+		}
+		if count >= int64(maxPerHour) {
+			return "", nil
+		}
+	}
+
+	//a destination shared by several accounts, or tried by several of them, is limited as a whole
+	maxPerDestination := c.Viper.GetInt(c.ConfigKey + ".max_per_destination_per_hour")
+	if maxPerDestination > 0 && destination != "" {
+		count, err := c.Repository.CountByDestinationSince(ctx, destination, now.Add(-time.Hour))
+		if err != nil {
+			return "", err
+		}
+		if count >= int64(maxPerDestination) {
+			return "", nil
+		}
+	}
+
+	code, err := generateOneTimeCode()
+	if err != nil {
+		return "", err
+	}
+
+	err = c.Repository.Save(ctx, &entity.OneTimeCode{
+		UserId:      userID,
+		Purpose:     c.Purpose,
+		Destination: destination,
+		CodeHash:    hashOneTimeCode(c.Viper, userID, code),
+		ExpiresAt:   now.Add(c.TTL()),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// verify checks code against the latest code of the user and uses it up when it matches, returning it so the
+// caller can read its destination. Every guess counts against max_attempts, whether it matches or not
+func (c *oneTimeCodes) verify(ctx context.Context, userID int, code string) (*entity.OneTimeCode, error) {
+	latest, err := c.Repository.FindLatest(ctx, userID, c.Purpose)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if latest == nil || !latest.IsUsable(now, c.maxAttempts()) {
+		return nil, nil
+	}
+
+	allowed, err := c.Repository.RegisterAttempt(ctx, latest.Id, c.maxAttempts())
+	if err != nil || !allowed {
+		return nil, err
+	}
+
+	if !hmac.Equal([]byte(hashOneTimeCode(c.Viper, userID, code)), []byte(latest.CodeHash)) {
+		return nil, nil
+	}
+
+	used, err := c.Repository.MarkUsed(ctx, latest.Id, now)
+	if err != nil || !used {
+		return nil, err
+	}
+	return latest, nil
+}
+
+func generateOneTimeCode() (string, error) {
+	number, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", number.Int64()), nil
+}
+
+// hashOneTimeCode keys the hash with key.otp, a 6 digit code alone would be found by trying them all
+func hashOneTimeCode(viper *viper.Viper, userID int, code string) string {
+	mac := hmac.New(sha256.New, []byte(viper.GetString("key.otp")))
+	mac.Write([]byte(strconv.Itoa(userID) + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
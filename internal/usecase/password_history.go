@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+)
+
+const PasswordReused = "password_reused"
+
+// passwordHistorySize is how many passwords, including the current one, can't be reused. 0 disables the check
+func passwordHistorySize(viper *viper.Viper) int {
+	return viper.GetInt("password.history.size")
+}
+
+// checkPasswordReuse rejects the password when it matches the current password or one of the previous ones
+// kept in the history
+func checkPasswordReuse(ctx context.Context, viper *viper.Viper, passwordUseCase *PasswordUseCase, passwordHistoryRepository repository.PasswordHistoryRepositoryInterface, user *entity.User, password string) error {
+	size := passwordHistorySize(viper)
+	if size <= 0 {
+		return nil
+	}
+
+	reused := passwordUseCase.ComparePassword(user.Password, password)
+	if !reused && size > 1 {
+		histories, err := passwordHistoryRepository.FindLatestByUserId(ctx, user.Id, size-1)
+		if err != nil {
+			fmt.Println("Error while getting password history: ", err)
+			return toRepositoryError(err)
+		}
+		for _, history := range histories {
+			if passwordUseCase.ComparePassword(history.Password, password) {
+				reused = true
+				break
+			}
+		}
+	}
+
+	if reused {
+		message := fmt.Sprintf("Password must not be one of your last %d passwords", size)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+			Errors:  []models.ErrorDetail{{Code: PasswordReused, Message: message}},
+		}
+	}
+	return nil
+}
+
+// recordPasswordHistory keeps the hash that is being replaced and drops the entries that fell out of the history
+func recordPasswordHistory(ctx context.Context, viper *viper.Viper, passwordHistoryRepository repository.PasswordHistoryRepositoryInterface, userID int, previousHash string) error {
+	size := passwordHistorySize(viper)
+	if size <= 1 {
+		return nil
+	}
+
+	_, err := passwordHistoryRepository.Save(ctx, &entity.PasswordHistory{UserId: userID, Password: previousHash})
+	if err != nil {
+		fmt.Println("Error while saving password history: ", err)
+		return toRepositoryError(err)
+	}
+
+	err = passwordHistoryRepository.DeleteAllByUserIdExceptLatest(ctx, userID, size-1)
+	if err != nil {
+		fmt.Println("Error while pruning password history: ", err)
+		return toRepositoryError(err)
+	}
+	return nil
+}
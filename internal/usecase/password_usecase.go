@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/security"
+)
+
+const PasswordBreached = "password_breached"
+
+// PasswordUseCase holds the rules shared by every flow that sets a password
+type PasswordUseCase struct {
+	Viper          *viper.Viper
+	PasswordPolicy *security.PasswordPolicy
+	BreachChecker  security.BreachCheckerInterface
+	PasswordHasher *security.PasswordHasher
+}
+
+func NewPasswordUseCase(viper *viper.Viper) *PasswordUseCase {
+	return &PasswordUseCase{
+		Viper:          viper,
+		PasswordPolicy: security.NewPasswordPolicy(viper),
+		BreachChecker:  security.NewBreachChecker(viper),
+		PasswordHasher: security.NewPasswordHasher(viper),
+	}
+}
+
+// ValidateNewPassword runs the password policy and the breach screening. personalInfo is passed to the policy
+func (u *PasswordUseCase) ValidateNewPassword(password string, personalInfo ...string) error {
+	violations := u.PasswordPolicy.Validate(password, personalInfo...)
+
+	breached, err := u.IsBreached(password)
+	if err != nil {
+		return err
+	}
+	if breached {
+		violations = append(violations, models.ErrorDetail{Code: PasswordBreached, Message: "Password has appeared in a data breach. Please choose another password"})
+	}
+
+	if len(violations) > 0 {
+		return &models.ErrorResponse{Message: violations[0].Message, Code: 400, Status: "Bad Request", Errors: violations}
+	}
+	return nil
+}
+
+func (u *PasswordUseCase) IsBreached(password string) (bool, error) {
+	breached, err := u.BreachChecker.IsBreached(password)
+	if err != nil {
+		fmt.Println("Error while checking breached password: ", err)
+		return false, &models.ErrorResponse{Code: 500, Message: "Something wrong", Status: "Internal Server Error"}
+	}
+	return breached, nil
+}
+
+func (u *PasswordUseCase) HashPassword(password string) (string, error) {
+	hashedPassword, err := u.PasswordHasher.Hash(password)
+	if err != nil {
+		fmt.Println("Error while hashing password :", err)
+		return "", &models.ErrorResponse{Code: 500, Message: "Something wrong", Status: "Internal Server Error"}
+	}
+
+	return hashedPassword, nil
+}
+
+// ComparePassword verifies the password against a hash of any supported algorithm
+func (u *PasswordUseCase) ComparePassword(hashedPassword string, password string) bool {
+	match, err := u.PasswordHasher.Verify(hashedPassword, password)
+	if err != nil {
+		fmt.Println("Error while comparing password: ", err)
+		return false
+	}
+	return match
+}
+
+// NeedsRehash reports whether the hash should be replaced by one of the configured algorithm and parameters
+func (u *PasswordUseCase) NeedsRehash(hashedPassword string) bool {
+	return u.PasswordHasher.NeedsRehash(hashedPassword)
+}
+
+// CheckHash returns an error when the hash can't be verified, because of its format or of cost parameters out of
+// bounds, used to refuse imported hashes before they are stored
+func (u *PasswordUseCase) CheckHash(hashedPassword string) error {
+	return u.PasswordHasher.Check(hashedPassword)
+}
@@ -0,0 +1,269 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"golang-authentication/internal/security"
+	"time"
+)
+
+type ProfileUseCase struct {
+	UserRepository            repository.UserRepositoryInterface
+	PasswordHistoryRepository repository.PasswordHistoryRepositoryInterface
+	TrustedDeviceRepository   repository.TrustedDeviceRepositoryInterface
+	Validator                 *validator.Validate
+	Viper                     *viper.Viper
+	EmailNormalizer           *helpers.EmailNormalizer
+	EmailDomainPolicy         *security.EmailDomainPolicy
+	PasswordUseCase           *PasswordUseCase
+}
+
+func NewProfileUseCase(userRepository repository.UserRepositoryInterface, passwordHistoryRepository repository.PasswordHistoryRepositoryInterface, trustedDeviceRepository repository.TrustedDeviceRepositoryInterface, emailDomainPolicy *security.EmailDomainPolicy, validator *validator.Validate, viper *viper.Viper) *ProfileUseCase {
+	return &ProfileUseCase{
+		UserRepository:            userRepository,
+		PasswordHistoryRepository: passwordHistoryRepository,
+		TrustedDeviceRepository:   trustedDeviceRepository,
+		EmailDomainPolicy:         emailDomainPolicy,
+		Validator:                 validator,
+		Viper:                     viper,
+		EmailNormalizer:           helpers.NewEmailNormalizer(viper),
+		PasswordUseCase:           NewPasswordUseCase(viper),
+	}
+}
+
+func (u *ProfileUseCase) GetProfile(ctx context.Context, userID int) (*models.UserResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := u.getUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toUserResponse(user), nil
+}
+
+func (u *ProfileUseCase) UpdateProfile(ctx context.Context, userID int, request *models.UpdateUserRequest) (*models.UserResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	request.Email = u.EmailNormalizer.Normalize(request.Email)
+	request.Username = normalizeUsername(request.Username)
+	err := u.Validator.Struct(request)
+	if err != nil {
+		if e, ok := err.(validator.ValidationErrors); ok {
+			message := helpers.GetFirstValidationErrorsAndConvert(e)
+			return nil, &models.ErrorResponse{Code: 400, Message: message, Status: "Bad Request"}
+		}
+		fmt.Println("Server error while validating: ", err)
+		return nil, &models.ErrorResponse{Code: 500, Message: "Something wrong", Status: "Internal Server Error"}
+	}
+
+	user, err := u.getUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Name != "" {
+		user.Name = request.Name
+	}
+
+	username := request.Username
+	if username != "" && (user.Username == nil || username != *user.Username) {
+		err = validateUsername(ctxWithTimeout, u.Viper, u.UserRepository, username, user.Id)
+		if err != nil {
+			return nil, err
+		}
+		user.Username = &username
+	}
+
+	if request.Email != "" && request.Email != user.Email {
+		//the new email has to be from a domain that could sign up
+		if violation := u.EmailDomainPolicy.Check(request.Email); violation != nil {
+			return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: violation.Message, Errors: []models.ErrorDetail{*violation}}
+		}
+
+		emailCanonical := u.EmailNormalizer.Canonicalize(request.Email)
+		//if email is already taken by another user, including one that is waiting to be purged
+		userExist, err := u.UserRepository.FindOneByEmailWithDeleted(ctxWithTimeout, emailCanonical)
+		if err != nil {
+			fmt.Println("Something error while getting user by email: ", err)
+			return nil, toRepositoryError(err)
+		}
+		if userExist != nil && userExist.Id != user.Id {
+			return nil, &models.ErrorResponse{Message: "Email already exists", Code: 400, Status: "Bad Request"}
+		}
+		user.Email = request.Email
+		user.EmailCanonical = emailCanonical
+	}
+
+	err = u.UserRepository.UpdateProfile(ctxWithTimeout, user)
+	if err != nil {
+		fmt.Println("Error while updating user: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	return toUserResponse(user), nil
+}
+
+// ChangesEmail tells whether email would replace the email of the user. When the user can't be read it says yes,
+// so a step up isn't skipped by mistake
+func (u *ProfileUseCase) ChangesEmail(ctx context.Context, userID int, email string) bool {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	email = u.EmailNormalizer.Normalize(email)
+	if email == "" {
+		return false
+	}
+
+	user, err := u.getUser(ctxWithTimeout, userID)
+	if err != nil {
+		return true
+	}
+	return email != user.Email
+}
+
+func (u *ProfileUseCase) ChangePassword(ctx context.Context, userID int, request *models.ChangePasswordRequest) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.Validator.Struct(request)
+	if err != nil {
+		if e, ok := err.(validator.ValidationErrors); ok {
+			message := helpers.GetFirstValidationErrorsAndConvert(e)
+			return &models.ErrorResponse{Code: 400, Message: message, Status: "Bad Request"}
+		}
+		fmt.Println("Server error while validating: ", err)
+		return &models.ErrorResponse{Code: 500, Message: "Something wrong", Status: "Internal Server Error"}
+	}
+
+	user, err := u.getUser(ctxWithTimeout, userID)
+	if err != nil {
+		return err
+	}
+
+	if !u.PasswordUseCase.ComparePassword(user.Password, request.CurrentPassword) {
+		return &models.ErrorResponse{Code: 400, Message: "Current password invalid", Status: "Bad Request"}
+	}
+
+	var username string
+	if user.Username != nil {
+		username = *user.Username
+	}
+	err = u.PasswordUseCase.ValidateNewPassword(request.NewPassword, user.Name, user.Email, username)
+	if err != nil {
+		return err
+	}
+
+	err = checkPasswordReuse(ctxWithTimeout, u.Viper, u.PasswordUseCase, u.PasswordHistoryRepository, user, request.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := u.PasswordUseCase.HashPassword(request.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	previousHash := user.Password
+	passwordChangedAt := time.Now()
+	user.Password = hashedPassword
+	user.PasswordChangeRequired = false
+	user.PasswordChangedAt = &passwordChangedAt
+	err = u.UserRepository.ChangePassword(ctxWithTimeout, user)
+	if err != nil {
+		fmt.Println("Error while updating password: ", err)
+		return toRepositoryError(err)
+	}
+
+	//a device remembered with the old password has to pass the second factor again
+	err = u.TrustedDeviceRepository.DeleteAllByUserId(ctxWithTimeout, user.Id)
+	if err != nil {
+		fmt.Println("Error while deleting trusted devices: ", err)
+		return toRepositoryError(err)
+	}
+
+	return recordPasswordHistory(ctxWithTimeout, u.Viper, u.PasswordHistoryRepository, user.Id, previousHash)
+}
+
+// DeleteAccount soft-deletes the user. The account is purged permanently once the grace period is over
+func (u *ProfileUseCase) DeleteAccount(ctx context.Context, userID int) (*models.AccountDeletionResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := u.getUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.UserRepository.SoftDeleteById(ctxWithTimeout, user.Id)
+	if err != nil {
+		fmt.Println("Error while deleting user: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	purgeAt := time.Now().Add(accountDeletionGracePeriod(u.Viper))
+	return &models.AccountDeletionResponse{PurgeAt: helpers.FormatTime(purgeAt)}, nil
+}
+
+func (u *ProfileUseCase) getUser(ctx context.Context, userID int) (*entity.User, error) {
+	user, err := u.UserRepository.FindOneById(ctx, userID)
+	if err != nil {
+		fmt.Println("Error while getting user by id: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	if user == nil {
+		return nil, &models.ErrorResponse{Code: 404, Message: "User not found", Status: "Not Found"}
+	}
+
+	return user, nil
+}
+
+func toRepositoryError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &models.ErrorResponse{Code: 408, Status: "Request Timeout", Message: "Request timeout. Please try again"}
+	}
+	return &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+}
+
+func accountDeletionGracePeriod(viper *viper.Viper) time.Duration {
+	return time.Duration(viper.GetInt("account.deletion.grace_period_days")) * 24 * time.Hour
+}
+
+func toUserResponse(user *entity.User) *models.UserResponse {
+	response := &models.UserResponse{
+		Id:           user.Id,
+		Name:         user.Name,
+		Email:        user.Email,
+		Role:         user.Role,
+		Status:       user.Status,
+		StatusReason: user.StatusReason,
+		CreatedAt:    helpers.FormatTime(user.CreatedAt),
+		UpdatedAt:    helpers.FormatTime(user.UpdatedAt),
+
+		PasswordChangeRequired: user.PasswordChangeRequired,
+		MfaEnabled:             user.MfaEnabled,
+	}
+	if user.Username != nil {
+		response.Username = *user.Username
+	}
+	if user.PhoneNumber != nil {
+		response.PhoneNumber = *user.PhoneNumber
+	}
+	if user.StatusExpiresAt != nil {
+		response.StatusExpiresAt = helpers.FormatTime(*user.StatusExpiresAt)
+	}
+	if user.LastLoginAt != nil {
+		response.LastLoginAt = helpers.FormatTime(*user.LastLoginAt)
+	}
+	return response
+}
@@ -5,28 +5,54 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
 	"golang-authentication/internal/entity"
 	"golang-authentication/internal/helpers"
 	"golang-authentication/internal/models"
 	"golang-authentication/internal/repository"
-	"golang.org/x/crypto/bcrypt"
+	"golang-authentication/internal/security"
+	"strings"
 	"time"
 )
 
 type SignUpUseCase struct {
-	UserRepository repository.UserRepositoryInterface
-	Validator      *validator.Validate
+	UserRepository       repository.UserRepositoryInterface
+	InvitationRepository repository.InvitationRepositoryInterface
+	Validator            *validator.Validate
+	Viper                *viper.Viper
+	EmailNormalizer      *helpers.EmailNormalizer
+	EmailDomainPolicy    *security.EmailDomainPolicy
+	PasswordUseCase      *PasswordUseCase
 }
 
-func NewSignupUseCase(userRepository repository.UserRepositoryInterface, validator *validator.Validate) *SignUpUseCase {
-	return &SignUpUseCase{UserRepository: userRepository, Validator: validator}
+func NewSignupUseCase(userRepository repository.UserRepositoryInterface, invitationRepository repository.InvitationRepositoryInterface, emailDomainPolicy *security.EmailDomainPolicy, validator *validator.Validate, viper *viper.Viper) *SignUpUseCase {
+	return &SignUpUseCase{
+		UserRepository:       userRepository,
+		InvitationRepository: invitationRepository,
+		Validator:            validator,
+		Viper:                viper,
+		EmailNormalizer:      helpers.NewEmailNormalizer(viper),
+		EmailDomainPolicy:    emailDomainPolicy,
+		PasswordUseCase:      NewPasswordUseCase(viper),
+	}
 }
 
+// CreateUser signs a user up according to signup.mode. In invite_only mode a valid invitation code is required,
+// in closed mode nobody can sign up. The role of the invitation, if any, is given to the user
 func (u *SignUpUseCase) CreateUser(ctx context.Context, userRequest *models.SignUpRequest) (*models.UserResponse, error) {
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := u.validateRequest(ctxWithTimeout, userRequest)
+	userRequest.Email = u.EmailNormalizer.Normalize(userRequest.Email)
+	userRequest.Username = normalizeUsername(userRequest.Username)
+
+	//the invitation is checked first so a closed signup doesn't tell which emails have an account
+	invitation, err := u.findInvitation(ctxWithTimeout, userRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.validateRequest(ctxWithTimeout, userRequest)
 	if err != nil {
 
 		return nil, err
@@ -37,34 +63,76 @@ func (u *SignUpUseCase) CreateUser(ctx context.Context, userRequest *models.Sign
 		return nil, err
 	}
 
+	if invitation != nil {
+		used, err := u.InvitationRepository.Use(ctxWithTimeout, invitation.Id, time.Now())
+		if err != nil {
+			fmt.Println("Error while using invitation: ", err)
+			return nil, toRepositoryError(err)
+		}
+		if !used {
+			return nil, signUpError(400, "Bad Request", InvitationInvalid, "Invitation is invalid or expired")
+		}
+	}
+
+	passwordChangedAt := time.Now()
 	user := &entity.User{
-		Name:     userRequest.Name,
-		Email:    userRequest.Email,
-		Password: hashedPassword,
+		Name:              userRequest.Name,
+		Email:             userRequest.Email,
+		EmailCanonical:    u.EmailNormalizer.Canonicalize(userRequest.Email),
+		Password:          hashedPassword,
+		PasswordChangedAt: &passwordChangedAt,
+	}
+	if userRequest.Username != "" {
+		user.Username = &userRequest.Username
+	}
+	if invitation != nil {
+		user.Role = invitation.Role
 	}
 	result, err := u.UserRepository.Save(ctxWithTimeout, user)
 
-	if errors.Is(err, context.DeadlineExceeded) {
-		return nil, &models.ErrorResponse{Code: 408, Message: "Request timeout. Please try again", Status: "Request Timeout"}
+	if err != nil {
+		fmt.Println("Error while saving user: ", err)
+		if invitation != nil {
+			if err := u.InvitationRepository.Release(ctxWithTimeout, invitation.Id); err != nil {
+				fmt.Println("Error while releasing invitation: ", err)
+			}
+		}
+		return nil, toRepositoryError(err)
 	}
-	return &models.UserResponse{
-		Id:        result.Id,
-		Name:      result.Name,
-		Email:     result.Email,
-		CreatedAt: result.CreatedAt,
-		UpdatedAt: result.UpdatedAt,
-	}, nil
+	return toUserResponse(result), nil
 }
 
-func (u *SignUpUseCase) HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		fmt.Println("Error while hashing password :", err)
-		return "", &models.ErrorResponse{Code: 500, Message: "Something wrong", Status: "Internal Server Error"}
+// findInvitation returns the invitation of the request, or nil when none is given and signup is open
+func (u *SignUpUseCase) findInvitation(ctx context.Context, userRequest *models.SignUpRequest) (*entity.Invitation, error) {
+	mode := signUpMode(u.Viper)
+	if mode == SignUpModeClosed {
+		return nil, signUpError(403, "Forbidden", SignUpClosed, "Sign up is closed")
 	}
 
-	return string(hashedPassword), nil
+	code := strings.TrimSpace(userRequest.InvitationCode)
+	if code == "" {
+		if mode == SignUpModeInviteOnly {
+			return nil, signUpError(403, "Forbidden", InvitationRequired, "An invitation is required to sign up")
+		}
+		return nil, nil
+	}
 
+	invitation, err := u.InvitationRepository.FindOneByCodeHash(ctx, helpers.HashToken(code))
+	if err != nil {
+		fmt.Println("Error while getting invitation: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if invitation == nil || !invitation.IsUsable(time.Now()) {
+		return nil, signUpError(400, "Bad Request", InvitationInvalid, "Invitation is invalid or expired")
+	}
+	if invitation.Email != nil && u.EmailNormalizer.Canonicalize(*invitation.Email) != u.EmailNormalizer.Canonicalize(userRequest.Email) {
+		return nil, signUpError(400, "Bad Request", InvitationInvalid, "Invitation was sent to another email")
+	}
+	return invitation, nil
+}
+
+func (u *SignUpUseCase) HashPassword(password string) (string, error) {
+	return u.PasswordUseCase.HashPassword(password)
 }
 
 func (u *SignUpUseCase) validateRequest(ctx context.Context, userRequest *models.SignUpRequest) error {
@@ -85,8 +153,18 @@ func (u *SignUpUseCase) validateRequest(ctx context.Context, userRequest *models
 
 	}
 
-	//if email is already taken
-	userExist, err := u.UserRepository.FindOneByEmail(ctx, userRequest.Email)
+	//email.domains decides which domains can sign up at all
+	if violation := u.EmailDomainPolicy.Check(userRequest.Email); violation != nil {
+		return signUpError(400, "Bad Request", violation.Code, violation.Message)
+	}
+
+	err = u.PasswordUseCase.ValidateNewPassword(userRequest.Password, userRequest.Name, userRequest.Email, userRequest.Username)
+	if err != nil {
+		return err
+	}
+
+	//if email is already taken, including by an account that is waiting to be purged
+	userExist, err := u.UserRepository.FindOneByEmailWithDeleted(ctx, u.EmailNormalizer.Canonicalize(userRequest.Email))
 	if err != nil {
 		fmt.Println("Something error while getting user by email: ", err)
 		if errors.Is(err, context.DeadlineExceeded) {
@@ -99,5 +177,18 @@ func (u *SignUpUseCase) validateRequest(ctx context.Context, userRequest *models
 	if userExist != nil {
 		return &models.ErrorResponse{Message: "Email already exists", Code: 400, Status: "Bad Request"}
 	}
-	return nil
+
+	if userRequest.Username == "" {
+		if u.Viper.GetBool("username.required") {
+			return &models.ErrorResponse{Message: "Username must be required", Code: 400, Status: "Bad Request"}
+		}
+		return nil
+	}
+
+	return validateUsername(ctx, u.Viper, u.UserRepository, userRequest.Username, 0)
+}
+
+// signUpError is a signup refusal carrying its machine-readable code in errors
+func signUpError(code int, status string, errorCode string, message string) *models.ErrorResponse {
+	return &models.ErrorResponse{Code: code, Status: status, Message: message, Errors: []models.ErrorDetail{{Code: errorCode, Message: message}}}
 }
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"time"
+)
+
+type TrustedDeviceUseCase struct {
+	AuthUseCase             *AuthUseCase
+	TrustedDeviceRepository repository.TrustedDeviceRepositoryInterface
+}
+
+func NewTrustedDeviceUseCase(authUseCase *AuthUseCase, trustedDeviceRepository repository.TrustedDeviceRepositoryInterface) *TrustedDeviceUseCase {
+	return &TrustedDeviceUseCase{
+		AuthUseCase:             authUseCase,
+		TrustedDeviceRepository: trustedDeviceRepository,
+	}
+}
+
+// GetDevices returns the devices where the user can skip the second factor. currentToken is the trusted device
+// cookie of the request, it marks the device making it
+func (u *TrustedDeviceUseCase) GetDevices(ctx context.Context, userID int, currentToken string) ([]models.TrustedDeviceResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	devices, err := u.TrustedDeviceRepository.FindAllActiveByUserId(ctxWithTimeout, userID, time.Now())
+	if err != nil {
+		fmt.Println("Error while getting trusted devices: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	current, err := u.AuthUseCase.FindTrustedDevice(ctxWithTimeout, currentToken)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.TrustedDeviceResponse, 0, len(devices))
+	for i := range devices {
+		response := toTrustedDeviceResponse(&devices[i])
+		response.Current = current != nil && current.Id == devices[i].Id
+		responses = append(responses, *response)
+	}
+	return responses, nil
+}
+
+// RevokeDevice makes the device ask for the second factor again
+func (u *TrustedDeviceUseCase) RevokeDevice(ctx context.Context, userID int, deviceID int) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	deleted, err := u.TrustedDeviceRepository.Delete(ctxWithTimeout, userID, deviceID)
+	if err != nil {
+		fmt.Println("Error while deleting trusted device: ", err)
+		return toRepositoryError(err)
+	}
+	if !deleted {
+		return &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Trusted device not found"}
+	}
+	return nil
+}
+
+// RevokeAllDevices makes every device of the user ask for the second factor again
+func (u *TrustedDeviceUseCase) RevokeAllDevices(ctx context.Context, userID int) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.TrustedDeviceRepository.DeleteAllByUserId(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while deleting trusted devices: ", err)
+		return toRepositoryError(err)
+	}
+	return nil
+}
+
+// PurgeExpiredDevices deletes the devices no longer trusted
+func (u *TrustedDeviceUseCase) PurgeExpiredDevices(ctx context.Context) (int64, error) {
+	return u.TrustedDeviceRepository.DeleteExpiredBefore(ctx, time.Now())
+}
+
+func toTrustedDeviceResponse(device *entity.TrustedDevice) *models.TrustedDeviceResponse {
+	response := &models.TrustedDeviceResponse{
+		Id:        device.Id,
+		UserAgent: device.UserAgent,
+		IpAddress: device.IpAddress,
+		CreatedAt: helpers.FormatTime(device.CreatedAt),
+		ExpiresAt: helpers.FormatTime(device.ExpiresAt),
+	}
+	if device.LastUsedAt != nil {
+		response.LastUsedAt = helpers.FormatTime(*device.LastUsedAt)
+	}
+	return response
+}
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"io"
+	"os"
+	"strings"
+)
+
+// errInvalidImportRow wraps a row that can't be parsed. The row is reported and the import goes on
+var errInvalidImportRow = errors.New("invalid row")
+
+type userImportReader interface {
+	// Next returns io.EOF after the last row
+	Next() (*models.UserImportRow, error)
+	Close() error
+}
+
+func openUserImportReader(path string, format string) (userImportReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case entity.UserImportFormatCSV:
+		reader, err := newCSVUserImportReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return reader, nil
+	case entity.UserImportFormatJSONL:
+		return newJSONLUserImportReader(file), nil
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// csvUserImportReader reads a CSV file whose header names the columns name, username, email and password_hash.
+// Other columns are ignored
+type csvUserImportReader struct {
+	file    *os.File
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVUserImportReader(file *os.File) (*csvUserImportReader, error) {
+	reader := csv.NewReader(bufio.NewReader(file))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		//a byte order mark sticks to the first column
+		column = strings.TrimPrefix(column, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range []string{"name", "email", "password_hash"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header is missing the %s column", required)
+		}
+	}
+
+	return &csvUserImportReader{file: file, reader: reader, columns: columns}, nil
+}
+
+func (r *csvUserImportReader) Next() (*models.UserImportRow, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			return nil, fmt.Errorf("%w: %v", errInvalidImportRow, parseError.Err)
+		}
+		return nil, err
+	}
+
+	return &models.UserImportRow{
+		Name:         r.column(record, "name"),
+		Username:     r.column(record, "username"),
+		Email:        r.column(record, "email"),
+		PasswordHash: r.column(record, "password_hash"),
+	}, nil
+}
+
+func (r *csvUserImportReader) column(record []string, name string) string {
+	index, ok := r.columns[name]
+	if !ok || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
+
+func (r *csvUserImportReader) Close() error {
+	return r.file.Close()
+}
+
+// jsonlUserImportRecord is a line of a JSONL file, either with the fields of models.UserImportRow or as exported
+// by Auth0, which names the hash passwordHash, or custom_password_hash in its import format
+type jsonlUserImportRecord struct {
+	Name               string `json:"name"`
+	GivenName          string `json:"given_name"`
+	FamilyName         string `json:"family_name"`
+	Nickname           string `json:"nickname"`
+	Username           string `json:"username"`
+	Email              string `json:"email"`
+	PasswordHash       string `json:"password_hash"`
+	Auth0PasswordHash  string `json:"passwordHash"`
+	CustomPasswordHash struct {
+		Hash struct {
+			Value string `json:"value"`
+		} `json:"hash"`
+	} `json:"custom_password_hash"`
+}
+
+// toRow falls back to the Auth0 fields, and to the email for the name since Auth0 users often have none
+func (r *jsonlUserImportRecord) toRow() *models.UserImportRow {
+	return &models.UserImportRow{
+		Name:         firstNonBlank(r.Name, strings.TrimSpace(r.GivenName+" "+r.FamilyName), r.Nickname, r.Email),
+		Username:     r.Username,
+		Email:        r.Email,
+		PasswordHash: firstNonBlank(r.PasswordHash, r.Auth0PasswordHash, r.CustomPasswordHash.Hash.Value),
+	}
+}
+
+func firstNonBlank(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// jsonlUserImportReader reads one JSON object per line. Blank lines are skipped
+type jsonlUserImportReader struct {
+	file    *os.File
+	scanner *bufio.Scanner
+}
+
+func newJSONLUserImportReader(file *os.File) *jsonlUserImportReader {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &jsonlUserImportReader{file: file, scanner: scanner}
+}
+
+func (r *jsonlUserImportReader) Next() (*models.UserImportRow, error) {
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		record := new(jsonlUserImportRecord)
+		err := json.Unmarshal(line, record)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidImportRow, err)
+		}
+		return record.toRow(), nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (r *jsonlUserImportReader) Close() error {
+	return r.file.Close()
+}
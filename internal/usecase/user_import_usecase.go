@@ -0,0 +1,394 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"golang-authentication/internal/security"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// userImportLease is how long a worker owns a job without reporting progress. A job whose worker died is picked up
+// again once its lease is over
+const userImportLease = time.Minute
+
+// UserImportUseCase imports users with their existing password hashes. Each import is a job that processes the
+// uploaded file row by row and records its progress, so an interrupted import resumes where it stopped
+type UserImportUseCase struct {
+	UserRepository       repository.UserRepositoryInterface
+	UserImportRepository repository.UserImportRepositoryInterface
+	Validator            *validator.Validate
+	Viper                *viper.Viper
+	EmailNormalizer      *helpers.EmailNormalizer
+	PasswordUseCase      *PasswordUseCase
+}
+
+func NewUserImportUseCase(userRepository repository.UserRepositoryInterface, userImportRepository repository.UserImportRepositoryInterface, validator *validator.Validate, viper *viper.Viper) *UserImportUseCase {
+	return &UserImportUseCase{
+		UserRepository:       userRepository,
+		UserImportRepository: userImportRepository,
+		Validator:            validator,
+		Viper:                viper,
+		EmailNormalizer:      helpers.NewEmailNormalizer(viper),
+		PasswordUseCase:      NewPasswordUseCase(viper),
+	}
+}
+
+// CreateImport stores the file in import.directory and queues a job for it. createdBy is the admin, or nil
+func (u *UserImportUseCase) CreateImport(ctx context.Context, format string, source io.Reader, createdBy *int) (*models.UserImportJobResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != entity.UserImportFormatCSV && format != entity.UserImportFormatJSONL {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Format must be csv or jsonl"}
+	}
+
+	filePath, err := u.storeImportFile(format, source)
+	if err != nil {
+		fmt.Println("Error while storing import file: ", err)
+		return nil, &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	job, err := u.UserImportRepository.SaveJob(ctxWithTimeout, &entity.UserImportJob{
+		Format:    format,
+		FilePath:  filePath,
+		Status:    entity.UserImportStatusPending,
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		fmt.Println("Error while saving import job: ", err)
+		os.Remove(filePath)
+		return nil, toRepositoryError(err)
+	}
+
+	return toUserImportJobResponse(job, nil), nil
+}
+
+func (u *UserImportUseCase) storeImportFile(format string, source io.Reader) (string, error) {
+	directory := helpers.ResolveConfigPath(u.Viper, u.Viper.GetString("import.directory"))
+	err := os.MkdirAll(directory, 0o700)
+	if err != nil {
+		return "", err
+	}
+
+	name, err := helpers.GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	filePath := filepath.Join(directory, name+"."+format)
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, source)
+	if err != nil {
+		os.Remove(filePath)
+		return "", err
+	}
+	return filePath, nil
+}
+
+// GetImport returns the job with one page of its row errors, and the total number of row errors
+func (u *UserImportUseCase) GetImport(ctx context.Context, jobID int, page models.PageRequest) (*models.UserImportJobResponse, int64, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	job, err := u.UserImportRepository.FindJobById(ctxWithTimeout, jobID)
+	if err != nil {
+		fmt.Println("Error while getting import job: ", err)
+		return nil, 0, toRepositoryError(err)
+	}
+	if job == nil {
+		return nil, 0, &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Import not found"}
+	}
+
+	page.Normalize()
+	importErrors, total, err := u.UserImportRepository.FindErrorsByJobId(ctxWithTimeout, job.Id, page.Offset(), page.Size)
+	if err != nil {
+		fmt.Println("Error while getting import errors: ", err)
+		return nil, 0, toRepositoryError(err)
+	}
+
+	return toUserImportJobResponse(job, importErrors), total, nil
+}
+
+// RunImport processes the job right away instead of waiting for the scheduler
+func (u *UserImportUseCase) RunImport(ctx context.Context, jobID int) (*models.UserImportJobResponse, error) {
+	claimed, err := u.UserImportRepository.ClaimJob(ctx, jobID, time.Now().Add(userImportLease))
+	if err != nil {
+		fmt.Println("Error while claiming import job: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	job, err := u.UserImportRepository.FindJobById(ctx, jobID)
+	if err != nil {
+		fmt.Println("Error while getting import job: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if job == nil {
+		return nil, &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Import not found"}
+	}
+
+	if !claimed {
+		if job.IsFinished() {
+			return toUserImportJobResponse(job, nil), nil
+		}
+		return nil, &models.ErrorResponse{Code: 409, Status: "Conflict", Message: "Import is already running"}
+	}
+
+	err = u.processJob(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	return toUserImportJobResponse(job, nil), nil
+}
+
+// ProcessPendingImports runs the queued and interrupted jobs one after another and returns how many were processed
+func (u *UserImportUseCase) ProcessPendingImports(ctx context.Context) (int, error) {
+	processed := 0
+	for ctx.Err() == nil {
+		job, err := u.UserImportRepository.ClaimNextJob(ctx, time.Now().Add(userImportLease))
+		if err != nil {
+			return processed, err
+		}
+		if job == nil {
+			return processed, nil
+		}
+
+		err = u.processJob(ctx, job)
+		if err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, ctx.Err()
+}
+
+// processJob imports the rows after job.ProcessedRows and saves the progress after every row. A row that can't be
+// imported is reported and skipped, but a database error stops the job so the row is tried again when it is resumed
+func (u *UserImportUseCase) processJob(ctx context.Context, job *entity.UserImportJob) error {
+	reader, err := openUserImportReader(job.FilePath, job.Format)
+	if err != nil {
+		return u.failJob(ctx, job, err)
+	}
+	defer reader.Close()
+
+	firstRow := job.ProcessedRows + 1
+	rowNumber := 0
+	for {
+		//the lease runs out and the job is resumed later
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, errInvalidImportRow) {
+			return u.failJob(ctx, job, err)
+		}
+
+		rowNumber++
+		if rowNumber <= job.ProcessedRows {
+			continue
+		}
+
+		if err == nil {
+			err = u.importRow(ctx, row, rowNumber == firstRow)
+			if err != nil && !isImportRowError(err) {
+				fmt.Println("Error while importing row: ", err)
+				return err
+			}
+		}
+		if err != nil {
+			job.FailedRows++
+			err = u.saveRowError(ctx, job, rowNumber, row, err)
+			if err != nil {
+				return err
+			}
+		} else {
+			job.ImportedRows++
+		}
+
+		job.ProcessedRows = rowNumber
+		leaseExpiresAt := time.Now().Add(userImportLease)
+		job.LeaseExpiresAt = &leaseExpiresAt
+		err = u.UserImportRepository.UpdateJobProgress(ctx, job)
+		if err != nil {
+			fmt.Println("Error while saving import progress: ", err)
+			return err
+		}
+	}
+
+	finishedAt := time.Now()
+	job.Status = entity.UserImportStatusCompleted
+	job.FinishedAt = &finishedAt
+	job.LeaseExpiresAt = nil
+	err = u.UserImportRepository.UpdateJobProgress(ctx, job)
+	if err != nil {
+		fmt.Println("Error while saving import progress: ", err)
+		return err
+	}
+
+	removeImportFile(job)
+	return nil
+}
+
+// failJob stops a job that can't go on, like a file that is gone or has no valid header
+func (u *UserImportUseCase) failJob(ctx context.Context, job *entity.UserImportJob, cause error) error {
+	fmt.Printf("Import %d failed: %v\n", job.Id, cause)
+
+	finishedAt := time.Now()
+	job.Status = entity.UserImportStatusFailed
+	job.ErrorMessage = helpers.Truncate(cause.Error(), 255)
+	job.FinishedAt = &finishedAt
+	job.LeaseExpiresAt = nil
+	err := u.UserImportRepository.UpdateJobProgress(ctx, job)
+	if err != nil {
+		fmt.Println("Error while saving import failure: ", err)
+		return err
+	}
+
+	removeImportFile(job)
+	return nil
+}
+
+// removeImportFile deletes the file of a finished job, it holds password hashes and is never read again. It is kept
+// until the job is saved as finished, otherwise the job would be resumed without its file
+func removeImportFile(job *entity.UserImportJob) {
+	err := os.Remove(job.FilePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Println("Error while removing import file: ", err)
+	}
+}
+
+func (u *UserImportUseCase) saveRowError(ctx context.Context, job *entity.UserImportJob, rowNumber int, row *models.UserImportRow, cause error) error {
+	message := cause.Error()
+	var errorResponse *models.ErrorResponse
+	if errors.As(cause, &errorResponse) {
+		message = errorResponse.Message
+	}
+
+	importError := &entity.UserImportError{JobId: job.Id, RowNumber: rowNumber, Message: helpers.Truncate(message, 255)}
+	if row != nil {
+		importError.Email = helpers.Truncate(row.Email, 255)
+	}
+
+	err := u.UserImportRepository.SaveError(ctx, importError)
+	if err != nil {
+		fmt.Println("Error while saving import error: ", err)
+		return err
+	}
+	return nil
+}
+
+// isImportRowError tells a row that is refused from a failure to reach the database
+func isImportRowError(err error) bool {
+	var errorResponse *models.ErrorResponse
+	return errors.As(err, &errorResponse) && errorResponse.Code == 400
+}
+
+// importRow validates the row the way signup does, except for the password policy since only the hash is known.
+// The first row of a run may have been saved just before the job stopped, without its progress
+func (u *UserImportUseCase) importRow(ctx context.Context, row *models.UserImportRow, firstRow bool) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row.Name = strings.TrimSpace(row.Name)
+	row.Email = u.EmailNormalizer.Normalize(row.Email)
+	row.Username = normalizeUsername(row.Username)
+	row.PasswordHash = strings.TrimSpace(row.PasswordHash)
+
+	err := u.Validator.Struct(row)
+	if err != nil {
+		if e, ok := err.(validator.ValidationErrors); ok {
+			return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(e)}
+		}
+		return err
+	}
+
+	err = u.PasswordUseCase.CheckHash(row.PasswordHash)
+	if errors.Is(err, security.ErrUnknownHashFormat) {
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Password hash format is not supported"}
+	}
+	if err != nil {
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Password hash is invalid: " + err.Error()}
+	}
+
+	emailCanonical := u.EmailNormalizer.Canonicalize(row.Email)
+	userExist, err := u.UserRepository.FindOneByEmailWithDeleted(ctxWithTimeout, emailCanonical)
+	if err != nil {
+		fmt.Println("Something error while getting user by email: ", err)
+		return toRepositoryError(err)
+	}
+	if userExist != nil {
+		if firstRow && userExist.Email == row.Email && userExist.Password == row.PasswordHash {
+			return nil
+		}
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Email already exists"}
+	}
+
+	user := &entity.User{
+		Name:           row.Name,
+		Email:          row.Email,
+		EmailCanonical: emailCanonical,
+		Password:       row.PasswordHash,
+	}
+
+	if row.Username != "" {
+		err = validateUsername(ctxWithTimeout, u.Viper, u.UserRepository, row.Username, 0)
+		if err != nil {
+			return err
+		}
+		user.Username = &row.Username
+	} else if u.Viper.GetBool("username.required") {
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Username must be required"}
+	}
+
+	_, err = u.UserRepository.Save(ctxWithTimeout, user)
+	if err != nil {
+		fmt.Println("Error while saving imported user: ", err)
+		return toRepositoryError(err)
+	}
+	return nil
+}
+
+func toUserImportJobResponse(job *entity.UserImportJob, importErrors []*entity.UserImportError) *models.UserImportJobResponse {
+	response := &models.UserImportJobResponse{
+		Id:            job.Id,
+		Format:        job.Format,
+		Status:        job.Status,
+		ProcessedRows: job.ProcessedRows,
+		ImportedRows:  job.ImportedRows,
+		FailedRows:    job.FailedRows,
+		ErrorMessage:  job.ErrorMessage,
+		CreatedAt:     helpers.FormatTime(job.CreatedAt),
+		UpdatedAt:     helpers.FormatTime(job.UpdatedAt),
+	}
+	if job.FinishedAt != nil {
+		response.FinishedAt = helpers.FormatTime(*job.FinishedAt)
+	}
+	for _, importError := range importErrors {
+		response.Errors = append(response.Errors, models.UserImportErrorResponse{
+			Row:     importError.RowNumber,
+			Email:   importError.Email,
+			Message: importError.Message,
+		})
+	}
+	return response
+}
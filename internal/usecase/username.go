@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"regexp"
+	"strings"
+)
+
+// a username starts with a letter, ends with a letter or digit and never has two separators in a row
+var usernamePattern = regexp.MustCompile(`^[a-z](?:[a-z0-9]|[._][a-z0-9])*$`)
+
+// normalizeUsername lowercases the username, so uniqueness is case-insensitive
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// validateUsername checks the character rules, reserved words and uniqueness of a normalized username.
+// exceptUserID is the user that already owns the username, or 0
+func validateUsername(ctx context.Context, viper *viper.Viper, userRepository repository.UserRepositoryInterface, username string, exceptUserID int) error {
+	if !usernamePattern.MatchString(username) {
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: "Username must start with a letter and only contain letters, numbers, single dots or underscores",
+		}
+	}
+
+	for _, reserved := range viper.GetStringSlice("username.reserved") {
+		if username == strings.ToLower(reserved) {
+			return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Username is not available"}
+		}
+	}
+
+	userExist, err := userRepository.FindOneByUsernameWithDeleted(ctx, username)
+	if err != nil {
+		fmt.Println("Something error while getting user by username: ", err)
+		return toRepositoryError(err)
+	}
+
+	if userExist != nil && userExist.Id != exceptUserID {
+		return &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Username already exists"}
+	}
+
+	return nil
+}
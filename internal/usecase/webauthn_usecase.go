@@ -0,0 +1,617 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"golang-authentication/internal/security"
+	"strings"
+	"time"
+)
+
+// webAuthnTransports are the transport hints browsers report, anything else is dropped
+var webAuthnTransports = map[string]bool{"usb": true, "nfc": true, "ble": true, "smart-card": true, "hybrid": true, "internal": true}
+
+// WebAuthnUseCase registers passkeys and signs in with them, either on their own or as the second factor after a
+// password. A passwordless sign in requires user verification, so the passkey alone stands for both factors
+type WebAuthnUseCase struct {
+	AuthUseCase                  *AuthUseCase
+	MfaUseCase                   *MfaUseCase
+	UserRepository               repository.UserRepositoryInterface
+	WebAuthnCredentialRepository repository.WebAuthnCredentialRepositoryInterface
+	WebAuthnChallengeRepository  repository.WebAuthnChallengeRepositoryInterface
+	Validator                    *validator.Validate
+	Viper                        *viper.Viper
+	WebAuthn                     *security.WebAuthn
+}
+
+func NewWebAuthnUseCase(authUseCase *AuthUseCase, mfaUseCase *MfaUseCase, userRepository repository.UserRepositoryInterface, webAuthnCredentialRepository repository.WebAuthnCredentialRepositoryInterface, webAuthnChallengeRepository repository.WebAuthnChallengeRepositoryInterface, validator *validator.Validate, viper *viper.Viper) *WebAuthnUseCase {
+	return &WebAuthnUseCase{
+		AuthUseCase:                  authUseCase,
+		MfaUseCase:                   mfaUseCase,
+		UserRepository:               userRepository,
+		WebAuthnCredentialRepository: webAuthnCredentialRepository,
+		WebAuthnChallengeRepository:  webAuthnChallengeRepository,
+		Validator:                    validator,
+		Viper:                        viper,
+		WebAuthn:                     security.NewWebAuthn(viper.GetString("webauthn.rp_id"), viper.GetStringSlice("webauthn.origins")),
+	}
+}
+
+// Timeout is how long the browser and the challenge of a ceremony stay valid
+func (u *WebAuthnUseCase) Timeout() time.Duration {
+	return time.Duration(u.Viper.GetInt("webauthn.timeout_seconds")) * time.Second
+}
+
+// BeginRegistration returns the options to create a passkey for the user
+func (u *WebAuthnUseCase) BeginRegistration(ctx context.Context, userID int) (*models.WebAuthnCreationOptions, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.checkEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.MfaUseCase.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := u.WebAuthnCredentialRepository.FindAllByUserId(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting passkeys: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	challenge, err := u.newChallenge(ctxWithTimeout, &userID, entity.WebAuthnChallengePurposeRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := make([]models.WebAuthnCredentialParameter, 0, len(security.WebAuthnAlgorithms))
+	for _, algorithm := range security.WebAuthnAlgorithms {
+		parameters = append(parameters, models.WebAuthnCredentialParameter{Type: "public-key", Alg: algorithm})
+	}
+
+	displayName := user.Name
+	if displayName == "" {
+		displayName = user.Email
+	}
+
+	return &models.WebAuthnCreationOptions{
+		Challenge:          challenge,
+		Rp:                 models.WebAuthnRelyingParty{Id: u.WebAuthn.RPID, Name: u.Viper.GetString("webauthn.rp_name")},
+		User:               models.WebAuthnUser{Id: webAuthnUserHandle(userID), Name: user.Email, DisplayName: displayName},
+		PubKeyCredParams:   parameters,
+		Timeout:            int(u.Timeout().Milliseconds()),
+		ExcludeCredentials: toWebAuthnCredentialDescriptors(credentials),
+		AuthenticatorSelection: models.WebAuthnAuthenticatorSelectionCriteria{
+			ResidentKey:      "preferred",
+			UserVerification: u.userVerification(),
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// FinishRegistration stores the passkey created with the options of BeginRegistration. The first passkey turns
+// two-factor authentication on, and then comes with recovery codes like an authenticator app does
+func (u *WebAuthnUseCase) FinishRegistration(ctx context.Context, userID int, request *models.WebAuthnRegistrationRequest) (*models.WebAuthnRegistrationResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.checkEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.Validator.Struct(request)
+	if err != nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+
+	clientDataJSON, err1 := decodeBase64URL(request.Response.ClientDataJSON)
+	attestationObject, err2 := decodeBase64URL(request.Response.AttestationObject)
+	if err1 != nil || err2 != nil {
+		return nil, invalidPasskey
+	}
+
+	challenge, err := u.findChallenge(ctxWithTimeout, clientDataJSON, entity.WebAuthnChallengePurposeRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserId == nil || *challenge.UserId != userID {
+		return nil, invalidChallenge
+	}
+
+	clientData, _ := security.ParseClientData(clientDataJSON)
+	authenticatorData, err := u.WebAuthn.VerifyRegistration(clientDataJSON, attestationObject, clientData.Challenge, u.userVerification() == "required")
+	if err != nil {
+		fmt.Println("Error while verifying passkey registration: ", err)
+		return nil, invalidPasskey
+	}
+
+	used, err := u.WebAuthnChallengeRepository.MarkUsed(ctxWithTimeout, challenge.Id, time.Now())
+	if err != nil {
+		fmt.Println("Error while using passkey challenge: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if !used {
+		return nil, invalidChallenge
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(authenticatorData.CredentialID)
+	existing, err := u.WebAuthnCredentialRepository.FindOneByCredentialIdHash(ctxWithTimeout, helpers.HashToken(credentialID))
+	if err != nil {
+		fmt.Println("Error while getting passkey: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if existing != nil {
+		return nil, &models.ErrorResponse{Code: 409, Status: "Conflict", Message: "Passkey is already registered"}
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+	credential := &entity.WebAuthnCredential{
+		UserId:           userID,
+		CredentialId:     credentialID,
+		CredentialIdHash: helpers.HashToken(credentialID),
+		PublicKey:        authenticatorData.PublicKey,
+		SignCount:        int64(authenticatorData.SignCount),
+		Transports:       strings.Join(filterWebAuthnTransports(request.Response.Transports), ","),
+		Name:             name,
+	}
+	err = u.WebAuthnCredentialRepository.Save(ctxWithTimeout, credential)
+	if err != nil {
+		fmt.Println("Error while saving passkey: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	response := &models.WebAuthnRegistrationResponse{Credential: toWebAuthnCredentialResponse(credential)}
+
+	user, err := u.MfaUseCase.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MfaEnabled {
+		err = u.MfaUseCase.updateMfaEnabled(ctxWithTimeout, user, true)
+		if err != nil {
+			return nil, err
+		}
+		recoveryCodes, err := u.MfaUseCase.replaceRecoveryCodes(ctxWithTimeout, userID)
+		if err != nil {
+			return nil, err
+		}
+		response.RecoveryCodes = recoveryCodes.RecoveryCodes
+	}
+
+	return response, nil
+}
+
+func (u *WebAuthnUseCase) GetCredentials(ctx context.Context, userID int) ([]models.WebAuthnCredentialResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	credentials, err := u.WebAuthnCredentialRepository.FindAllByUserId(ctxWithTimeout, userID)
+	if err != nil {
+		fmt.Println("Error while getting passkeys: ", err)
+		return nil, toRepositoryError(err)
+	}
+
+	responses := make([]models.WebAuthnCredentialResponse, 0, len(credentials))
+	for i := range credentials {
+		responses = append(responses, *toWebAuthnCredentialResponse(&credentials[i]))
+	}
+	return responses, nil
+}
+
+// DeleteCredential removes a passkey, and turns MFA off when no other second factor is left
+func (u *WebAuthnUseCase) DeleteCredential(ctx context.Context, userID int, credentialID int) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	deleted, err := u.WebAuthnCredentialRepository.Delete(ctxWithTimeout, userID, credentialID)
+	if err != nil {
+		fmt.Println("Error while deleting passkey: ", err)
+		return toRepositoryError(err)
+	}
+	if !deleted {
+		return &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Passkey not found"}
+	}
+
+	user, err := u.MfaUseCase.findUser(ctxWithTimeout, userID)
+	if err != nil {
+		return err
+	}
+	return u.MfaUseCase.refreshMfaEnabled(ctxWithTimeout, user)
+}
+
+// BeginSignIn returns the options to sign in with a passkey. With an MFA token only the passkeys of that user are
+// allowed, without one the browser offers the discoverable passkeys it holds for the site
+func (u *WebAuthnUseCase) BeginSignIn(ctx context.Context, request *models.WebAuthnSignInOptionsRequest) (*models.WebAuthnRequestOptions, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.checkEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	if request.MfaToken != "" {
+		userID, _, err := u.AuthUseCase.VerifyMfaChallengeToken(request.MfaToken)
+		if err != nil {
+			return nil, err
+		}
+		return u.requestOptions(ctxWithTimeout, &userID, entity.WebAuthnChallengePurposeAuthentication, u.userVerification())
+	}
+	return u.requestOptions(ctxWithTimeout, nil, entity.WebAuthnChallengePurposeAuthentication, "required")
+}
+
+// SignIn finishes the ceremony of BeginSignIn and signs the user in like AuthUseCase.SignIn, without asking for
+// another factor
+func (u *WebAuthnUseCase) SignIn(ctx context.Context, request *models.WebAuthnSignInRequest) (*models.SignInResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.checkEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.Validator.Struct(request)
+	if err != nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+
+	assertion, err := u.readAssertion(ctxWithTimeout, &request.PublicKeyCredentialRequest, entity.WebAuthnChallengePurposeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+
+	//a second factor challenge only takes a passkey of the user who passed the first one
+	secondFactor := assertion.Challenge.UserId != nil
+	//a passkey verifying the user is two factors on its own
+	methods := []string{AmrHardwareKey, AmrMfa}
+	if secondFactor {
+		userID, firstFactor, err := u.AuthUseCase.VerifyMfaChallengeToken(request.MfaToken)
+		if err != nil {
+			return nil, err
+		}
+		methods = WithSecondFactor(firstFactor, AmrHardwareKey)
+		if userID != *assertion.Challenge.UserId || userID != assertion.Credential.UserId {
+			return nil, invalidPasskey
+		}
+	}
+
+	user, err := u.UserRepository.FindOneById(ctxWithTimeout, assertion.Credential.UserId)
+	if err != nil {
+		fmt.Println("Error while getting user: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if user == nil {
+		return nil, invalidPasskey
+	}
+
+	requireUserVerification := !secondFactor || u.userVerification() == "required"
+	err = u.useAssertion(ctxWithTimeout, assertion, user, request.ClientInfo, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsLocked(time.Now()) {
+		u.AuthUseCase.RecordLoginAttempt(ctxWithTimeout, user.Email, request.ClientInfo, user, entity.LoginOutcomeLocked)
+		return nil, u.AuthUseCase.lockedError()
+	}
+
+	err = u.AuthUseCase.resetFailedSignIns(ctxWithTimeout, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.AuthUseCase.CompleteSecondFactor(ctxWithTimeout, user, user.Email, request.ClientInfo, methods, secondFactor && request.RememberDevice)
+}
+
+// BeginReauthentication returns the options to authenticate the session again with a passkey of its user, as an
+// alternative to the password and code of MfaUseCase.Reauthenticate
+func (u *WebAuthnUseCase) BeginReauthentication(ctx context.Context, claims *AccessClaims) (*models.WebAuthnRequestOptions, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.checkEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = u.AuthUseCase.findActiveSession(ctxWithTimeout, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	//the passkey replaces both factors, so it has to verify the user
+	return u.requestOptions(ctxWithTimeout, &claims.UserId, entity.WebAuthnChallengePurposeReauthentication, "required")
+}
+
+// Reauthenticate finishes the ceremony of BeginReauthentication. The session then counts as authenticated now with
+// two factors, and the new access token says so
+func (u *WebAuthnUseCase) Reauthenticate(ctx context.Context, claims *AccessClaims, request *models.WebAuthnReauthenticateRequest) (*models.GetTokenResponse, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.checkEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.Validator.Struct(request)
+	if err != nil {
+		return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: helpers.GetFirstValidationErrorsAndConvert(err)}
+	}
+
+	session, err := u.AuthUseCase.findActiveSession(ctxWithTimeout, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	assertion, err := u.readAssertion(ctxWithTimeout, &request.PublicKeyCredentialRequest, entity.WebAuthnChallengePurposeReauthentication)
+	if err != nil {
+		return nil, err
+	}
+	//the challenge and the passkey both have to be of the user of the session
+	if assertion.Challenge.UserId == nil || *assertion.Challenge.UserId != claims.UserId || assertion.Credential.UserId != claims.UserId {
+		return nil, invalidPasskey
+	}
+
+	user, err := u.MfaUseCase.findUser(ctxWithTimeout, claims.UserId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if user.IsLocked(now) {
+		return nil, u.AuthUseCase.lockedError()
+	}
+
+	err = u.useAssertion(ctxWithTimeout, assertion, user, request.ClientInfo, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.AuthUseCase.resetFailedSignIns(ctxWithTimeout, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.AuthUseCase.reauthenticateSession(ctxWithTimeout, session, []string{AmrHardwareKey, AmrMfa}, now)
+}
+
+// PurgeExpiredChallenges deletes the challenges of abandoned ceremonies
+func (u *WebAuthnUseCase) PurgeExpiredChallenges(ctx context.Context) (int64, error) {
+	return u.WebAuthnChallengeRepository.DeleteExpiredBefore(ctx, time.Now())
+}
+
+var invalidChallenge = &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Passkey challenge is invalid or expired"}
+
+var invalidPasskey = &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Passkey is invalid"}
+
+// passkeyAssertion is a decoded assertion, together with its challenge and the passkey that signed it
+type passkeyAssertion struct {
+	Challenge         *entity.WebAuthnChallenge
+	Credential        *entity.WebAuthnCredential
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+}
+
+// requestOptions starts a ceremony of purpose. With a user, only the passkeys of that user are allowed
+func (u *WebAuthnUseCase) requestOptions(ctx context.Context, userID *int, purpose string, userVerification string) (*models.WebAuthnRequestOptions, error) {
+	allowCredentials := []models.WebAuthnCredentialDescriptor{}
+	if userID != nil {
+		credentials, err := u.WebAuthnCredentialRepository.FindAllByUserId(ctx, *userID)
+		if err != nil {
+			fmt.Println("Error while getting passkeys: ", err)
+			return nil, toRepositoryError(err)
+		}
+		if len(credentials) == 0 {
+			return nil, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "No passkey is registered"}
+		}
+		allowCredentials = toWebAuthnCredentialDescriptors(credentials)
+	}
+
+	challenge, err := u.newChallenge(ctx, userID, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WebAuthnRequestOptions{
+		Challenge:        challenge,
+		RpId:             u.WebAuthn.RPID,
+		Timeout:          int(u.Timeout().Milliseconds()),
+		AllowCredentials: allowCredentials,
+		UserVerification: userVerification,
+	}, nil
+}
+
+// readAssertion decodes the assertion, and finds its challenge of purpose and its passkey
+func (u *WebAuthnUseCase) readAssertion(ctx context.Context, request *models.PublicKeyCredentialRequest, purpose string) (*passkeyAssertion, error) {
+	clientDataJSON, err1 := decodeBase64URL(request.Response.ClientDataJSON)
+	authenticatorData, err2 := decodeBase64URL(request.Response.AuthenticatorData)
+	signature, err3 := decodeBase64URL(request.Response.Signature)
+	credentialID, err4 := decodeBase64URL(request.Id)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return nil, invalidPasskey
+	}
+
+	challenge, err := u.findChallenge(ctx, clientDataJSON, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := u.WebAuthnCredentialRepository.FindOneByCredentialIdHash(ctx, helpers.HashToken(base64.RawURLEncoding.EncodeToString(credentialID)))
+	if err != nil {
+		fmt.Println("Error while getting passkey: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if credential == nil {
+		return nil, invalidPasskey
+	}
+	if request.Response.UserHandle != "" && request.Response.UserHandle != webAuthnUserHandle(credential.UserId) {
+		return nil, invalidPasskey
+	}
+
+	return &passkeyAssertion{
+		Challenge:         challenge,
+		Credential:        credential,
+		ClientDataJSON:    clientDataJSON,
+		AuthenticatorData: authenticatorData,
+		Signature:         signature,
+	}, nil
+}
+
+// useAssertion verifies the signature of the passkey of user, then uses the challenge up and records the sign count.
+// A sign count going back is refused, the passkey may be cloned
+func (u *WebAuthnUseCase) useAssertion(ctx context.Context, assertion *passkeyAssertion, user *entity.User, clientInfo models.ClientInfo, requireUserVerification bool) error {
+	clientData, _ := security.ParseClientData(assertion.ClientDataJSON)
+	verified, err := u.WebAuthn.VerifyAssertion(assertion.ClientDataJSON, assertion.AuthenticatorData, assertion.Signature, assertion.Credential.PublicKey, clientData.Challenge, requireUserVerification)
+	if err != nil {
+		fmt.Println("Error while verifying passkey: ", err)
+		u.AuthUseCase.RecordLoginAttempt(ctx, user.Email, clientInfo, user, entity.LoginOutcomeInvalidPasskey)
+		return invalidPasskey
+	}
+
+	now := time.Now()
+	used, err := u.WebAuthnChallengeRepository.MarkUsed(ctx, assertion.Challenge.Id, now)
+	if err != nil {
+		fmt.Println("Error while using passkey challenge: ", err)
+		return toRepositoryError(err)
+	}
+	if !used {
+		return invalidChallenge
+	}
+
+	counted, err := u.WebAuthnCredentialRepository.UpdateSignCount(ctx, assertion.Credential.Id, int64(verified.SignCount), now)
+	if err != nil {
+		fmt.Println("Error while updating passkey sign count: ", err)
+		return toRepositoryError(err)
+	}
+	if !counted {
+		fmt.Println("Passkey sign count went back, it may be cloned: ", assertion.Credential.Id)
+		u.AuthUseCase.RecordLoginAttempt(ctx, user.Email, clientInfo, user, entity.LoginOutcomeInvalidPasskey)
+		return invalidPasskey
+	}
+	return nil
+}
+
+func (u *WebAuthnUseCase) checkEnabled() error {
+	if !u.Viper.GetBool("webauthn.enabled") {
+		return &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Passkeys are disabled"}
+	}
+	return nil
+}
+
+// userVerification is the webauthn.user_verification setting, required, preferred or discouraged
+func (u *WebAuthnUseCase) userVerification() string {
+	switch value := u.Viper.GetString("webauthn.user_verification"); value {
+	case "required", "discouraged":
+		return value
+	}
+	return "preferred"
+}
+
+// newChallenge stores the hash of a random challenge and returns the challenge. Hex is valid base64url, and the
+// browser gives it back unchanged in the client data
+func (u *WebAuthnUseCase) newChallenge(ctx context.Context, userID *int, purpose string) (string, error) {
+	challenge, err := helpers.GenerateRandomToken(32)
+	if err != nil {
+		fmt.Println("Error while generating passkey challenge: ", err)
+		return "", &models.ErrorResponse{Code: 500, Status: "Internal Server Error", Message: "Something wrong!"}
+	}
+
+	err = u.WebAuthnChallengeRepository.Save(ctx, &entity.WebAuthnChallenge{
+		UserId:        userID,
+		Purpose:       purpose,
+		ChallengeHash: helpers.HashToken(challenge),
+		ExpiresAt:     time.Now().Add(u.Timeout()),
+	})
+	if err != nil {
+		fmt.Println("Error while saving passkey challenge: ", err)
+		return "", toRepositoryError(err)
+	}
+	return challenge, nil
+}
+
+// findChallenge looks the challenge up through the client data, which the authenticator signed over
+func (u *WebAuthnUseCase) findChallenge(ctx context.Context, clientDataJSON []byte, purpose string) (*entity.WebAuthnChallenge, error) {
+	clientData, err := security.ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, invalidChallenge
+	}
+
+	challenge, err := u.WebAuthnChallengeRepository.FindOneByChallengeHash(ctx, helpers.HashToken(clientData.Challenge))
+	if err != nil {
+		fmt.Println("Error while getting passkey challenge: ", err)
+		return nil, toRepositoryError(err)
+	}
+	if challenge == nil || challenge.Purpose != purpose || !challenge.IsUsable(time.Now()) {
+		return nil, invalidChallenge
+	}
+	return challenge, nil
+}
+
+// webAuthnUserHandle is the user.id of the passkeys of a user, which authenticators return at sign in
+func webAuthnUserHandle(userID int) string {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return base64.RawURLEncoding.EncodeToString(handle)
+}
+
+// decodeBase64URL accepts base64url with or without padding, as browsers and libraries differ
+func decodeBase64URL(value string) ([]byte, error) {
+	if value == "" {
+		return nil, errors.New("empty value")
+	}
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+func filterWebAuthnTransports(transports []string) []string {
+	filtered := make([]string, 0, len(transports))
+	for _, transport := range transports {
+		if webAuthnTransports[transport] {
+			filtered = append(filtered, transport)
+		}
+	}
+	return filtered
+}
+
+func toWebAuthnCredentialDescriptors(credentials []entity.WebAuthnCredential) []models.WebAuthnCredentialDescriptor {
+	descriptors := make([]models.WebAuthnCredentialDescriptor, 0, len(credentials))
+	for i := range credentials {
+		descriptors = append(descriptors, models.WebAuthnCredentialDescriptor{
+			Type:       "public-key",
+			Id:         credentials[i].CredentialId,
+			Transports: credentials[i].TransportList(),
+		})
+	}
+	return descriptors
+}
+
+func toWebAuthnCredentialResponse(credential *entity.WebAuthnCredential) *models.WebAuthnCredentialResponse {
+	response := &models.WebAuthnCredentialResponse{
+		Id:         credential.Id,
+		Name:       credential.Name,
+		Transports: credential.TransportList(),
+		CreatedAt:  helpers.FormatTime(credential.CreatedAt),
+	}
+	if credential.LastUsedAt != nil {
+		response.LastUsedAt = helpers.FormatTime(*credential.LastUsedAt)
+	}
+	return response
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	Jobs []Job
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+func (s *Scheduler) Add(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.Jobs = append(s.Jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start runs every job once right away and then on its interval until ctx is done
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.Jobs {
+		if job.Interval <= 0 {
+			fmt.Printf("Job %s is disabled because its interval is not positive\n", job.Name)
+			continue
+		}
+		go s.run(ctx, job)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := job.Run(ctx)
+		if err != nil {
+			fmt.Printf("Error while running job %s: %v\n", job.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,13 +1,22 @@
 package main
 
-import "golang-authentication/internal/config"
+import (
+	"context"
+	"golang-authentication/internal/config"
+	"log"
+)
 
 func main() {
 	viperConfig := config.NewViper("./../")
+	err := config.CheckSecrets(viperConfig)
+	if err != nil {
+		log.Fatalf("Error while loading the secrets %v", err)
+	}
 	validator := config.NewValidator()
 	database := config.NewGorm(viperConfig)
 	app := config.NewApp(viperConfig, validator, database)
 	app.Setup()
+	app.StartWorkers(context.Background())
 	app.StartServer()
 
 }
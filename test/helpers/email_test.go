@@ -0,0 +1,33 @@
+package helpers
+
+import (
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/helpers"
+	"testing"
+)
+
+func TestEmailNormalizer(t *testing.T) {
+	viper := config.NewViper("./../../")
+	normalizer := helpers.NewEmailNormalizer(viper)
+
+	t.Run("Normalize should trim and only lowercase the domain", func(t *testing.T) {
+		require.Equal(t, "Danar@gmail.com", normalizer.Normalize("  Danar@Gmail.COM "))
+	})
+
+	t.Run("Canonicalize should lowercase the local part", func(t *testing.T) {
+		require.Equal(t, normalizer.Canonicalize("danar@example.com"), normalizer.Canonicalize("Danar@Example.com"))
+	})
+
+	t.Run("Canonicalize should apply gmail rules", func(t *testing.T) {
+		require.Equal(t, "danarcahyadi@gmail.com", normalizer.Canonicalize("Danar.Cahyadi+news@googlemail.com"))
+	})
+
+	t.Run("Canonicalize should only strip plus tag for outlook", func(t *testing.T) {
+		require.Equal(t, "danar.cahyadi@outlook.com", normalizer.Canonicalize("danar.cahyadi+news@outlook.com"))
+	})
+
+	t.Run("Canonicalize should leave other providers alone", func(t *testing.T) {
+		require.Equal(t, "danar.cahyadi+news@example.com", normalizer.Canonicalize("danar.cahyadi+news@example.com"))
+	})
+}
@@ -0,0 +1,30 @@
+package helpers
+
+import (
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/helpers"
+	"testing"
+)
+
+func TestPhoneNumber(t *testing.T) {
+	t.Run("Normalize should drop separators", func(t *testing.T) {
+		phoneNumber, ok := helpers.NormalizePhoneNumber(" +62 (812) 3456-7890 ")
+		require.True(t, ok)
+		require.Equal(t, "+6281234567890", phoneNumber)
+
+		phoneNumber, ok = helpers.NormalizePhoneNumber("0044 20 7946 0958")
+		require.True(t, ok)
+		require.Equal(t, "+442079460958", phoneNumber)
+	})
+
+	t.Run("Normalize should reject numbers without a country code", func(t *testing.T) {
+		for _, phoneNumber := range []string{"081234567890", "+0812345678", "+62", "+62abc4567890", "+1234567890123456"} {
+			_, ok := helpers.NormalizePhoneNumber(phoneNumber)
+			require.False(t, ok, phoneNumber)
+		}
+	})
+
+	t.Run("Mask should keep the last digits", func(t *testing.T) {
+		require.Equal(t, "+62*********90", helpers.MaskPhoneNumber("+6281234567890"))
+	})
+}
@@ -0,0 +1,25 @@
+package mail
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/mail"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	mailer := &mail.FileMailer{Directory: t.TempDir(), From: "no-reply@localhost"}
+
+	err := mailer.Send(context.Background(), &mail.Message{To: "Danar@gmail.com", Subject: "Hello", Body: "first"})
+	require.Nil(t, err)
+	err = mailer.Send(context.Background(), &mail.Message{To: "danar@gmail.com", Subject: "Hello", Body: "second"})
+	require.Nil(t, err)
+
+	content, err := os.ReadFile(mailer.Path("danar@gmail.com"))
+	require.Nil(t, err)
+	require.Equal(t, 2, strings.Count(string(content), "Subject: Hello"))
+	require.Contains(t, string(content), "first")
+	require.Contains(t, string(content), "second")
+}
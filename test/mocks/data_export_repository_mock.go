@@ -0,0 +1,65 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+	"time"
+)
+
+type DataExportRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewDataExportRepositoryMock() *DataExportRepositoryMock {
+	return &DataExportRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *DataExportRepositoryMock) Save(ctx context.Context, export *entity.DataExport) (*entity.DataExport, error) {
+	args := r.Mock.Called(export)
+	return args.Get(0).(*entity.DataExport), nil
+}
+
+func (r *DataExportRepositoryMock) FindOneById(ctx context.Context, id int) (*entity.DataExport, error) {
+	args := r.Mock.Called(id)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.DataExport), nil
+}
+
+func (r *DataExportRepositoryMock) FindUnfinishedByUserId(ctx context.Context, userID int) (*entity.DataExport, error) {
+	args := r.Mock.Called(userID)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.DataExport), nil
+}
+
+func (r *DataExportRepositoryMock) ClaimNext(ctx context.Context, leaseExpiresAt time.Time) (*entity.DataExport, error) {
+	args := r.Mock.Called()
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.DataExport), nil
+}
+
+func (r *DataExportRepositoryMock) Update(ctx context.Context, export *entity.DataExport) error {
+	args := r.Mock.Called(export)
+	return args.Error(0)
+}
+
+func (r *DataExportRepositoryMock) FindExpiredBefore(ctx context.Context, before time.Time, limit int) ([]*entity.DataExport, error) {
+	args := r.Mock.Called()
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).([]*entity.DataExport), nil
+}
+
+func (r *DataExportRepositoryMock) ExistsByFilePath(ctx context.Context, filePath string) (bool, error) {
+	args := r.Mock.Called(filePath)
+	return args.Bool(0), args.Error(1)
+}
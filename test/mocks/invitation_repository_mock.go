@@ -0,0 +1,51 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+	"time"
+)
+
+type InvitationRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewInvitationRepositoryMock() *InvitationRepositoryMock {
+	return &InvitationRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *InvitationRepositoryMock) Save(ctx context.Context, invitation *entity.Invitation) error {
+	args := r.Mock.Called(invitation)
+	return args.Error(0)
+}
+
+func (r *InvitationRepositoryMock) FindOneByCodeHash(ctx context.Context, codeHash string) (*entity.Invitation, error) {
+	args := r.Mock.Called(codeHash)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.Invitation), nil
+}
+
+func (r *InvitationRepositoryMock) FindAll(ctx context.Context, offset int, limit int) ([]*entity.Invitation, int64, error) {
+	args := r.Mock.Called(offset, limit)
+	return args.Get(0).([]*entity.Invitation), args.Get(1).(int64), nil
+}
+
+func (r *InvitationRepositoryMock) Use(ctx context.Context, id int, now time.Time) (bool, error) {
+	args := r.Mock.Called(id)
+	return args.Bool(0), nil
+}
+
+func (r *InvitationRepositoryMock) Release(ctx context.Context, id int) error {
+	args := r.Mock.Called(id)
+	return args.Error(0)
+}
+
+func (r *InvitationRepositoryMock) Revoke(ctx context.Context, id int, revokedAt time.Time) (bool, error) {
+	args := r.Mock.Called(id)
+	return args.Bool(0), nil
+}
@@ -0,0 +1,39 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/repository"
+)
+
+type LoginAttemptRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewLoginAttemptRepositoryMock() *LoginAttemptRepositoryMock {
+	return &LoginAttemptRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *LoginAttemptRepositoryMock) Save(ctx context.Context, attempt *entity.LoginAttempt) error {
+	args := r.Mock.Called(attempt)
+	return args.Error(0)
+}
+
+func (r *LoginAttemptRepositoryMock) FindAllByUserId(ctx context.Context, userID int, offset int, limit int) ([]*entity.LoginAttempt, int64, error) {
+	args := r.Mock.Called(userID, offset, limit)
+	if args.Get(0) == nil {
+		return nil, 0, nil
+	}
+	return args.Get(0).([]*entity.LoginAttempt), args.Get(1).(int64), nil
+}
+
+func (r *LoginAttemptRepositoryMock) Search(ctx context.Context, filter repository.LoginAttemptFilter, offset int, limit int) ([]*entity.LoginAttempt, int64, error) {
+	args := r.Mock.Called(filter, offset, limit)
+	if args.Get(0) == nil {
+		return nil, 0, nil
+	}
+	return args.Get(0).([]*entity.LoginAttempt), args.Get(1).(int64), nil
+}
@@ -0,0 +1,54 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+	"time"
+)
+
+type MagicLinkRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewMagicLinkRepositoryMock() *MagicLinkRepositoryMock {
+	return &MagicLinkRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *MagicLinkRepositoryMock) Save(ctx context.Context, magicLink *entity.MagicLink) error {
+	args := r.Mock.Called(magicLink)
+	return args.Error(0)
+}
+
+func (r *MagicLinkRepositoryMock) FindOneByTokenHash(ctx context.Context, tokenHash string) (*entity.MagicLink, error) {
+	args := r.Mock.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.MagicLink), nil
+}
+
+func (r *MagicLinkRepositoryMock) FindLatest(ctx context.Context, userID int) (*entity.MagicLink, error) {
+	args := r.Mock.Called(userID)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.MagicLink), nil
+}
+
+func (r *MagicLinkRepositoryMock) CountSince(ctx context.Context, userID int, since time.Time) (int64, error) {
+	args := r.Mock.Called(userID)
+	return args.Get(0).(int64), nil
+}
+
+func (r *MagicLinkRepositoryMock) MarkUsed(ctx context.Context, id int, usedAt time.Time) (bool, error) {
+	args := r.Mock.Called(id)
+	return args.Bool(0), nil
+}
+
+func (r *MagicLinkRepositoryMock) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := r.Mock.Called()
+	return args.Get(0).(int64), nil
+}
@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"context"
+	"golang-authentication/internal/mail"
+	"sync"
+)
+
+// MailerMock keeps the sent messages so tests can read the links and codes in them
+type MailerMock struct {
+	Messages []*mail.Message
+	mutex    sync.Mutex
+}
+
+func NewMailerMock() *MailerMock {
+	return &MailerMock{}
+}
+
+func (m *MailerMock) Send(ctx context.Context, message *mail.Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Messages = append(m.Messages, message)
+	return nil
+}
+
+func (m *MailerMock) Last() *mail.Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.Messages) == 0 {
+		return nil
+	}
+	return m.Messages[len(m.Messages)-1]
+}
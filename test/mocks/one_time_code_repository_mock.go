@@ -0,0 +1,56 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+	"time"
+)
+
+type OneTimeCodeRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewOneTimeCodeRepositoryMock() *OneTimeCodeRepositoryMock {
+	return &OneTimeCodeRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *OneTimeCodeRepositoryMock) Save(ctx context.Context, code *entity.OneTimeCode) error {
+	args := r.Mock.Called(code)
+	return args.Error(0)
+}
+
+func (r *OneTimeCodeRepositoryMock) FindLatest(ctx context.Context, userID int, purpose string) (*entity.OneTimeCode, error) {
+	args := r.Mock.Called(userID, purpose)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.OneTimeCode), nil
+}
+
+func (r *OneTimeCodeRepositoryMock) CountSince(ctx context.Context, userID int, purpose string, since time.Time) (int64, error) {
+	args := r.Mock.Called(userID, purpose)
+	return args.Get(0).(int64), nil
+}
+
+func (r *OneTimeCodeRepositoryMock) CountByDestinationSince(ctx context.Context, destination string, since time.Time) (int64, error) {
+	args := r.Mock.Called(destination)
+	return args.Get(0).(int64), nil
+}
+
+func (r *OneTimeCodeRepositoryMock) RegisterAttempt(ctx context.Context, id int, maxAttempts int) (bool, error) {
+	args := r.Mock.Called(id)
+	return args.Bool(0), nil
+}
+
+func (r *OneTimeCodeRepositoryMock) MarkUsed(ctx context.Context, id int, usedAt time.Time) (bool, error) {
+	args := r.Mock.Called(id)
+	return args.Bool(0), nil
+}
+
+func (r *OneTimeCodeRepositoryMock) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := r.Mock.Called()
+	return args.Get(0).(int64), nil
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+)
+
+type PasswordHistoryRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewPasswordHistoryRepositoryMock() *PasswordHistoryRepositoryMock {
+	return &PasswordHistoryRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *PasswordHistoryRepositoryMock) Save(ctx context.Context, history *entity.PasswordHistory) (*entity.PasswordHistory, error) {
+	args := r.Mock.Called(history)
+	return args.Get(0).(*entity.PasswordHistory), nil
+}
+
+func (r *PasswordHistoryRepositoryMock) FindLatestByUserId(ctx context.Context, userID int, limit int) ([]*entity.PasswordHistory, error) {
+	args := r.Mock.Called(userID, limit)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).([]*entity.PasswordHistory), nil
+}
+
+func (r *PasswordHistoryRepositoryMock) DeleteAllByUserIdExceptLatest(ctx context.Context, userID int, keep int) error {
+	args := r.Mock.Called(userID, keep)
+	return args.Error(0)
+}
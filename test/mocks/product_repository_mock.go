@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+)
+
+type ProductRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewProductRepositoryMock() *ProductRepositoryMock {
+	return &ProductRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *ProductRepositoryMock) FindAllByUserId(ctx context.Context, userID int) ([]*entity.Product, error) {
+	args := r.Mock.Called(userID)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).([]*entity.Product), nil
+}
@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type RecoveryCodeRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewRecoveryCodeRepositoryMock() *RecoveryCodeRepositoryMock {
+	return &RecoveryCodeRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *RecoveryCodeRepositoryMock) ReplaceAll(ctx context.Context, userID int, codeHashes []string) error {
+	args := r.Mock.Called(userID, codeHashes)
+	return args.Error(0)
+}
+
+func (r *RecoveryCodeRepositoryMock) Use(ctx context.Context, userID int, codeHash string, usedAt time.Time) (bool, error) {
+	args := r.Mock.Called(userID, codeHash)
+	return args.Bool(0), nil
+}
+
+func (r *RecoveryCodeRepositoryMock) CountUnused(ctx context.Context, userID int) (int64, error) {
+	args := r.Mock.Called(userID)
+	return args.Get(0).(int64), nil
+}
+
+func (r *RecoveryCodeRepositoryMock) DeleteAllByUserId(ctx context.Context, userID int) error {
+	args := r.Mock.Called(userID)
+	return args.Error(0)
+}
@@ -0,0 +1,48 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+)
+
+type SessionRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewSessionRepositoryMock() *SessionRepositoryMock {
+	return &SessionRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *SessionRepositoryMock) Save(ctx context.Context, session *entity.Session) (*entity.Session, error) {
+	args := r.Mock.Called(session)
+	return args.Get(0).(*entity.Session), nil
+}
+
+func (r *SessionRepositoryMock) FindOneById(ctx context.Context, id string) (*entity.Session, error) {
+	args := r.Mock.Called(id)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.Session), nil
+}
+
+func (r *SessionRepositoryMock) RevokeAllByUserId(ctx context.Context, userID int) error {
+	args := r.Mock.Called(userID)
+	return args.Error(0)
+}
+
+func (r *SessionRepositoryMock) FindAllByUserId(ctx context.Context, userID int) ([]*entity.Session, error) {
+	args := r.Mock.Called(userID)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).([]*entity.Session), nil
+}
+
+func (r *SessionRepositoryMock) UpdateAuthentication(ctx context.Context, session *entity.Session) error {
+	args := r.Mock.Called(session)
+	return args.Error(0)
+}
@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"context"
+	"golang-authentication/internal/sms"
+	"sync"
+)
+
+// SmsSenderMock keeps the sent messages so tests can read the codes in them
+type SmsSenderMock struct {
+	Messages []*sms.Message
+	mutex    sync.Mutex
+}
+
+func NewSmsSenderMock() *SmsSenderMock {
+	return &SmsSenderMock{}
+}
+
+func (m *SmsSenderMock) Send(ctx context.Context, message *sms.Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Messages = append(m.Messages, message)
+	return nil
+}
+
+func (m *SmsSenderMock) Last() *sms.Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.Messages) == 0 {
+		return nil
+	}
+	return m.Messages[len(m.Messages)-1]
+}
@@ -0,0 +1,202 @@
+package mocks
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"golang-authentication/internal/models"
+	"sort"
+)
+
+// SoftwareAuthenticator is a WebAuthn authenticator with a P-256 key in memory, producing the attestations and
+// assertions a browser would pass on
+type SoftwareAuthenticator struct {
+	RPID         string
+	Origin       string
+	PrivateKey   *ecdsa.PrivateKey
+	CredentialID []byte
+	UserHandle   string
+	SignCount    uint32
+	UserVerified bool
+}
+
+func NewSoftwareAuthenticator(rpID string, origin string) *SoftwareAuthenticator {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	credentialID := make([]byte, 16)
+	_, _ = rand.Read(credentialID)
+	return &SoftwareAuthenticator{RPID: rpID, Origin: origin, PrivateKey: privateKey, CredentialID: credentialID, UserVerified: true}
+}
+
+func (a *SoftwareAuthenticator) Id() string {
+	return base64.RawURLEncoding.EncodeToString(a.CredentialID)
+}
+
+// COSEKey is the public key in the COSE_Key format of the attested credential data
+func (a *SoftwareAuthenticator) COSEKey() []byte {
+	return EncodeCBOR(map[int]interface{}{
+		1:  2,
+		3:  -7,
+		-1: 1,
+		-2: a.PrivateKey.X.FillBytes(make([]byte, 32)),
+		-3: a.PrivateKey.Y.FillBytes(make([]byte, 32)),
+	})
+}
+
+// Register answers navigator.credentials.create() with a none or a packed self attestation
+func (a *SoftwareAuthenticator) Register(challenge string, format string) models.PublicKeyCredentialRequest {
+	clientDataJSON := a.clientData("webauthn.create", challenge)
+
+	credentialIDLength := make([]byte, 2)
+	binary.BigEndian.PutUint16(credentialIDLength, uint16(len(a.CredentialID)))
+	authenticatorData := a.authenticatorData(0x40)
+	authenticatorData = append(authenticatorData, make([]byte, 16)...)
+	authenticatorData = append(authenticatorData, credentialIDLength...)
+	authenticatorData = append(authenticatorData, a.CredentialID...)
+	authenticatorData = append(authenticatorData, a.COSEKey()...)
+
+	statement := map[string]interface{}{}
+	if format == "packed" {
+		statement["alg"] = -7
+		statement["sig"] = a.sign(authenticatorData, clientDataJSON)
+	}
+	attestationObject := EncodeCBOR(map[string]interface{}{
+		"fmt":      format,
+		"attStmt":  statement,
+		"authData": authenticatorData,
+	})
+
+	return models.PublicKeyCredentialRequest{
+		Id:   a.Id(),
+		Type: "public-key",
+		Response: models.AuthenticatorResponseRequest{
+			ClientDataJSON:    base64.RawURLEncoding.EncodeToString(clientDataJSON),
+			AttestationObject: base64.RawURLEncoding.EncodeToString(attestationObject),
+			Transports:        []string{"internal", "hybrid"},
+		},
+	}
+}
+
+// Assert answers navigator.credentials.get(), counting the signature
+func (a *SoftwareAuthenticator) Assert(challenge string) models.PublicKeyCredentialRequest {
+	a.SignCount++
+	clientDataJSON := a.clientData("webauthn.get", challenge)
+	authenticatorData := a.authenticatorData(0)
+
+	return models.PublicKeyCredentialRequest{
+		Id:   a.Id(),
+		Type: "public-key",
+		Response: models.AuthenticatorResponseRequest{
+			ClientDataJSON:    base64.RawURLEncoding.EncodeToString(clientDataJSON),
+			AuthenticatorData: base64.RawURLEncoding.EncodeToString(authenticatorData),
+			Signature:         base64.RawURLEncoding.EncodeToString(a.sign(authenticatorData, clientDataJSON)),
+			UserHandle:        a.UserHandle,
+		},
+	}
+}
+
+func (a *SoftwareAuthenticator) clientData(ceremony string, challenge string) []byte {
+	clientDataJSON, _ := json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	return clientDataJSON
+}
+
+func (a *SoftwareAuthenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags |= 0x01
+	if a.UserVerified {
+		flags |= 0x04
+	}
+	signCount := make([]byte, 4)
+	binary.BigEndian.PutUint32(signCount, a.SignCount)
+	return append(append(rpIDHash[:], flags), signCount...)
+}
+
+func (a *SoftwareAuthenticator) sign(authenticatorData []byte, clientDataJSON []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authenticatorData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.PrivateKey, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signature
+}
+
+// EncodeCBOR encodes integers, byte and text strings, and maps of them, which is all the authenticator needs
+func EncodeCBOR(value interface{}) []byte {
+	buffer := new(bytes.Buffer)
+	encodeCBOR(buffer, value)
+	return buffer.Bytes()
+}
+
+func encodeCBOR(buffer *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			writeCBORHead(buffer, 1, uint64(-1-v))
+		} else {
+			writeCBORHead(buffer, 0, uint64(v))
+		}
+	case []byte:
+		writeCBORHead(buffer, 2, uint64(len(v)))
+		buffer.Write(v)
+	case string:
+		writeCBORHead(buffer, 3, uint64(len(v)))
+		buffer.WriteString(v)
+	case map[int]interface{}:
+		keys := make([]int, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Ints(keys)
+		writeCBORHead(buffer, 5, uint64(len(v)))
+		for _, key := range keys {
+			encodeCBOR(buffer, key)
+			encodeCBOR(buffer, v[key])
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		writeCBORHead(buffer, 5, uint64(len(v)))
+		for _, key := range keys {
+			encodeCBOR(buffer, key)
+			encodeCBOR(buffer, v[key])
+		}
+	default:
+		panic("unsupported cbor value")
+	}
+}
+
+func writeCBORHead(buffer *bytes.Buffer, majorType byte, argument uint64) {
+	head := majorType << 5
+	switch {
+	case argument < 24:
+		buffer.WriteByte(head | byte(argument))
+	case argument <= 0xff:
+		buffer.WriteByte(head | 24)
+		buffer.WriteByte(byte(argument))
+	case argument <= 0xffff:
+		buffer.WriteByte(head | 25)
+		_ = binary.Write(buffer, binary.BigEndian, uint16(argument))
+	case argument <= 0xffffffff:
+		buffer.WriteByte(head | 26)
+		_ = binary.Write(buffer, binary.BigEndian, uint32(argument))
+	default:
+		buffer.WriteByte(head | 27)
+		_ = binary.Write(buffer, binary.BigEndian, argument)
+	}
+}
@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+	"time"
+)
+
+type TotpCredentialRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewTotpCredentialRepositoryMock() *TotpCredentialRepositoryMock {
+	return &TotpCredentialRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *TotpCredentialRepositoryMock) FindOneByUserId(ctx context.Context, userID int) (*entity.TotpCredential, error) {
+	args := r.Mock.Called(userID)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.TotpCredential), nil
+}
+
+func (r *TotpCredentialRepositoryMock) Save(ctx context.Context, credential *entity.TotpCredential) error {
+	args := r.Mock.Called(credential)
+	return args.Error(0)
+}
+
+func (r *TotpCredentialRepositoryMock) Confirm(ctx context.Context, userID int, confirmedAt time.Time, step int64) error {
+	args := r.Mock.Called(userID, step)
+	return args.Error(0)
+}
+
+func (r *TotpCredentialRepositoryMock) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	args := r.Mock.Called(userID, step)
+	return args.Bool(0), nil
+}
+
+func (r *TotpCredentialRepositoryMock) DeleteByUserId(ctx context.Context, userID int) error {
+	args := r.Mock.Called(userID)
+	return args.Error(0)
+}
@@ -0,0 +1,56 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+	"time"
+)
+
+type TrustedDeviceRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewTrustedDeviceRepositoryMock() *TrustedDeviceRepositoryMock {
+	return &TrustedDeviceRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *TrustedDeviceRepositoryMock) Save(ctx context.Context, device *entity.TrustedDevice) error {
+	args := r.Mock.Called(device)
+	return args.Error(0)
+}
+
+func (r *TrustedDeviceRepositoryMock) FindOneByTokenHash(ctx context.Context, tokenHash string) (*entity.TrustedDevice, error) {
+	args := r.Mock.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.TrustedDevice), nil
+}
+
+func (r *TrustedDeviceRepositoryMock) FindAllActiveByUserId(ctx context.Context, userID int, now time.Time) ([]entity.TrustedDevice, error) {
+	args := r.Mock.Called(userID)
+	return args.Get(0).([]entity.TrustedDevice), nil
+}
+
+func (r *TrustedDeviceRepositoryMock) UpdateLastUsedAt(ctx context.Context, id int, usedAt time.Time) error {
+	args := r.Mock.Called(id)
+	return args.Error(0)
+}
+
+func (r *TrustedDeviceRepositoryMock) Delete(ctx context.Context, userID int, id int) (bool, error) {
+	args := r.Mock.Called(userID, id)
+	return args.Bool(0), nil
+}
+
+func (r *TrustedDeviceRepositoryMock) DeleteAllByUserId(ctx context.Context, userID int) error {
+	args := r.Mock.Called(userID)
+	return args.Error(0)
+}
+
+func (r *TrustedDeviceRepositoryMock) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := r.Mock.Called()
+	return args.Get(0).(int64), nil
+}
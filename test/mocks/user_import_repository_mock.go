@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+	"time"
+)
+
+type UserImportRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewUserImportRepositoryMock() *UserImportRepositoryMock {
+	return &UserImportRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *UserImportRepositoryMock) SaveJob(ctx context.Context, job *entity.UserImportJob) (*entity.UserImportJob, error) {
+	args := r.Mock.Called(job)
+	return args.Get(0).(*entity.UserImportJob), nil
+}
+
+func (r *UserImportRepositoryMock) FindJobById(ctx context.Context, id int) (*entity.UserImportJob, error) {
+	args := r.Mock.Called(id)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.UserImportJob), nil
+}
+
+func (r *UserImportRepositoryMock) ClaimNextJob(ctx context.Context, leaseExpiresAt time.Time) (*entity.UserImportJob, error) {
+	args := r.Mock.Called()
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.UserImportJob), nil
+}
+
+func (r *UserImportRepositoryMock) ClaimJob(ctx context.Context, id int, leaseExpiresAt time.Time) (bool, error) {
+	args := r.Mock.Called(id)
+	return args.Bool(0), nil
+}
+
+func (r *UserImportRepositoryMock) UpdateJobProgress(ctx context.Context, job *entity.UserImportJob) error {
+	args := r.Mock.Called(job)
+	return args.Error(0)
+}
+
+func (r *UserImportRepositoryMock) SaveError(ctx context.Context, importError *entity.UserImportError) error {
+	args := r.Mock.Called(importError)
+	return args.Error(0)
+}
+
+func (r *UserImportRepositoryMock) FindErrorsByJobId(ctx context.Context, jobID int, offset int, limit int) ([]*entity.UserImportError, int64, error) {
+	args := r.Mock.Called(jobID, offset, limit)
+	if args.Get(0) == nil {
+		return nil, 0, nil
+	}
+	return args.Get(0).([]*entity.UserImportError), int64(args.Int(1)), nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/stretchr/testify/mock"
 	"golang-authentication/internal/entity"
+	"time"
 )
 
 type UserRepositoryMock struct {
@@ -18,6 +19,9 @@ func NewUserRepositoryMock() *UserRepositoryMock {
 
 func (r *UserRepositoryMock) Save(ctx context.Context, user *entity.User) (*entity.User, error) {
 	args := r.Mock.Called(user)
+	if len(args) > 1 && args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).(*entity.User), nil
 
 }
@@ -35,3 +39,147 @@ func (r *UserRepositoryMock) FindOneByEmail(ctx context.Context, email string) (
 	}
 	return args.Get(0).(*entity.User), nil
 }
+
+func (r *UserRepositoryMock) FindOneByExactEmail(ctx context.Context, email string) (*entity.User, error) {
+	args := r.Mock.Called(email)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.User), nil
+}
+
+func (r *UserRepositoryMock) FindOneById(ctx context.Context, id int) (*entity.User, error) {
+	args := r.Mock.Called(id)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.User), nil
+}
+
+func (r *UserRepositoryMock) Update(ctx context.Context, user *entity.User) (*entity.User, error) {
+	args := r.Mock.Called(user)
+	return args.Get(0).(*entity.User), nil
+}
+
+func (r *UserRepositoryMock) FindOneByEmailWithDeleted(ctx context.Context, email string) (*entity.User, error) {
+	args := r.Mock.Called(email)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.User), nil
+}
+
+func (r *UserRepositoryMock) FindOneByIdWithDeleted(ctx context.Context, id int) (*entity.User, error) {
+	args := r.Mock.Called(id)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.User), nil
+}
+
+func (r *UserRepositoryMock) SoftDeleteById(ctx context.Context, id int) error {
+	args := r.Mock.Called(id)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) RestoreById(ctx context.Context, id int) error {
+	args := r.Mock.Called(id)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) DeleteSoftDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := r.Mock.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *UserRepositoryMock) UpdateSignInAttempts(ctx context.Context, user *entity.User) error {
+	args := r.Mock.Called(user)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) IncrementFailedSignInAttempts(ctx context.Context, user *entity.User) error {
+	args := r.Mock.Called(user)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) Lock(ctx context.Context, id int, maxAttempts int, lockedUntil time.Time) (bool, error) {
+	args := r.Mock.Called(id, maxAttempts, lockedUntil)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *UserRepositoryMock) UpdateStatus(ctx context.Context, user *entity.User) error {
+	args := r.Mock.Called(user)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) ChangePassword(ctx context.Context, user *entity.User) error {
+	args := r.Mock.Called(user)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) UpdateProfile(ctx context.Context, user *entity.User) error {
+	args := r.Mock.Called(user)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) FindOneByUsername(ctx context.Context, username string) (*entity.User, error) {
+	args := r.Mock.Called(username)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.User), nil
+}
+
+func (r *UserRepositoryMock) FindOneByUsernameWithDeleted(ctx context.Context, username string) (*entity.User, error) {
+	args := r.Mock.Called(username)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.User), nil
+}
+
+func (r *UserRepositoryMock) FindAllInBatches(ctx context.Context, batchSize int, process func(users []*entity.User) error) error {
+	args := r.Mock.Called(batchSize)
+	if users, ok := args.Get(0).([]*entity.User); ok {
+		for start := 0; start < len(users); start += batchSize {
+			end := start + batchSize
+			if end > len(users) {
+				end = len(users)
+			}
+			if err := process(users[start:end]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (r *UserRepositoryMock) UpdateEmailCanonical(ctx context.Context, id int, emailCanonical string) error {
+	args := r.Mock.Called(id, emailCanonical)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) UpdatePasswordChangeRequired(ctx context.Context, id int, required bool) error {
+	args := r.Mock.Called(id, required)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) UpdatePassword(ctx context.Context, id int, password string) error {
+	args := r.Mock.Called(id, password)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) UpdateLastLoginAt(ctx context.Context, id int, lastLoginAt time.Time) error {
+	args := r.Mock.Called(id, lastLoginAt)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) UpdateMfaEnabled(ctx context.Context, id int, enabled bool) error {
+	args := r.Mock.Called(id, enabled)
+	return args.Error(0)
+}
+
+func (r *UserRepositoryMock) UpdatePhoneNumber(ctx context.Context, id int, phoneNumber *string) error {
+	args := r.Mock.Called(id, phoneNumber)
+	return args.Error(0)
+}
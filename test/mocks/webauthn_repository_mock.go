@@ -0,0 +1,84 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"golang-authentication/internal/entity"
+	"time"
+)
+
+type WebAuthnCredentialRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewWebAuthnCredentialRepositoryMock() *WebAuthnCredentialRepositoryMock {
+	return &WebAuthnCredentialRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *WebAuthnCredentialRepositoryMock) Save(ctx context.Context, credential *entity.WebAuthnCredential) error {
+	args := r.Mock.Called(credential)
+	return args.Error(0)
+}
+
+func (r *WebAuthnCredentialRepositoryMock) FindAllByUserId(ctx context.Context, userID int) ([]entity.WebAuthnCredential, error) {
+	args := r.Mock.Called(userID)
+	return args.Get(0).([]entity.WebAuthnCredential), nil
+}
+
+func (r *WebAuthnCredentialRepositoryMock) FindOneByCredentialIdHash(ctx context.Context, credentialIdHash string) (*entity.WebAuthnCredential, error) {
+	args := r.Mock.Called(credentialIdHash)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.WebAuthnCredential), nil
+}
+
+func (r *WebAuthnCredentialRepositoryMock) CountByUserId(ctx context.Context, userID int) (int64, error) {
+	args := r.Mock.Called(userID)
+	return args.Get(0).(int64), nil
+}
+
+func (r *WebAuthnCredentialRepositoryMock) UpdateSignCount(ctx context.Context, id int, signCount int64, usedAt time.Time) (bool, error) {
+	args := r.Mock.Called(id, signCount)
+	return args.Bool(0), nil
+}
+
+func (r *WebAuthnCredentialRepositoryMock) Delete(ctx context.Context, userID int, id int) (bool, error) {
+	args := r.Mock.Called(userID, id)
+	return args.Bool(0), nil
+}
+
+type WebAuthnChallengeRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewWebAuthnChallengeRepositoryMock() *WebAuthnChallengeRepositoryMock {
+	return &WebAuthnChallengeRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *WebAuthnChallengeRepositoryMock) Save(ctx context.Context, challenge *entity.WebAuthnChallenge) error {
+	args := r.Mock.Called(challenge)
+	return args.Error(0)
+}
+
+func (r *WebAuthnChallengeRepositoryMock) FindOneByChallengeHash(ctx context.Context, challengeHash string) (*entity.WebAuthnChallenge, error) {
+	args := r.Mock.Called(challengeHash)
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*entity.WebAuthnChallenge), nil
+}
+
+func (r *WebAuthnChallengeRepositoryMock) MarkUsed(ctx context.Context, id int, usedAt time.Time) (bool, error) {
+	args := r.Mock.Called(id)
+	return args.Bool(0), nil
+}
+
+func (r *WebAuthnChallengeRepositoryMock) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := r.Mock.Called()
+	return args.Get(0).(int64), nil
+}
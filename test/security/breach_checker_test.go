@@ -0,0 +1,52 @@
+package security
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/security"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRange writes the range file for password the way the Have I Been Pwned downloader does
+func writeRange(t *testing.T, directory string, password string, count string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	content := "0000000000000000000000000000000000A:3\r\n" + hash[5:] + ":" + count + "\r\n"
+	err := os.WriteFile(filepath.Join(directory, hash[:5]+".txt"), []byte(content), 0o600)
+	require.Nil(t, err)
+}
+
+func TestRangeBreachChecker(t *testing.T) {
+	directory := t.TempDir()
+	writeRange(t, directory, "12345678", "2938")
+	writeRange(t, directory, "rarely-seen-password", "1")
+
+	checker := &security.RangeBreachChecker{Directory: directory, MinCount: 1}
+
+	t.Run("Should find a breached password", func(t *testing.T) {
+		breached, err := checker.IsBreached("12345678")
+		require.Nil(t, err)
+		require.True(t, breached)
+	})
+
+	t.Run("Should not find a password without a range file", func(t *testing.T) {
+		breached, err := checker.IsBreached("correct-Horse-battery-Staple-42")
+		require.Nil(t, err)
+		require.False(t, breached)
+	})
+
+	t.Run("Should ignore passwords seen less than the minimum count", func(t *testing.T) {
+		strict := &security.RangeBreachChecker{Directory: directory, MinCount: 10}
+		breached, err := strict.IsBreached("rarely-seen-password")
+		require.Nil(t, err)
+		require.False(t, breached)
+
+		breached, err = strict.IsBreached("12345678")
+		require.Nil(t, err)
+		require.True(t, breached)
+	})
+}
@@ -0,0 +1,76 @@
+package security
+
+import (
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/security"
+	"testing"
+)
+
+func TestEmailDomainPolicy(t *testing.T) {
+	code := func(policy *security.EmailDomainPolicy, email string) string {
+		violation := policy.Check(email)
+		if violation == nil {
+			return ""
+		}
+		return violation.Code
+	}
+
+	t.Run("Should load the disposable domain list", func(t *testing.T) {
+		viper := config.NewViper("./../../")
+		viper.Set("email.domains.block_disposable", true)
+		policy, err := security.NewEmailDomainPolicy(viper)
+		require.Nil(t, err)
+
+		require.Equal(t, security.EmailDomainDisposable, code(policy, "someone@mailinator.com"))
+		require.Equal(t, security.EmailDomainDisposable, code(policy, "someone@inbox.Mailinator.com"))
+		require.Equal(t, "", code(policy, "someone@gmail.com"))
+		for domain := range policy.DisposableDomains {
+			require.NotContains(t, domain, "#")
+		}
+	})
+
+	t.Run("Should only block disposable domains when enabled", func(t *testing.T) {
+		policy, err := security.NewEmailDomainPolicy(config.NewViper("./../../"))
+		require.Nil(t, err)
+		require.Equal(t, "", code(policy, "someone@mailinator.com"))
+		require.Empty(t, policy.DisposableDomains)
+	})
+
+	t.Run("Should fail when the disposable domain list is missing", func(t *testing.T) {
+		viper := config.NewViper("./../../")
+		viper.Set("email.domains.disposable_domains_file", "./missing-disposable-domains.txt")
+
+		_, err := security.NewEmailDomainPolicy(viper)
+		require.Nil(t, err)
+
+		viper.Set("email.domains.block_disposable", true)
+		_, err = security.NewEmailDomainPolicy(viper)
+		require.NotNil(t, err)
+	})
+
+	t.Run("Should match the allow list exactly or by wildcard subdomain", func(t *testing.T) {
+		policy := &security.EmailDomainPolicy{Allow: []string{"acme.com", "*.partner.io"}}
+
+		require.Equal(t, "", code(policy, "jane@acme.com"))
+		require.Equal(t, "", code(policy, "jane@eu.partner.io"))
+		require.Equal(t, security.EmailDomainNotAllowed, code(policy, "jane@sales.acme.com"))
+		require.Equal(t, security.EmailDomainNotAllowed, code(policy, "jane@partner.io"))
+		require.Equal(t, security.EmailDomainNotAllowed, code(policy, "jane@notpartner.io"))
+		require.Equal(t, security.EmailDomainNotAllowed, code(policy, "jane@gmail.com"))
+	})
+
+	t.Run("Should let the deny list win over the allow list", func(t *testing.T) {
+		policy := &security.EmailDomainPolicy{Allow: []string{"*.acme.com"}, Deny: []string{"contractors.acme.com", "*.example.com"}}
+
+		require.Equal(t, "", code(policy, "jane@hq.acme.com"))
+		require.Equal(t, security.EmailDomainDenied, code(policy, "jane@contractors.acme.com"))
+		require.Equal(t, security.EmailDomainDenied, code(policy, "jane@mail.example.com"))
+	})
+
+	t.Run("Should trust an allowed domain even when it is listed as disposable", func(t *testing.T) {
+		policy := &security.EmailDomainPolicy{Allow: []string{"yopmail.com"}, BlockDisposable: true, DisposableDomains: map[string]struct{}{"yopmail.com": {}}}
+
+		require.Equal(t, "", code(policy, "qa@yopmail.com"))
+	})
+}
@@ -0,0 +1,67 @@
+package security
+
+import (
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/security"
+	"testing"
+)
+
+func TestLegacyPasswordHasher(t *testing.T) {
+	viper := config.NewViper("./../../")
+	hasher := security.NewPasswordHasher(viper)
+
+	hashes := map[string]string{
+		"scrypt":        "$scrypt$ln=14,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$LLP61ttm3z3DWEeq2HIZpVEfPYfB2aLgurtIsW8/FCE",
+		"pbkdf2-sha256": "$pbkdf2-sha256$29000$MDEyMzQ1Njc4OWFiY2RlZg$cTz3gk3UdWQZMqIuASuTgJzTcoQTvJe/HL9yRSZa4JE",
+		"pbkdf2-sha512": "$pbkdf2-sha512$i=25000$MDEyMzQ1Njc4OWFiY2RlZg$bb7PZtM/bQxySenleHNV51eW9lelZsuDvkRMP/UVeG98ELmGCti7o2K8YYHQe92+tmIqkYwh3He/sSOPOUNB4w",
+		"ssha":          "{SSHA}IbmSpl71D9zoXWwMIXWGhS8VTOtzYWx0c2FsdA==",
+		"ssha512":       "{SSHA512}ErLPl2tkbZ5lypvugDtx510yBp/2KhoXMgROuEjF2Y4xuEeRSRvJBAMQVwZYgdy26+ObbzTnHHZZIeSTryn3wnNhbHRzYWx0",
+	}
+
+	for name, hashedPassword := range hashes {
+		t.Run("Should verify "+name, func(t *testing.T) {
+			require.True(t, hasher.Supports(hashedPassword))
+			require.Nil(t, hasher.Check(hashedPassword))
+
+			match, err := hasher.Verify(hashedPassword, "correct-Horse-battery-Staple-42")
+			require.Nil(t, err)
+			require.True(t, match)
+
+			match, err = hasher.Verify(hashedPassword, "wrong-password")
+			require.Nil(t, err)
+			require.False(t, match)
+
+			require.True(t, hasher.NeedsRehash(hashedPassword))
+		})
+	}
+
+	t.Run("Should not hash with a legacy algorithm", func(t *testing.T) {
+		_, err := (&security.ScryptHasher{}).Hash("password")
+		require.ErrorIs(t, err, security.ErrVerifyOnly)
+	})
+
+	t.Run("Should refuse parameters out of bounds", func(t *testing.T) {
+		outOfBounds := []string{
+			"$scrypt$ln=30,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$LLP61ttm3z3DWEeq2HIZpVEfPYfB2aLgurtIsW8/FCE",
+			"$scrypt$ln=14,r=1000000,p=1$MDEyMzQ1Njc4OWFiY2RlZg$LLP61ttm3z3DWEeq2HIZpVEfPYfB2aLgurtIsW8/FCE",
+			"$scrypt$ln=14,r=8,p=100000$MDEyMzQ1Njc4OWFiY2RlZg$LLP61ttm3z3DWEeq2HIZpVEfPYfB2aLgurtIsW8/FCE",
+			"$scrypt$ln=14,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$",
+			"$pbkdf2-sha256$2000000000$MDEyMzQ1Njc4OWFiY2RlZg$cTz3gk3UdWQZMqIuASuTgJzTcoQTvJe/HL9yRSZa4JE",
+			"$pbkdf2-sha256$29000$MDEyMzQ1Njc4OWFiY2RlZg$",
+			"$2a$31$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS",
+		}
+		for _, hashedPassword := range outOfBounds {
+			require.NotNil(t, hasher.Check(hashedPassword), hashedPassword)
+
+			match, err := hasher.Verify(hashedPassword, "correct-Horse-battery-Staple-42")
+			require.NotNil(t, err, hashedPassword)
+			require.False(t, match)
+		}
+	})
+
+	t.Run("Should not support an unsalted hash", func(t *testing.T) {
+		require.False(t, hasher.Supports("5f4dcc3b5aa765d61d8327deb882cf99"))
+		require.False(t, hasher.Supports("{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="))
+	})
+}
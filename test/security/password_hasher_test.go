@@ -0,0 +1,132 @@
+package security
+
+import (
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/security"
+	"strings"
+	"testing"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("key.pepper.current_version", 0)
+	hasher := security.NewPasswordHasher(viper)
+
+	t.Run("Should hash with argon2id in the PHC format", func(t *testing.T) {
+		hashedPassword, err := hasher.Hash("correct-Horse-battery-Staple-42")
+		require.Nil(t, err)
+		require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=19456,t=2,p=1$"))
+
+		match, err := hasher.Verify(hashedPassword, "correct-Horse-battery-Staple-42")
+		require.Nil(t, err)
+		require.True(t, match)
+
+		match, err = hasher.Verify(hashedPassword, "wrong-password")
+		require.Nil(t, err)
+		require.False(t, match)
+		require.False(t, hasher.NeedsRehash(hashedPassword))
+	})
+
+	t.Run("Should verify a bcrypt hash and ask for a rehash", func(t *testing.T) {
+		hashedPassword := "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"
+		match, err := hasher.Verify(hashedPassword, "12345678")
+		require.Nil(t, err)
+		require.True(t, match)
+		require.True(t, hasher.NeedsRehash(hashedPassword))
+	})
+
+	t.Run("Should ask for a rehash when the parameters changed", func(t *testing.T) {
+		weaker := &security.Argon2idHasher{Memory: 8192, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+		hashedPassword, err := weaker.Hash("correct-Horse-battery-Staple-42")
+		require.Nil(t, err)
+
+		match, err := hasher.Verify(hashedPassword, "correct-Horse-battery-Staple-42")
+		require.Nil(t, err)
+		require.True(t, match)
+		require.True(t, hasher.NeedsRehash(hashedPassword))
+	})
+
+	t.Run("Should refuse an unknown hash format", func(t *testing.T) {
+		match, err := hasher.Verify("5f4dcc3b5aa765d61d8327deb882cf99", "password")
+		require.ErrorIs(t, err, security.ErrUnknownHashFormat)
+		require.False(t, match)
+	})
+
+	t.Run("Should refuse argon2id parameters out of bounds", func(t *testing.T) {
+		salt := "c29tZXNhbHRzb21lc2FsdA"
+		key := "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+		for _, params := range []string{"m=65536,t=0,p=0", "m=65536,t=1,p=0", "m=4294967295,t=1,p=1", "m=19456,t=100000,p=1", "m=19456,t=2,p=255", "m=0,t=2,p=1"} {
+			hashedPassword := "$argon2id$v=19$" + params + "$" + salt + "$" + key
+			require.NotPanics(t, func() {
+				match, err := hasher.Verify(hashedPassword, "password")
+				require.NotNil(t, err, params)
+				require.False(t, match)
+			})
+			require.True(t, hasher.NeedsRehash(hashedPassword))
+		}
+
+		match, err := hasher.Verify("$argon2id$v=19$m=19456,t=2,p=1$"+salt+"$", "password")
+		require.NotNil(t, err)
+		require.False(t, match)
+	})
+
+	t.Run("Should hash with bcrypt when configured", func(t *testing.T) {
+		viper.Set("password.hashing.algorithm", "bcrypt")
+		defer viper.Set("password.hashing.algorithm", "argon2id")
+
+		hashedPassword, err := security.NewPasswordHasher(viper).Hash("password")
+		require.Nil(t, err)
+		require.True(t, strings.HasPrefix(hashedPassword, "$2a$10$"))
+	})
+}
+
+func TestPasswordHasherPepper(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("key.pepper.current_version", 1)
+	viper.Set("key.pepper.secrets", map[string]string{"1": "first-secret"})
+	hasher := security.NewPasswordHasher(viper)
+
+	hashedPassword, err := hasher.Hash("correct-Horse-battery-Staple-42")
+	require.Nil(t, err)
+
+	t.Run("Should record the pepper version in the hash", func(t *testing.T) {
+		require.True(t, strings.HasPrefix(hashedPassword, "$pepper$v=1$argon2id$v=19$"))
+
+		match, err := hasher.Verify(hashedPassword, "correct-Horse-battery-Staple-42")
+		require.Nil(t, err)
+		require.True(t, match)
+		require.False(t, hasher.NeedsRehash(hashedPassword))
+	})
+
+	t.Run("Should not verify without the pepper", func(t *testing.T) {
+		unpeppered := strings.TrimPrefix(hashedPassword, "$pepper$v=1")
+		match, err := hasher.Verify(unpeppered, "correct-Horse-battery-Staple-42")
+		require.Nil(t, err)
+		require.False(t, match)
+		require.True(t, hasher.NeedsRehash(unpeppered))
+	})
+
+	t.Run("Should keep verifying the previous version after a rotation", func(t *testing.T) {
+		viper.Set("key.pepper.current_version", 2)
+		viper.Set("key.pepper.secrets", map[string]string{"1": "first-secret", "2": "second-secret"})
+		rotated := security.NewPasswordHasher(viper)
+
+		match, err := rotated.Verify(hashedPassword, "correct-Horse-battery-Staple-42")
+		require.Nil(t, err)
+		require.True(t, match)
+		require.True(t, rotated.NeedsRehash(hashedPassword))
+
+		rehashed, err := rotated.Hash("correct-Horse-battery-Staple-42")
+		require.Nil(t, err)
+		require.True(t, strings.HasPrefix(rehashed, "$pepper$v=2$"))
+	})
+
+	t.Run("Should fail when the secret of the version is gone", func(t *testing.T) {
+		viper.Set("key.pepper.current_version", 2)
+		viper.Set("key.pepper.secrets", map[string]string{"2": "second-secret"})
+
+		_, err := security.NewPasswordHasher(viper).Verify(hashedPassword, "correct-Horse-battery-Staple-42")
+		require.NotNil(t, err)
+	})
+}
@@ -0,0 +1,65 @@
+package security
+
+import (
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/security"
+	"testing"
+)
+
+func codes(violations []models.ErrorDetail) []string {
+	var result []string
+	for _, violation := range violations {
+		result = append(result, violation.Code)
+	}
+	return result
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	viper := config.NewViper("./../../")
+	policy := security.NewPasswordPolicy(viper)
+
+	t.Run("Should load the common password dictionary", func(t *testing.T) {
+		require.Contains(t, codes(policy.Validate("Password123")), security.PasswordTooCommon)
+	})
+
+	t.Run("Should report every failure", func(t *testing.T) {
+		strict := &security.PasswordPolicy{
+			MinLength:            10,
+			MaxLength:            72,
+			RequireUppercase:     true,
+			RequireLowercase:     true,
+			RequireDigit:         true,
+			RequireSymbol:        true,
+			DisallowPersonalInfo: true,
+			MinStrengthScore:     3,
+			CommonPasswords:      map[string]struct{}{},
+		}
+
+		violations := strict.Validate("danar", "Danar Cahyadi", "danar@gmail.com")
+		require.Equal(t, []string{
+			security.PasswordTooShort,
+			security.PasswordMissingUppercase,
+			security.PasswordMissingDigit,
+			security.PasswordMissingSymbol,
+			security.PasswordContainsPersonalInfo,
+			security.PasswordTooWeak,
+		}, codes(violations))
+		require.Equal(t, "Password must be min 10", violations[0].Message)
+
+		require.Empty(t, strict.Validate("Tr0ub4dor&3-horse", "Danar Cahyadi", "danar@gmail.com"))
+	})
+
+	t.Run("Should find personal info in any case", func(t *testing.T) {
+		require.Contains(t, codes(policy.Validate("MyCahyadiSecret!", "Danar Cahyadi")), security.PasswordContainsPersonalInfo)
+		require.Contains(t, codes(policy.Validate("xx-d.cahyadi-xx", "", "d.cahyadi@gmail.com")), security.PasswordContainsPersonalInfo)
+		require.NotContains(t, codes(policy.Validate("correct horse battery", "Al Bo")), security.PasswordContainsPersonalInfo)
+	})
+
+	t.Run("Strength score should grow with length and character classes", func(t *testing.T) {
+		require.Equal(t, 0, security.StrengthScore("aaaaaaaa"))
+		require.Less(t, security.StrengthScore("abcdefgh"), security.StrengthScore("abcdEFG1!xyz"))
+		require.Equal(t, 4, security.StrengthScore("correct-Horse-battery-Staple-42"))
+	})
+}
@@ -0,0 +1,79 @@
+package security
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/security"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTOTP(t *testing.T) {
+	// the SHA1 secret of the RFC 6238 test vectors
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	t.Run("Should match the RFC 6238 test vectors", func(t *testing.T) {
+		totp := &security.TOTP{Digits: 8, Period: 30 * time.Second}
+		vectors := map[int64]string{
+			59:         "94287082",
+			1111111109: "07081804",
+			1234567890: "89005924",
+			2000000000: "69279037",
+		}
+		for unix, expected := range vectors {
+			code, err := totp.Code(secret, time.Unix(unix, 0))
+			require.Nil(t, err)
+			require.Equal(t, expected, code)
+		}
+	})
+
+	t.Run("Should accept codes within the skew only", func(t *testing.T) {
+		totp := security.NewTOTP(1)
+		now := time.Unix(1234567890, 0)
+
+		previous, err := totp.Code(secret, now.Add(-30*time.Second))
+		require.Nil(t, err)
+		step, err := totp.Verify(secret, previous, now)
+		require.Nil(t, err)
+		require.Equal(t, totp.Step(now)-1, step)
+
+		old, err := totp.Code(secret, now.Add(-90*time.Second))
+		require.Nil(t, err)
+		step, err = totp.Verify(secret, old, now)
+		require.Nil(t, err)
+		require.Equal(t, int64(-1), step)
+	})
+
+	t.Run("Should build a provisioning uri for authenticator apps", func(t *testing.T) {
+		totp := security.NewTOTP(1)
+		uri, err := url.Parse(totp.ProvisioningURI("golang-authentication", "danar@gmail.com", secret))
+		require.Nil(t, err)
+		require.Equal(t, "otpauth", uri.Scheme)
+		require.Equal(t, "totp", uri.Host)
+		require.Equal(t, secret, uri.Query().Get("secret"))
+		require.Equal(t, "golang-authentication", uri.Query().Get("issuer"))
+	})
+}
+
+func TestSecretBox(t *testing.T) {
+	box := security.NewSecretBox("secret-box-key")
+
+	t.Run("Should open what it sealed", func(t *testing.T) {
+		sealed, err := box.Seal("JBSWY3DPEHPK3PXP")
+		require.Nil(t, err)
+		require.NotContains(t, sealed, "JBSWY3DPEHPK3PXP")
+
+		opened, err := box.Open(sealed)
+		require.Nil(t, err)
+		require.Equal(t, "JBSWY3DPEHPK3PXP", opened)
+	})
+
+	t.Run("Should reject a value sealed with another key", func(t *testing.T) {
+		sealed, err := security.NewSecretBox("another-key").Seal("JBSWY3DPEHPK3PXP")
+		require.Nil(t, err)
+
+		_, err = box.Open(sealed)
+		require.Equal(t, security.ErrInvalidCiphertext, err)
+	})
+}
@@ -0,0 +1,82 @@
+package security
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/security"
+	"golang-authentication/test/mocks"
+	"testing"
+)
+
+func TestWebAuthn(t *testing.T) {
+	webAuthn := security.NewWebAuthn("localhost", []string{"http://localhost:8080"})
+	challenge := "3f1c0e9a7b2d4c6e8f0a1b2c3d4e5f60"
+
+	decode := func(t *testing.T, value string) []byte {
+		decoded, err := base64.RawURLEncoding.DecodeString(value)
+		require.Nil(t, err)
+		return decoded
+	}
+
+	register := func(t *testing.T, authenticator *mocks.SoftwareAuthenticator, format string) (*security.AuthenticatorData, error) {
+		credential := authenticator.Register(challenge, format)
+		return webAuthn.VerifyRegistration(decode(t, credential.Response.ClientDataJSON), decode(t, credential.Response.AttestationObject), challenge, false)
+	}
+
+	assert := func(t *testing.T, credential models.PublicKeyCredentialRequest, publicKey []byte, requireUserVerification bool) (*security.AuthenticatorData, error) {
+		return webAuthn.VerifyAssertion(decode(t, credential.Response.ClientDataJSON), decode(t, credential.Response.AuthenticatorData),
+			decode(t, credential.Response.Signature), publicKey, challenge, requireUserVerification)
+	}
+
+	t.Run("Should register with none and packed self attestation", func(t *testing.T) {
+		for _, format := range []string{"none", "packed"} {
+			authenticator := mocks.NewSoftwareAuthenticator("localhost", "http://localhost:8080")
+			authenticatorData, err := register(t, authenticator, format)
+			require.Nil(t, err, format)
+			require.Equal(t, authenticator.CredentialID, authenticatorData.CredentialID)
+			require.Equal(t, authenticator.COSEKey(), authenticatorData.PublicKey)
+			require.True(t, authenticatorData.UserVerified())
+		}
+	})
+
+	t.Run("Should reject a registration for another origin, relying party or challenge", func(t *testing.T) {
+		_, err := register(t, mocks.NewSoftwareAuthenticator("localhost", "https://evil.example"), "none")
+		require.ErrorIs(t, err, security.ErrInvalidWebAuthnResponse)
+
+		_, err = register(t, mocks.NewSoftwareAuthenticator("evil.example", "http://localhost:8080"), "none")
+		require.ErrorIs(t, err, security.ErrInvalidWebAuthnResponse)
+
+		authenticator := mocks.NewSoftwareAuthenticator("localhost", "http://localhost:8080")
+		credential := authenticator.Register("another-challenge", "none")
+		_, err = webAuthn.VerifyRegistration(decode(t, credential.Response.ClientDataJSON), decode(t, credential.Response.AttestationObject), challenge, false)
+		require.ErrorIs(t, err, security.ErrInvalidWebAuthnResponse)
+	})
+
+	t.Run("Should reject an unsupported attestation format", func(t *testing.T) {
+		_, err := register(t, mocks.NewSoftwareAuthenticator("localhost", "http://localhost:8080"), "fido-u2f")
+		require.ErrorIs(t, err, security.ErrInvalidWebAuthnResponse)
+	})
+
+	t.Run("Should verify an assertion with the registered key", func(t *testing.T) {
+		authenticator := mocks.NewSoftwareAuthenticator("localhost", "http://localhost:8080")
+
+		authenticatorData, err := assert(t, authenticator.Assert(challenge), authenticator.COSEKey(), true)
+		require.Nil(t, err)
+		require.Equal(t, uint32(1), authenticatorData.SignCount)
+
+		other := mocks.NewSoftwareAuthenticator("localhost", "http://localhost:8080")
+		_, err = assert(t, authenticator.Assert(challenge), other.COSEKey(), true)
+		require.ErrorIs(t, err, security.ErrInvalidWebAuthnResponse)
+	})
+
+	t.Run("Should require user verification when asked to", func(t *testing.T) {
+		authenticator := mocks.NewSoftwareAuthenticator("localhost", "http://localhost:8080")
+		authenticator.UserVerified = false
+
+		_, err := assert(t, authenticator.Assert(challenge), authenticator.COSEKey(), false)
+		require.Nil(t, err)
+		_, err = assert(t, authenticator.Assert(challenge), authenticator.COSEKey(), true)
+		require.ErrorIs(t, err, security.ErrInvalidWebAuthnResponse)
+	})
+}
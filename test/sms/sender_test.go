@@ -0,0 +1,25 @@
+package sms
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/sms"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileSender(t *testing.T) {
+	sender := &sms.FileSender{Directory: t.TempDir()}
+
+	err := sender.Send(context.Background(), &sms.Message{To: "+6281234567890", Body: "first"})
+	require.Nil(t, err)
+	err = sender.Send(context.Background(), &sms.Message{To: "+6281234567890", Body: "second"})
+	require.Nil(t, err)
+
+	content, err := os.ReadFile(sender.Path("+6281234567890"))
+	require.Nil(t, err)
+	require.Equal(t, 2, strings.Count(string(content), "To: +6281234567890"))
+	require.Contains(t, string(content), "first")
+	require.Contains(t, string(content), "second")
+}
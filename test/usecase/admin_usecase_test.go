@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestAdminUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	validator := config.NewValidator()
+	repositoryMock := mocks.NewUserRepositoryMock()
+	sessionRepositoryMock := mocks.NewSessionRepositoryMock()
+	adminUseCase := usecase.NewAdminUseCase(repositoryMock, sessionRepositoryMock, validator, viper)
+
+	t.Run("Restore user", func(t *testing.T) {
+		t.Run("When user doesn't exist", func(t *testing.T) {
+			repositoryMock.Mock.On("FindOneByIdWithDeleted", 99).Return(nil)
+			result, err := adminUseCase.RestoreUser(context.Background(), 99)
+			require.Equal(t, &models.ErrorResponse{Code: 404, Message: "User not found", Status: "Not Found"}, err)
+			require.Nil(t, result)
+		})
+
+		t.Run("When user is not deleted", func(t *testing.T) {
+			user := &entity.User{Id: 1, Name: "danar", Email: "danar@gmail.com"}
+			repositoryMock.Mock.On("FindOneByIdWithDeleted", 1).Return(user).Once()
+			result, err := adminUseCase.RestoreUser(context.Background(), 1)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "User is not deleted", Status: "Bad Request"}, err)
+			require.Nil(t, result)
+		})
+
+		t.Run("When user is deleted", func(t *testing.T) {
+			user := &entity.User{
+				Id:        2,
+				Name:      "danar",
+				Email:     "danar@gmail.com",
+				DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true},
+			}
+			repositoryMock.Mock.On("FindOneByIdWithDeleted", 2).Return(user).Once()
+			repositoryMock.Mock.On("RestoreById", 2).Return(nil).Once()
+			result, err := adminUseCase.RestoreUser(context.Background(), 2)
+			require.Nil(t, err)
+			require.Equal(t, 2, result.Id)
+			repositoryMock.Mock.AssertCalled(t, "RestoreById", 2)
+		})
+	})
+
+	t.Run("Require password change", func(t *testing.T) {
+		user := &entity.User{Id: 6, Name: "danar", Email: "danar@gmail.com"}
+		repositoryMock.Mock.On("FindOneById", 6).Return(user).Once()
+		repositoryMock.Mock.On("UpdatePasswordChangeRequired", 6, true).Return(nil).Once()
+
+		result, err := adminUseCase.RequirePasswordChange(context.Background(), 6)
+		require.Nil(t, err)
+		require.True(t, result.PasswordChangeRequired)
+		repositoryMock.Mock.AssertCalled(t, "UpdatePasswordChangeRequired", 6, true)
+	})
+
+	t.Run("Update user status", func(t *testing.T) {
+		t.Run("Invalid status", func(t *testing.T) {
+			request := &models.UpdateUserStatusRequest{Status: "banned"}
+			result, err := adminUseCase.UpdateUserStatus(context.Background(), 1, request)
+			require.Equal(t, 400, err.(*models.ErrorResponse).Code)
+			require.Nil(t, result)
+		})
+
+		t.Run("Expiry is only allowed for suspension", func(t *testing.T) {
+			expiresAt := time.Now().Add(time.Hour)
+			request := &models.UpdateUserStatusRequest{Status: entity.UserStatusDisabled, ExpiresAt: &expiresAt}
+			result, err := adminUseCase.UpdateUserStatus(context.Background(), 1, request)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "ExpiresAt is only allowed for suspended status", Status: "Bad Request"}, err)
+			require.Nil(t, result)
+		})
+
+		t.Run("Suspending should revoke sessions", func(t *testing.T) {
+			user := &entity.User{Id: 5, Name: "danar", Email: "danar@gmail.com", Status: entity.UserStatusActive}
+			repositoryMock.Mock.On("FindOneById", 5).Return(user).Once()
+			repositoryMock.Mock.On("UpdateStatus", user).Return(nil).Once()
+			sessionRepositoryMock.Mock.On("RevokeAllByUserId", 5).Return(nil).Once()
+
+			expiresAt := time.Now().Add(time.Hour)
+			request := &models.UpdateUserStatusRequest{Status: entity.UserStatusSuspended, Reason: "spam", ExpiresAt: &expiresAt}
+			result, err := adminUseCase.UpdateUserStatus(context.Background(), 5, request)
+			require.Nil(t, err)
+			require.Equal(t, entity.UserStatusSuspended, result.Status)
+			require.Equal(t, "spam", result.StatusReason)
+			require.NotEmpty(t, result.StatusExpiresAt)
+			sessionRepositoryMock.Mock.AssertCalled(t, "RevokeAllByUserId", 5)
+		})
+
+		t.Run("Reactivating should keep sessions", func(t *testing.T) {
+			user := &entity.User{Id: 6, Name: "danar", Email: "danar@gmail.com", Status: entity.UserStatusDisabled}
+			repositoryMock.Mock.On("FindOneById", 6).Return(user).Once()
+			repositoryMock.Mock.On("UpdateStatus", user).Return(nil).Once()
+
+			request := &models.UpdateUserStatusRequest{Status: entity.UserStatusActive}
+			result, err := adminUseCase.UpdateUserStatus(context.Background(), 6, request)
+			require.Nil(t, err)
+			require.Equal(t, entity.UserStatusActive, result.Status)
+			sessionRepositoryMock.Mock.AssertNotCalled(t, "RevokeAllByUserId", 6)
+		})
+	})
+
+	t.Run("Purge users whose grace period is over", func(t *testing.T) {
+		gracePeriod := time.Duration(viper.GetInt("account.deletion.grace_period_days")) * 24 * time.Hour
+		repositoryMock.Mock.On("DeleteSoftDeletedBefore", mock.MatchedBy(func(before time.Time) bool {
+			return time.Since(before.Add(gracePeriod)) < time.Minute
+		})).Return(int64(3), nil).Once()
+
+		purged, err := adminUseCase.PurgeDeletedUsers(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, int64(3), purged)
+	})
+}
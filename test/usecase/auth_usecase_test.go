@@ -3,21 +3,34 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"golang-authentication/internal/config"
 	"golang-authentication/internal/entity"
 	"golang-authentication/internal/models"
 	"golang-authentication/internal/usecase"
 	"golang-authentication/test/mocks"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestAuthUseCase(t *testing.T) {
 	viper := config.NewViper("./../../")
+	//keep the legacy hash of the fixtures current, the upgrade is covered by TestAuthUseCaseRehash
+	viper.Set("password.hashing.algorithm", "bcrypt")
+	viper.Set("key.pepper.current_version", 0)
 	validator := config.NewValidator()
 	repositoryMock := mocks.NewUserRepositoryMock()
-	authUseCase := usecase.NewAuthUseCase(repositoryMock, validator, viper)
+	repositoryMock.Mock.On("FindOneByExactEmail", mock.Anything).Return(nil)
+	repositoryMock.Mock.On("UpdatePassword", mock.Anything, mock.Anything).Return(nil)
+	repositoryMock.Mock.On("UpdateLastLoginAt", mock.Anything, mock.Anything).Return(nil)
+	sessionRepositoryMock := mocks.NewSessionRepositoryMock()
+	loginAttemptRepositoryMock := newLoginAttemptRepositoryMock()
+	authUseCase := usecase.NewAuthUseCase(repositoryMock, sessionRepositoryMock, loginAttemptRepositoryMock, mocks.NewTrustedDeviceRepositoryMock(), validator, viper)
+	repositoryMock.Mock.On("UpdateSignInAttempts", mock.Anything).Return(nil)
+	countFailedSignIns(repositoryMock)
 
 	t.Run("Validate request", func(t *testing.T) {
 		t.Run("Sign in with empty email", func(t *testing.T) {
@@ -92,10 +105,29 @@ func TestAuthUseCase(t *testing.T) {
 
 	})
 
+	t.Run("Validate user by username", func(t *testing.T) {
+		username := "danar"
+		user := &entity.User{
+			Id:       1,
+			Username: &username,
+			Email:    "danar@gmail.com",
+			Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS",
+		}
+		model := &models.SignInRequest{
+			Identifier: "Danar",
+			Password:   "12345678",
+		}
+
+		repositoryMock.Mock.On("FindOneByUsername", "danar").Return(user)
+		result, err := authUseCase.GetAndValidateUser(context.Background(), model)
+		require.Nil(t, err)
+		require.Equal(t, user, result)
+	})
+
 	t.Run("Token", func(t *testing.T) {
 		t.Run("Generate access token", func(t *testing.T) {
 			const userID = 1
-			accessToken, err := authUseCase.GenerateAccessToken(userID)
+			accessToken, err := authUseCase.GenerateAccessToken(&entity.Session{UserId: userID})
 			require.Nil(t, err)
 			require.NotNil(t, accessToken)
 
@@ -107,7 +139,7 @@ func TestAuthUseCase(t *testing.T) {
 			go func() {
 				defer wg.Done()
 				const userID = 1
-				accessToken, err := authUseCase.GenerateAccessToken(userID)
+				accessToken, err := authUseCase.GenerateAccessToken(&entity.Session{UserId: userID})
 				require.Nil(t, err)
 				require.NotNil(t, accessToken)
 			}()
@@ -115,7 +147,7 @@ func TestAuthUseCase(t *testing.T) {
 			go func() {
 				defer wg.Done()
 				const userID = 1
-				refreshToken, err := authUseCase.GenerateRefreshToken(userID)
+				refreshToken, err := authUseCase.GenerateRefreshToken(userID, "session")
 				require.Nil(t, err)
 				require.NotNil(t, refreshToken)
 			}()
@@ -127,7 +159,7 @@ func TestAuthUseCase(t *testing.T) {
 		t.Run("Verify refresh token should not return an error", func(t *testing.T) {
 			refreshTokenKey := viper.GetString("key.token.refresh")
 			require.NotNil(t, refreshTokenKey)
-			refreshToken, err := authUseCase.GenerateRefreshToken(2)
+			refreshToken, err := authUseCase.GenerateRefreshToken(2, "session")
 
 			require.Nil(t, err)
 			require.NotNil(t, refreshToken)
@@ -138,15 +170,89 @@ func TestAuthUseCase(t *testing.T) {
 			require.Nil(t, err)
 		})
 		t.Run("Should generate new access token", func(t *testing.T) {
-			refreshToken, err := authUseCase.GenerateRefreshToken(2)
+			session := &entity.Session{Id: "active-session", UserId: 2, ExpiresAt: time.Now().Add(time.Hour)}
+			sessionRepositoryMock.Mock.On("FindOneById", session.Id).Return(session)
+			repositoryMock.Mock.On("FindOneById", 2).Return(&entity.User{Id: 2, Status: entity.UserStatusActive})
+			refreshToken, err := authUseCase.GenerateRefreshToken(2, session.Id)
 
 			require.Nil(t, err)
 			require.NotNil(t, refreshToken)
 
-			result, err := authUseCase.GetToken(refreshToken)
+			result, err := authUseCase.GetToken(context.Background(), refreshToken)
 			require.Nil(t, err)
 			require.NotNil(t, result.AccessToken)
 		})
+
+		t.Run("Should keep the authentication of the session in a refreshed access token", func(t *testing.T) {
+			authTime := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+			session := &entity.Session{Id: "mfa-session", UserId: 2, AuthTime: authTime, Amr: "pwd otp mfa", Acr: usecase.AcrMultiFactor, ExpiresAt: time.Now().Add(time.Hour)}
+			sessionRepositoryMock.Mock.On("FindOneById", session.Id).Return(session)
+			refreshToken, err := authUseCase.GenerateRefreshToken(2, session.Id)
+			require.Nil(t, err)
+
+			result, err := authUseCase.GetToken(context.Background(), refreshToken)
+			require.Nil(t, err)
+			claims, err := authUseCase.VerifyAccessClaims(result.AccessToken)
+			require.Nil(t, err)
+			require.Equal(t, &usecase.AccessClaims{UserId: 2, SessionId: "mfa-session", AuthTime: authTime, Amr: []string{"pwd", "otp", "mfa"}, Acr: usecase.AcrMultiFactor}, claims)
+
+			require.NotNil(t, authUseCase.CheckStepUp(claims, 10*time.Minute, ""))
+			require.Nil(t, authUseCase.CheckStepUp(claims, 3*time.Hour, usecase.AcrMultiFactor))
+			require.Nil(t, authUseCase.CheckStepUp(claims, 0, usecase.AcrSingleFactor))
+			claims.Acr = usecase.AcrSingleFactor
+			require.Equal(t, &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Please authenticate again to continue"}, authUseCase.CheckStepUp(claims, 0, usecase.AcrMultiFactor))
+		})
+
+		t.Run("Should require two factors once mfa is enabled", func(t *testing.T) {
+			claims := &usecase.AccessClaims{UserId: 8, SessionId: "single-factor-session", Acr: usecase.AcrSingleFactor}
+			repositoryMock.Mock.On("FindOneById", 8).Return(&entity.User{Id: 8}).Once()
+			require.Nil(t, authUseCase.CheckMfaStepUp(context.Background(), claims))
+
+			repositoryMock.Mock.On("FindOneById", 8).Return(&entity.User{Id: 8, MfaEnabled: true}).Once()
+			err := authUseCase.CheckMfaStepUp(context.Background(), claims)
+			require.Equal(t, &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Please authenticate again to continue"}, err)
+
+			claims.Acr = usecase.AcrMultiFactor
+			repositoryMock.Mock.On("FindOneById", 8).Return(&entity.User{Id: 8, MfaEnabled: true}).Once()
+			require.Nil(t, authUseCase.CheckMfaStepUp(context.Background(), claims))
+		})
+
+		t.Run("Should not generate access token for a revoked session", func(t *testing.T) {
+			revokedAt := time.Now()
+			session := &entity.Session{Id: "revoked-session", UserId: 2, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}
+			sessionRepositoryMock.Mock.On("FindOneById", session.Id).Return(session)
+			refreshToken, err := authUseCase.GenerateRefreshToken(2, session.Id)
+			require.Nil(t, err)
+
+			result, err := authUseCase.GetToken(context.Background(), refreshToken)
+			require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+			require.Nil(t, result)
+		})
+
+		t.Run("Should not generate access token for a suspended user", func(t *testing.T) {
+			session := &entity.Session{Id: "suspended-session", UserId: 3, ExpiresAt: time.Now().Add(time.Hour)}
+			sessionRepositoryMock.Mock.On("FindOneById", session.Id).Return(session)
+			repositoryMock.Mock.On("FindOneById", 3).Return(&entity.User{Id: 3, Status: entity.UserStatusSuspended})
+			refreshToken, err := authUseCase.GenerateRefreshToken(3, session.Id)
+			require.Nil(t, err)
+
+			result, err := authUseCase.GetToken(context.Background(), refreshToken)
+			require.Equal(t, &models.ErrorResponse{Code: 403, Message: "Account is suspended", Status: "Forbidden"}, err)
+			require.Nil(t, result)
+		})
+
+		t.Run("Should check the session and the user of an access token", func(t *testing.T) {
+			require.Nil(t, authUseCase.CheckSession(context.Background(), &usecase.AccessClaims{UserId: 2, SessionId: "active-session"}))
+
+			err := authUseCase.CheckSession(context.Background(), &usecase.AccessClaims{UserId: 2, SessionId: "revoked-session"})
+			require.Equal(t, &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Session expired. Please sign in again"}, err)
+
+			err = authUseCase.CheckSession(context.Background(), &usecase.AccessClaims{UserId: 3, SessionId: "active-session"})
+			require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+
+			err = authUseCase.CheckSession(context.Background(), &usecase.AccessClaims{UserId: 3, SessionId: "suspended-session"})
+			require.Equal(t, &models.ErrorResponse{Code: 403, Message: "Account is suspended", Status: "Forbidden"}, err)
+		})
 	})
 
 	t.Run("Should return access token and refresh token after sign in", func(t *testing.T) {
@@ -161,9 +267,348 @@ func TestAuthUseCase(t *testing.T) {
 		}
 
 		repositoryMock.Mock.On("FindOneByEmail", model.Email).Return(user)
+		sessionRepositoryMock.Mock.On("Save", mock.AnythingOfType("*entity.Session")).Return(&entity.Session{Id: "new-session", UserId: 1})
 		response, err := authUseCase.SignIn(context.Background(), model)
 		require.Nil(t, err)
 		require.NotNil(t, response)
+		repositoryMock.Mock.AssertCalled(t, "UpdateLastLoginAt", 1, mock.Anything)
+		loginAttemptRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(attempt *entity.LoginAttempt) bool {
+			return *attempt.UserId == 1 && attempt.Outcome == entity.LoginOutcomeSuccess
+		}))
+		sessionRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(session *entity.Session) bool {
+			return session.Amr == "pwd" && session.Acr == usecase.AcrSingleFactor && time.Since(session.AuthTime) < time.Minute
+		}))
+
+	})
+
+	t.Run("Should refuse to sign in a disabled user", func(t *testing.T) {
+		user := &entity.User{
+			Id:       4,
+			Email:    "disabled@gmail.com",
+			Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS",
+			Status:   entity.UserStatusDisabled,
+		}
+		model := &models.SignInRequest{
+			Email:    "disabled@gmail.com",
+			Password: "12345678",
+		}
+
+		repositoryMock.Mock.On("FindOneByEmail", model.Email).Return(user)
+		response, err := authUseCase.SignIn(context.Background(), model)
+		require.Equal(t, &models.ErrorResponse{Code: 403, Message: "Account is disabled", Status: "Forbidden"}, err)
+		require.Nil(t, response)
+		loginAttemptRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(attempt *entity.LoginAttempt) bool {
+			return *attempt.UserId == 4 && attempt.Outcome == entity.LoginOutcomeDisabled
+		}))
+	})
+
+	t.Run("Should record a failed sign in with the client", func(t *testing.T) {
+		model := &models.SignInRequest{
+			Identifier: "unknown@gmail.com",
+			Password:   "12345678",
+			ClientInfo: models.ClientInfo{IpAddress: "10.0.0.1", UserAgent: "curl/8.0"},
+		}
+
+		repositoryMock.Mock.On("FindOneByEmail", model.Identifier).Return(nil)
+		_, err := authUseCase.SignIn(context.Background(), model)
+		require.NotNil(t, err)
+		loginAttemptRepositoryMock.Mock.AssertCalled(t, "Save", &entity.LoginAttempt{
+			Identifier: "unknown@gmail.com",
+			IpAddress:  "10.0.0.1",
+			UserAgent:  "curl/8.0",
+			Outcome:    entity.LoginOutcomeUnknownUser,
+		})
+	})
+}
+
+func newLoginAttemptRepositoryMock() *mocks.LoginAttemptRepositoryMock {
+	loginAttemptRepositoryMock := mocks.NewLoginAttemptRepositoryMock()
+	loginAttemptRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+	return loginAttemptRepositoryMock
+}
+
+// countFailedSignIns lets the mock count the failed attempts on the user like the database does
+func countFailedSignIns(repositoryMock *mocks.UserRepositoryMock) {
+	repositoryMock.Mock.On("IncrementFailedSignInAttempts", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*entity.User).FailedSignInAttempts++
+	}).Return(nil)
+	repositoryMock.Mock.On("Lock", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+}
+
+func TestAuthUseCaseCollidingEmail(t *testing.T) {
+	viper := config.NewViper("./../../")
+	validator := config.NewValidator()
+	repositoryMock := mocks.NewUserRepositoryMock()
+	authUseCase := usecase.NewAuthUseCase(repositoryMock, mocks.NewSessionRepositoryMock(), newLoginAttemptRepositoryMock(), mocks.NewTrustedDeviceRepositoryMock(), validator, viper)
+
+	//j.doe@gmail.com kept the canonical email of older rules, jdoe@gmail.com already has the current one
+	stale := &entity.User{Id: 1, Email: "j.doe@gmail.com", EmailCanonical: "j.doe@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+	current := &entity.User{Id: 2, Email: "jdoe@gmail.com", EmailCanonical: "jdoe@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+	repositoryMock.Mock.On("FindOneByEmail", "jdoe@gmail.com").Return(current)
+	repositoryMock.Mock.On("FindOneByExactEmail", "j.doe@gmail.com").Return(stale)
+	repositoryMock.Mock.On("FindOneByExactEmail", "j.d.o.e@gmail.com").Return(nil)
+	repositoryMock.Mock.On("UpdatePassword", mock.Anything, mock.Anything).Return(nil)
+
+	t.Run("Should find the user by the email as stored", func(t *testing.T) {
+		user, err := authUseCase.GetAndValidateUser(context.Background(), &models.SignInRequest{Identifier: "j.doe@gmail.com", Password: "12345678"})
+		require.Nil(t, err)
+		require.Equal(t, 1, user.Id)
+	})
+
+	t.Run("Should keep the canonical match otherwise", func(t *testing.T) {
+		user, err := authUseCase.GetAndValidateUser(context.Background(), &models.SignInRequest{Identifier: "jdoe@gmail.com", Password: "12345678"})
+		require.Nil(t, err)
+		require.Equal(t, 2, user.Id)
+
+		user, err = authUseCase.GetAndValidateUser(context.Background(), &models.SignInRequest{Identifier: "j.d.o.e@gmail.com", Password: "12345678"})
+		require.Nil(t, err)
+		require.Equal(t, 2, user.Id)
+		repositoryMock.Mock.AssertNotCalled(t, "FindOneByExactEmail", "jdoe@gmail.com")
+	})
+}
+
+func TestAuthUseCaseLockout(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("security.lockout.max_attempts", 3)
+	viper.Set("security.lockout.base_duration_seconds", 60)
+	viper.Set("security.lockout.max_duration_seconds", 150)
+	viper.Set("security.lockout.reveal", false)
+	validator := config.NewValidator()
+
+	wrongPassword := &models.SignInRequest{Email: "danar@gmail.com", Password: "1234567890"}
+	correctPassword := &models.SignInRequest{Email: "danar@gmail.com", Password: "12345678"}
+	invalidCredential := &models.ErrorResponse{Code: 400, Message: "Email or password invalid", Status: "Bad Request"}
+
+	setup := func(user *entity.User) *usecase.AuthUseCase {
+		repositoryMock := mocks.NewUserRepositoryMock()
+		repositoryMock.Mock.On("FindOneByEmail", user.Email).Return(user)
+		repositoryMock.Mock.On("UpdatePassword", mock.Anything, mock.Anything).Return(nil)
+		repositoryMock.Mock.On("UpdateSignInAttempts", user).Return(nil)
+		countFailedSignIns(repositoryMock)
+		return usecase.NewAuthUseCase(repositoryMock, mocks.NewSessionRepositoryMock(), newLoginAttemptRepositoryMock(), mocks.NewTrustedDeviceRepositoryMock(), validator, viper)
+	}
 
+	t.Run("Should lock the account after max failed attempts", func(t *testing.T) {
+		user := &entity.User{Id: 1, Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+		authUseCase := setup(user)
+
+		for i := 0; i < 3; i++ {
+			_, err := authUseCase.GetAndValidateUser(context.Background(), wrongPassword)
+			require.Equal(t, invalidCredential, err)
+		}
+		require.True(t, user.IsLocked(time.Now()))
+		require.Equal(t, 1, user.LockoutCount)
+		require.WithinDuration(t, time.Now().Add(60*time.Second), *user.LockedUntil, 5*time.Second)
+
+		//the correct password is refused while locked, without revealing the lock
+		result, err := authUseCase.GetAndValidateUser(context.Background(), correctPassword)
+		require.Equal(t, invalidCredential, err)
+		require.Nil(t, result)
+	})
+
+	t.Run("Should lock once when attempts race", func(t *testing.T) {
+		user := &entity.User{Id: 1, Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+		repositoryMock := mocks.NewUserRepositoryMock()
+		repositoryMock.Mock.On("FindOneByEmail", user.Email).Return(user)
+		//another attempt counted meanwhile, and already locked the account
+		repositoryMock.Mock.On("IncrementFailedSignInAttempts", user).Run(func(args mock.Arguments) {
+			user.FailedSignInAttempts = 4
+		}).Return(nil)
+		repositoryMock.Mock.On("Lock", 1, 3, mock.Anything).Return(false, nil).Once()
+		authUseCase := usecase.NewAuthUseCase(repositoryMock, mocks.NewSessionRepositoryMock(), newLoginAttemptRepositoryMock(), mocks.NewTrustedDeviceRepositoryMock(), validator, viper)
+
+		_, err := authUseCase.GetAndValidateUser(context.Background(), wrongPassword)
+		require.Equal(t, invalidCredential, err)
+		require.Equal(t, 0, user.LockoutCount)
+		require.Nil(t, user.LockedUntil)
+		repositoryMock.Mock.AssertNotCalled(t, "UpdateSignInAttempts", mock.Anything)
+	})
+
+	t.Run("Lock duration should grow exponentially up to the maximum", func(t *testing.T) {
+		user := &entity.User{Id: 1, Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS", LockoutCount: 1, FailedSignInAttempts: 2}
+		authUseCase := setup(user)
+
+		_, err := authUseCase.GetAndValidateUser(context.Background(), wrongPassword)
+		require.Equal(t, invalidCredential, err)
+		require.Equal(t, 2, user.LockoutCount)
+		require.WithinDuration(t, time.Now().Add(120*time.Second), *user.LockedUntil, 5*time.Second)
+
+		user.LockedUntil = nil
+		user.FailedSignInAttempts = 2
+		_, err = authUseCase.GetAndValidateUser(context.Background(), wrongPassword)
+		require.Equal(t, invalidCredential, err)
+		require.Equal(t, 3, user.LockoutCount)
+		require.WithinDuration(t, time.Now().Add(150*time.Second), *user.LockedUntil, 5*time.Second)
+	})
+
+	t.Run("Should reset the counter after successful sign in", func(t *testing.T) {
+		expired := time.Now().Add(-time.Minute)
+		user := &entity.User{Id: 1, Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS", LockoutCount: 2, FailedSignInAttempts: 1, LockedUntil: &expired}
+		authUseCase := setup(user)
+
+		result, err := authUseCase.GetAndValidateUser(context.Background(), correctPassword)
+		require.Nil(t, err)
+		require.Equal(t, 0, result.FailedSignInAttempts)
+		require.Equal(t, 0, result.LockoutCount)
+		require.Nil(t, result.LockedUntil)
+	})
+
+	t.Run("Should reveal the lock when configured", func(t *testing.T) {
+		lockedUntil := time.Now().Add(time.Minute)
+		user := &entity.User{Id: 1, Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS", LockedUntil: &lockedUntil}
+		authUseCase := setup(user)
+		viper.Set("security.lockout.reveal", true)
+		defer viper.Set("security.lockout.reveal", false)
+
+		_, err := authUseCase.GetAndValidateUser(context.Background(), correctPassword)
+		require.Equal(t, 423, err.(*models.ErrorResponse).Code)
+	})
+}
+
+func TestAuthUseCaseBreachedPassword(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("password.breach.flag_on_sign_in", true)
+	validator := config.NewValidator()
+	repositoryMock := mocks.NewUserRepositoryMock()
+	repositoryMock.Mock.On("FindOneByExactEmail", mock.Anything).Return(nil)
+	repositoryMock.Mock.On("UpdatePassword", mock.Anything, mock.Anything).Return(nil)
+	sessionRepositoryMock := mocks.NewSessionRepositoryMock()
+	authUseCase := usecase.NewAuthUseCase(repositoryMock, sessionRepositoryMock, newLoginAttemptRepositoryMock(), mocks.NewTrustedDeviceRepositoryMock(), validator, viper)
+	authUseCase.PasswordUseCase.BreachChecker = breachChecker{"12345678": true}
+
+	t.Run("Should flag a breached password on sign in", func(t *testing.T) {
+		user := &entity.User{
+			Id:       7,
+			Email:    "danar@gmail.com",
+			Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS",
+		}
+		model := &models.SignInRequest{Identifier: "danar@gmail.com", Password: "12345678"}
+		repositoryMock.Mock.On("FindOneByEmail", "danar@gmail.com").Return(user)
+		repositoryMock.Mock.On("UpdatePasswordChangeRequired", 7, true).Return(nil).Once()
+
+		response, err := authUseCase.SignIn(context.Background(), model)
+		require.Nil(t, err)
+		require.True(t, response.PasswordChangeRequired)
+		require.Equal(t, usecase.PasswordChangeReasonRequired, response.PasswordChangeReason)
+		require.NotEmpty(t, response.PasswordChangeToken)
+		require.Empty(t, response.AccessToken)
+		require.Empty(t, response.RefreshToken)
+		repositoryMock.Mock.AssertCalled(t, "UpdatePasswordChangeRequired", 7, true)
+		sessionRepositoryMock.Mock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+}
+
+func TestAuthUseCasePasswordChange(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("password.expiry.max_age_days", 90)
+	validator := config.NewValidator()
+	repositoryMock := mocks.NewUserRepositoryMock()
+	repositoryMock.Mock.On("FindOneByExactEmail", mock.Anything).Return(nil)
+	repositoryMock.Mock.On("UpdatePassword", mock.Anything, mock.Anything).Return(nil)
+	sessionRepositoryMock := mocks.NewSessionRepositoryMock()
+	authUseCase := usecase.NewAuthUseCase(repositoryMock, sessionRepositoryMock, newLoginAttemptRepositoryMock(), mocks.NewTrustedDeviceRepositoryMock(), validator, viper)
+
+	t.Run("Should return a password change token when the password is expired", func(t *testing.T) {
+		passwordChangedAt := time.Now().Add(-91 * 24 * time.Hour)
+		user := &entity.User{
+			Id:                8,
+			Email:             "expired@gmail.com",
+			Password:          "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS",
+			PasswordChangedAt: &passwordChangedAt,
+		}
+		repositoryMock.Mock.On("FindOneByEmail", "expired@gmail.com").Return(user)
+
+		model := &models.SignInRequest{Identifier: "expired@gmail.com", Password: "12345678"}
+		response, err := authUseCase.SignIn(context.Background(), model)
+		require.Nil(t, err)
+		require.Equal(t, usecase.PasswordChangeReasonExpired, response.PasswordChangeReason)
+		require.Empty(t, response.RefreshToken)
+		sessionRepositoryMock.Mock.AssertNotCalled(t, "Save", mock.Anything)
+
+		t.Run("The token only grants access to the password change", func(t *testing.T) {
+			_, err := authUseCase.VerifyAccessToken(response.PasswordChangeToken)
+			require.Equal(t, 403, err.(*models.ErrorResponse).Code)
+
+			repositoryMock.Mock.On("FindOneById", 8).Return(user).Once()
+			userID, err := authUseCase.VerifyPasswordChangeAccess(context.Background(), response.PasswordChangeToken)
+			require.Nil(t, err)
+			require.Equal(t, 8, userID)
+		})
+
+		t.Run("The token is refused once the user is suspended", func(t *testing.T) {
+			suspended := *user
+			suspended.Status = entity.UserStatusSuspended
+			repositoryMock.Mock.On("FindOneById", 8).Return(&suspended).Once()
+
+			_, err := authUseCase.VerifyPasswordChangeAccess(context.Background(), response.PasswordChangeToken)
+			require.Equal(t, &models.ErrorResponse{Code: 403, Message: "Account is suspended", Status: "Forbidden"}, err)
+		})
+	})
+
+	t.Run("Should count the password age from the creation of the user", func(t *testing.T) {
+		user := &entity.User{CreatedAt: time.Now().Add(-100 * 24 * time.Hour)}
+		require.True(t, user.IsPasswordExpired(time.Now(), 90*24*time.Hour))
+		require.False(t, user.IsPasswordExpired(time.Now(), 0))
+	})
+
+	t.Run("A regular access token also grants access to the password change", func(t *testing.T) {
+		session := &entity.Session{Id: "password-change-session", UserId: 9, ExpiresAt: time.Now().Add(time.Hour)}
+		sessionRepositoryMock.Mock.On("FindOneById", session.Id).Return(session)
+		repositoryMock.Mock.On("FindOneById", 9).Return(&entity.User{Id: 9, Status: entity.UserStatusActive})
+		accessToken, err := authUseCase.GenerateAccessToken(session)
+		require.Nil(t, err)
+
+		userID, err := authUseCase.VerifyPasswordChangeAccess(context.Background(), accessToken)
+		require.Nil(t, err)
+		require.Equal(t, 9, userID)
+	})
+
+	t.Run("A regular access token of a revoked session is refused", func(t *testing.T) {
+		revokedAt := time.Now()
+		session := &entity.Session{Id: "revoked-password-change-session", UserId: 9, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}
+		sessionRepositoryMock.Mock.On("FindOneById", session.Id).Return(session)
+		accessToken, err := authUseCase.GenerateAccessToken(session)
+		require.Nil(t, err)
+
+		_, err = authUseCase.VerifyPasswordChangeAccess(context.Background(), accessToken)
+		require.Equal(t, &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Session expired. Please sign in again"}, err)
+	})
+}
+
+func TestAuthUseCaseRehash(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("password.hashing.algorithm", "argon2id")
+	validator := config.NewValidator()
+	repositoryMock := mocks.NewUserRepositoryMock()
+	repositoryMock.Mock.On("FindOneByExactEmail", mock.Anything).Return(nil)
+	authUseCase := usecase.NewAuthUseCase(repositoryMock, mocks.NewSessionRepositoryMock(), newLoginAttemptRepositoryMock(), mocks.NewTrustedDeviceRepositoryMock(), validator, viper)
+
+	t.Run("Should upgrade an unpeppered bcrypt hash to a peppered argon2id hash", func(t *testing.T) {
+		user := &entity.User{
+			Id:       11,
+			Email:    "legacy@gmail.com",
+			Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS",
+		}
+		model := &models.SignInRequest{Identifier: "legacy@gmail.com", Password: "12345678"}
+		repositoryMock.Mock.On("FindOneByEmail", "legacy@gmail.com").Return(user).Once()
+		repositoryMock.Mock.On("UpdatePassword", 11, mock.Anything).Return(nil).Once()
+
+		result, err := authUseCase.GetAndValidateUser(context.Background(), model)
+		require.Nil(t, err)
+		require.True(t, strings.HasPrefix(result.Password, "$pepper$v=1$argon2id$"))
+		require.True(t, authUseCase.PasswordUseCase.ComparePassword(result.Password, "12345678"))
+		repositoryMock.Mock.AssertCalled(t, "UpdatePassword", 11, result.Password)
+	})
+
+	t.Run("Should keep a hash with the current parameters", func(t *testing.T) {
+		hashedPassword, err := authUseCase.PasswordUseCase.HashPassword("12345678")
+		require.Nil(t, err)
+		user := &entity.User{Id: 12, Email: "current@gmail.com", Password: hashedPassword}
+		model := &models.SignInRequest{Identifier: "current@gmail.com", Password: "12345678"}
+		repositoryMock.Mock.On("FindOneByEmail", "current@gmail.com").Return(user).Once()
+
+		_, err = authUseCase.GetAndValidateUser(context.Background(), model)
+		require.Nil(t, err)
+		repositoryMock.Mock.AssertNotCalled(t, "UpdatePassword", 12, mock.Anything)
 	})
 }
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDataExportUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("export.directory", t.TempDir())
+
+	setup := func() (*usecase.DataExportUseCase, *mocks.UserRepositoryMock, *mocks.SessionRepositoryMock, *mocks.ProductRepositoryMock, *mocks.DataExportRepositoryMock) {
+		userRepositoryMock := mocks.NewUserRepositoryMock()
+		sessionRepositoryMock := mocks.NewSessionRepositoryMock()
+		productRepositoryMock := mocks.NewProductRepositoryMock()
+		loginAttemptRepositoryMock := mocks.NewLoginAttemptRepositoryMock()
+		loginAttemptRepositoryMock.Mock.On("FindAllByUserId", 1, 0, 500).Return([]*entity.LoginAttempt{{Id: 8, Outcome: entity.LoginOutcomeSuccess}}, int64(1))
+		dataExportRepositoryMock := mocks.NewDataExportRepositoryMock()
+		dataExportUseCase := usecase.NewDataExportUseCase(userRepositoryMock, sessionRepositoryMock, productRepositoryMock, loginAttemptRepositoryMock, dataExportRepositoryMock, viper)
+		return dataExportUseCase, userRepositoryMock, sessionRepositoryMock, productRepositoryMock, dataExportRepositoryMock
+	}
+
+	completedExport := func(t *testing.T) *entity.DataExport {
+		filePath := viper.GetString("export.directory") + "/export.json"
+		require.Nil(t, os.WriteFile(filePath, []byte("{}"), 0o600))
+		expiresAt := time.Now().Add(time.Hour)
+		return &entity.DataExport{Id: 3, UserId: 1, Status: entity.DataExportStatusCompleted, FilePath: filePath, ExpiresAt: &expiresAt}
+	}
+
+	t.Run("Should return the export in progress instead of queueing another", func(t *testing.T) {
+		dataExportUseCase, _, _, _, dataExportRepositoryMock := setup()
+		dataExportRepositoryMock.Mock.On("FindUnfinishedByUserId", 1).Return(&entity.DataExport{Id: 2, UserId: 1, Status: entity.DataExportStatusPending})
+
+		result, err := dataExportUseCase.RequestExport(context.Background(), 1)
+		require.Nil(t, err)
+		require.Equal(t, 2, result.Id)
+		dataExportRepositoryMock.Mock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("Should queue a new export", func(t *testing.T) {
+		dataExportUseCase, _, _, _, dataExportRepositoryMock := setup()
+		dataExportRepositoryMock.Mock.On("FindUnfinishedByUserId", 1).Return(nil)
+		dataExportRepositoryMock.Mock.On("Save", mock.Anything).Return(&entity.DataExport{Id: 4, UserId: 1, Status: entity.DataExportStatusPending})
+
+		result, err := dataExportUseCase.RequestExport(context.Background(), 1)
+		require.Nil(t, err)
+		require.Equal(t, entity.DataExportStatusPending, result.Status)
+		require.Empty(t, result.DownloadUrl)
+	})
+
+	t.Run("Should hide the exports of other users", func(t *testing.T) {
+		dataExportUseCase, _, _, _, dataExportRepositoryMock := setup()
+		dataExportRepositoryMock.Mock.On("FindOneById", 3).Return(completedExport(t))
+
+		result, err := dataExportUseCase.GetExport(context.Background(), 2, 3)
+		require.Equal(t, &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Export not found"}, err)
+		require.Nil(t, result)
+	})
+
+	t.Run("Should generate the archive", func(t *testing.T) {
+		dataExportUseCase, userRepositoryMock, sessionRepositoryMock, productRepositoryMock, dataExportRepositoryMock := setup()
+		export := &entity.DataExport{Id: 5, UserId: 1, Status: entity.DataExportStatusRunning}
+		dataExportRepositoryMock.Mock.On("ClaimNext").Return(export).Once()
+		dataExportRepositoryMock.Mock.On("ClaimNext").Return(nil)
+		dataExportRepositoryMock.Mock.On("Update", mock.Anything).Return(nil)
+		userRepositoryMock.Mock.On("FindOneById", 1).Return(&entity.User{Id: 1, Name: "Danar", Email: "danar@gmail.com"})
+		sessionRepositoryMock.Mock.On("FindAllByUserId", 1).Return([]*entity.Session{{IpAddress: "127.0.0.1", UserAgent: "curl"}})
+		productRepositoryMock.Mock.On("FindAllByUserId", 1).Return([]*entity.Product{{Id: 7, Name: "Book", Stock: 2, Price: 10}})
+
+		processed, err := dataExportUseCase.ProcessPendingExports(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, 1, processed)
+		require.Equal(t, entity.DataExportStatusCompleted, export.Status)
+		require.NotNil(t, export.ExpiresAt)
+
+		content, err := os.ReadFile(export.FilePath)
+		require.Nil(t, err)
+		archive := new(models.DataExportArchive)
+		require.Nil(t, json.Unmarshal(content, archive))
+		require.Equal(t, "danar@gmail.com", archive.Profile.Email)
+		require.Equal(t, "curl", archive.Sessions[0].UserAgent)
+		require.Equal(t, "Book", archive.Products[0].Name)
+		require.Equal(t, entity.LoginOutcomeSuccess, archive.LoginHistory[0].Outcome)
+	})
+
+	t.Run("Should only download through a valid signed link", func(t *testing.T) {
+		dataExportUseCase, _, _, _, dataExportRepositoryMock := setup()
+		export := completedExport(t)
+		dataExportRepositoryMock.Mock.On("FindOneById", 3).Return(export)
+
+		result, err := dataExportUseCase.GetExport(context.Background(), 1, 3)
+		require.Nil(t, err)
+		link, err := url.Parse(result.DownloadUrl)
+		require.Nil(t, err)
+		require.Equal(t, "/exports/3/download", link.Path)
+		expires, err := strconv.ParseInt(link.Query().Get("expires"), 10, 64)
+		require.Nil(t, err)
+
+		filePath, err := dataExportUseCase.OpenDownload(context.Background(), 3, expires, link.Query().Get("signature"))
+		require.Nil(t, err)
+		require.Equal(t, export.FilePath, filePath)
+
+		_, err = dataExportUseCase.OpenDownload(context.Background(), 3, expires+60, link.Query().Get("signature"))
+		require.Equal(t, &models.ErrorResponse{Code: 403, Status: "Forbidden", Message: "Invalid download link"}, err)
+	})
+
+	t.Run("Should delete the expired archives", func(t *testing.T) {
+		dataExportUseCase, _, _, _, dataExportRepositoryMock := setup()
+		export := completedExport(t)
+		filePath := export.FilePath
+		dataExportRepositoryMock.Mock.On("FindExpiredBefore").Return([]*entity.DataExport{export})
+		dataExportRepositoryMock.Mock.On("Update", mock.Anything).Return(nil)
+
+		purged, err := dataExportUseCase.PurgeExpiredExports(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, 1, purged)
+		require.Equal(t, entity.DataExportStatusExpired, export.Status)
+		_, err = os.Stat(filePath)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Should delete the archives of purged users", func(t *testing.T) {
+		dataExportUseCase, _, _, _, dataExportRepositoryMock := setup()
+		directory := viper.GetString("export.directory")
+		orphan := filepath.Join(directory, "orphan.json")
+		kept := filepath.Join(directory, "kept.json")
+		recent := filepath.Join(directory, "recent.json")
+		old := time.Now().Add(-time.Hour)
+		for _, filePath := range []string{orphan, kept, recent} {
+			require.Nil(t, os.WriteFile(filePath, []byte("{}"), 0o600))
+		}
+		require.Nil(t, os.Chtimes(orphan, old, old))
+		require.Nil(t, os.Chtimes(kept, old, old))
+		dataExportRepositoryMock.Mock.On("FindExpiredBefore").Return(nil)
+		dataExportRepositoryMock.Mock.On("ExistsByFilePath", orphan).Return(false, nil)
+		dataExportRepositoryMock.Mock.On("ExistsByFilePath", kept).Return(true, nil)
+
+		purged, err := dataExportUseCase.PurgeExpiredExports(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, 1, purged)
+		_, err = os.Stat(orphan)
+		require.True(t, os.IsNotExist(err))
+		require.FileExists(t, kept)
+		require.FileExists(t, recent)
+		dataExportRepositoryMock.Mock.AssertNotCalled(t, "ExistsByFilePath", recent)
+	})
+}
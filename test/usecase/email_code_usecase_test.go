@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestEmailCodeUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("otp.email.enabled", true)
+	validator := config.NewValidator()
+	user := &entity.User{Id: 1, Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+	invalidCode := &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is invalid or expired"}
+
+	setup := func() (*usecase.EmailCodeUseCase, *mocks.OneTimeCodeRepositoryMock, *mocks.MailerMock) {
+		userRepositoryMock := mocks.NewUserRepositoryMock()
+		userRepositoryMock.Mock.On("FindOneByExactEmail", mock.Anything).Return(nil)
+		userRepositoryMock.Mock.On("FindOneByEmail", "danar@gmail.com").Return(user)
+		userRepositoryMock.Mock.On("FindOneByEmail", mock.Anything).Return(nil)
+		userRepositoryMock.Mock.On("UpdateLastLoginAt", mock.Anything, mock.Anything).Return(nil)
+		sessionRepositoryMock := mocks.NewSessionRepositoryMock()
+		sessionRepositoryMock.Mock.On("Save", mock.Anything).Return(&entity.Session{Id: "code-session", UserId: 1})
+		authUseCase := usecase.NewAuthUseCase(userRepositoryMock, sessionRepositoryMock, newLoginAttemptRepositoryMock(), mocks.NewTrustedDeviceRepositoryMock(), validator, viper)
+		oneTimeCodeRepositoryMock := mocks.NewOneTimeCodeRepositoryMock()
+		oneTimeCodeRepositoryMock.Mock.On("CountSince", 1, entity.OneTimeCodePurposeEmailLogin).Return(int64(0))
+		mailerMock := mocks.NewMailerMock()
+		return usecase.NewEmailCodeUseCase(authUseCase, userRepositoryMock, oneTimeCodeRepositoryMock, mailerMock, validator, viper), oneTimeCodeRepositoryMock, mailerMock
+	}
+
+	request := func(t *testing.T, emailCodeUseCase *usecase.EmailCodeUseCase, oneTimeCodeRepositoryMock *mocks.OneTimeCodeRepositoryMock, mailerMock *mocks.MailerMock) (*entity.OneTimeCode, string) {
+		var saved *entity.OneTimeCode
+		oneTimeCodeRepositoryMock.Mock.On("FindLatest", 1, entity.OneTimeCodePurposeEmailLogin).Return(nil).Once()
+		oneTimeCodeRepositoryMock.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*entity.OneTimeCode)
+			saved.Id = 3
+			saved.CreatedAt = time.Now()
+		}).Return(nil).Once()
+
+		err := emailCodeUseCase.RequestCode(context.Background(), &models.EmailCodeRequest{Email: "danar@gmail.com"})
+		require.Nil(t, err)
+		code := regexp.MustCompile(`\b\d{6}\b`).FindString(mailerMock.Last().Body)
+		require.NotEmpty(t, code)
+		require.NotContains(t, saved.CodeHash, code)
+		oneTimeCodeRepositoryMock.Mock.On("FindLatest", 1, entity.OneTimeCodePurposeEmailLogin).Return(saved)
+		return saved, code
+	}
+
+	t.Run("Should sign in with the emailed code", func(t *testing.T) {
+		emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock := setup()
+		_, code := request(t, emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock)
+		oneTimeCodeRepositoryMock.Mock.On("RegisterAttempt", 3).Return(true)
+		oneTimeCodeRepositoryMock.Mock.On("MarkUsed", 3).Return(true)
+
+		response, err := emailCodeUseCase.SignIn(context.Background(), &models.EmailCodeSignInRequest{Email: "danar@gmail.com", Code: code})
+		require.Nil(t, err)
+		require.NotEmpty(t, response.AccessToken)
+	})
+
+	t.Run("Should count a wrong code as an attempt", func(t *testing.T) {
+		emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock := setup()
+		_, code := request(t, emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock)
+		wrongCode := "000000"
+		if code == wrongCode {
+			wrongCode = "111111"
+		}
+		oneTimeCodeRepositoryMock.Mock.On("RegisterAttempt", 3).Return(true)
+
+		_, err := emailCodeUseCase.SignIn(context.Background(), &models.EmailCodeSignInRequest{Email: "danar@gmail.com", Code: wrongCode})
+		require.Equal(t, invalidCode, err)
+		oneTimeCodeRepositoryMock.Mock.AssertCalled(t, "RegisterAttempt", 3)
+		oneTimeCodeRepositoryMock.Mock.AssertNotCalled(t, "MarkUsed", mock.Anything)
+	})
+
+	t.Run("Should refuse the right code once the attempts are used up", func(t *testing.T) {
+		emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock := setup()
+		saved, code := request(t, emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock)
+		saved.Attempts = 5
+
+		_, err := emailCodeUseCase.SignIn(context.Background(), &models.EmailCodeSignInRequest{Email: "danar@gmail.com", Code: code})
+		require.Equal(t, invalidCode, err)
+		oneTimeCodeRepositoryMock.Mock.AssertNotCalled(t, "RegisterAttempt", mock.Anything)
+	})
+
+	t.Run("Should refuse an expired code", func(t *testing.T) {
+		emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock := setup()
+		saved, code := request(t, emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock)
+		saved.ExpiresAt = time.Now().Add(-time.Second)
+
+		_, err := emailCodeUseCase.SignIn(context.Background(), &models.EmailCodeSignInRequest{Email: "danar@gmail.com", Code: code})
+		require.Equal(t, invalidCode, err)
+	})
+
+	t.Run("Should not resend before the resend interval", func(t *testing.T) {
+		emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock := setup()
+		request(t, emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock)
+
+		err := emailCodeUseCase.RequestCode(context.Background(), &models.EmailCodeRequest{Email: "danar@gmail.com"})
+		require.Nil(t, err)
+		require.Len(t, mailerMock.Messages, 1)
+	})
+
+	t.Run("Should not send more than max_per_hour codes", func(t *testing.T) {
+		emailCodeUseCase, oneTimeCodeRepositoryMock, mailerMock := setup()
+		oneTimeCodeRepositoryMock.Mock.ExpectedCalls = nil
+		oneTimeCodeRepositoryMock.Mock.On("FindLatest", 1, entity.OneTimeCodePurposeEmailLogin).Return(&entity.OneTimeCode{CreatedAt: time.Now().Add(-10 * time.Minute)})
+		oneTimeCodeRepositoryMock.Mock.On("CountSince", 1, entity.OneTimeCodePurposeEmailLogin).Return(int64(5))
+
+		err := emailCodeUseCase.RequestCode(context.Background(), &models.EmailCodeRequest{Email: "danar@gmail.com"})
+		require.Nil(t, err)
+		require.Empty(t, mailerMock.Messages)
+		oneTimeCodeRepositoryMock.Mock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("Should refuse a code that is not 6 digits", func(t *testing.T) {
+		emailCodeUseCase, _, _ := setup()
+		_, err := emailCodeUseCase.SignIn(context.Background(), &models.EmailCodeSignInRequest{Email: "danar@gmail.com", Code: "12ab56"})
+		require.Equal(t, 400, err.(*models.ErrorResponse).Code)
+	})
+}
@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"testing"
+)
+
+func TestEmailCollisionUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	repositoryMock := mocks.NewUserRepositoryMock()
+	emailCollisionUseCase := usecase.NewEmailCollisionUseCase(repositoryMock, viper)
+
+	users := []*entity.User{
+		{Id: 1, Email: "danar@gmail.com", EmailCanonical: "danar@gmail.com"},
+		{Id: 2, Email: "Danar@Gmail.com", EmailCanonical: "danar@gmail.com"},
+		{Id: 3, Email: "d.anar+test@gmail.com", EmailCanonical: "d.anar+test@gmail.com"},
+		{Id: 4, Email: "other@example.com", EmailCanonical: "other@example.com"},
+		{Id: 5, Email: "j.doe+news@gmail.com", EmailCanonical: "j.doe+news@gmail.com"},
+	}
+	repositoryMock.Mock.On("FindAllInBatches", 500).Return(users, nil)
+	repositoryMock.Mock.On("UpdateEmailCanonical", 5, "jdoe@gmail.com").Return(nil).Once()
+
+	t.Run("Should find users colliding after normalization", func(t *testing.T) {
+		collisions, err := emailCollisionUseCase.FindCollisions(context.Background(), false)
+		require.Nil(t, err)
+		require.Len(t, collisions, 1)
+		require.Equal(t, "danar@gmail.com", collisions[0].EmailCanonical)
+		require.Len(t, collisions[0].Users, 3)
+		repositoryMock.Mock.AssertNotCalled(t, "UpdateEmailCanonical", 5, "jdoe@gmail.com")
+	})
+
+	t.Run("Should backfill outdated canonical emails except the colliding ones", func(t *testing.T) {
+		_, err := emailCollisionUseCase.FindCollisions(context.Background(), true)
+		require.Nil(t, err)
+		repositoryMock.Mock.AssertCalled(t, "UpdateEmailCanonical", 5, "jdoe@gmail.com")
+		repositoryMock.Mock.AssertNotCalled(t, "UpdateEmailCanonical", 3, "danar@gmail.com")
+	})
+}
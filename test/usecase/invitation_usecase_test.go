@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"testing"
+	"time"
+)
+
+func TestInvitationUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	validator := config.NewValidator()
+	invitationRepositoryMock := mocks.NewInvitationRepositoryMock()
+	mailerMock := mocks.NewMailerMock()
+	invitationUseCase := usecase.NewInvitationUseCase(invitationRepositoryMock, mailerMock, validator, viper)
+
+	t.Run("Should email an invitation and only store the hash of its code", func(t *testing.T) {
+		var saved *entity.Invitation
+		invitationRepositoryMock.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*entity.Invitation)
+			saved.Id = 1
+		}).Return(nil).Once()
+
+		result, err := invitationUseCase.CreateInvitation(context.Background(), 9, &models.CreateInvitationRequest{Email: "New.Hire@ACME.com", Role: entity.RoleAdmin})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.Code)
+		require.Equal(t, helpers.HashToken(result.Code), saved.CodeHash)
+		require.Equal(t, "New.Hire@acme.com", *saved.Email)
+		require.Equal(t, entity.RoleAdmin, saved.Role)
+		require.Equal(t, 1, saved.MaxUses)
+		require.Equal(t, 9, *saved.CreatedBy)
+		require.True(t, saved.ExpiresAt.After(time.Now().Add(6*24*time.Hour)))
+		require.Equal(t, "New.Hire@acme.com", mailerMock.Last().To)
+		require.Contains(t, mailerMock.Last().Body, "?invitation_code="+result.Code)
+	})
+
+	t.Run("Should create a code for several users with the default role", func(t *testing.T) {
+		invitationRepositoryMock.Mock.On("Save", mock.MatchedBy(func(invitation *entity.Invitation) bool {
+			return invitation.Email == nil && invitation.Role == entity.RoleUser && invitation.MaxUses == 25
+		})).Return(nil).Once()
+		sent := len(mailerMock.Messages)
+
+		result, err := invitationUseCase.CreateInvitation(context.Background(), 9, &models.CreateInvitationRequest{MaxUses: 25, ExpiresInDays: 2})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.Code)
+		require.Len(t, mailerMock.Messages, sent)
+	})
+
+	t.Run("Should refuse an emailed invitation for several uses", func(t *testing.T) {
+		_, err := invitationUseCase.CreateInvitation(context.Background(), 9, &models.CreateInvitationRequest{Email: "team@acme.com", MaxUses: 5})
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "An emailed invitation can only be used once"}, err)
+	})
+
+	t.Run("Should refuse an unknown role", func(t *testing.T) {
+		_, err := invitationUseCase.CreateInvitation(context.Background(), 9, &models.CreateInvitationRequest{Role: "owner"})
+		require.Equal(t, 400, err.(*models.ErrorResponse).Code)
+	})
+
+	t.Run("Should list invitations without their code", func(t *testing.T) {
+		invitationRepositoryMock.Mock.On("FindAll", 0, 20).Return([]*entity.Invitation{{Id: 2, CodeHash: "hash", Role: entity.RoleUser, MaxUses: 1}}, int64(1))
+
+		result, total, err := invitationUseCase.GetInvitations(context.Background(), models.PageRequest{Page: 1, Size: 20})
+		require.Nil(t, err)
+		require.Equal(t, int64(1), total)
+		require.Empty(t, result[0].Code)
+	})
+
+	t.Run("Should revoke an invitation", func(t *testing.T) {
+		invitationRepositoryMock.Mock.On("Revoke", 2).Return(true)
+		invitationRepositoryMock.Mock.On("Revoke", 3).Return(false)
+
+		require.Nil(t, invitationUseCase.RevokeInvitation(context.Background(), 2))
+		err := invitationUseCase.RevokeInvitation(context.Background(), 3)
+		require.Equal(t, &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Invitation not found"}, err)
+	})
+}
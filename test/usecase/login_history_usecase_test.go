@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/repository"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"testing"
+	"time"
+)
+
+func TestLoginHistoryUseCase(t *testing.T) {
+	loginAttemptRepositoryMock := mocks.NewLoginAttemptRepositoryMock()
+	loginHistoryUseCase := usecase.NewLoginHistoryUseCase(loginAttemptRepositoryMock)
+	page := models.PageRequest{Page: 2, Size: 10}
+	userID := 1
+
+	t.Run("Should return one page of the history of the user", func(t *testing.T) {
+		attempts := []*entity.LoginAttempt{{Id: 12, UserId: &userID, IpAddress: "10.0.0.1", UserAgent: "curl/8.0", Outcome: entity.LoginOutcomeInvalidPassword}}
+		loginAttemptRepositoryMock.Mock.On("FindAllByUserId", 1, 10, 10).Return(attempts, int64(11))
+
+		result, total, err := loginHistoryUseCase.GetLoginHistory(context.Background(), 1, page)
+		require.Nil(t, err)
+		require.Equal(t, int64(11), total)
+		require.Len(t, result, 1)
+		require.Equal(t, "10.0.0.1", result[0].IpAddress)
+		require.Equal(t, entity.LoginOutcomeInvalidPassword, result[0].Outcome)
+	})
+
+	t.Run("Should search with the admin filters", func(t *testing.T) {
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		filter := repository.LoginAttemptFilter{IpAddress: "10.0.0.1", Outcome: entity.LoginOutcomeLocked, From: &from}
+		loginAttemptRepositoryMock.Mock.On("Search", filter, 10, 10).Return([]*entity.LoginAttempt{}, int64(0))
+
+		query := &models.LoginHistoryQuery{IpAddress: "10.0.0.1", Outcome: entity.LoginOutcomeLocked, From: "2024-01-01T00:00:00Z"}
+		result, total, err := loginHistoryUseCase.SearchLoginHistory(context.Background(), query, page)
+		require.Nil(t, err)
+		require.Equal(t, int64(0), total)
+		require.Empty(t, result)
+	})
+
+	t.Run("Should refuse an unknown outcome", func(t *testing.T) {
+		_, _, err := loginHistoryUseCase.SearchLoginHistory(context.Background(), &models.LoginHistoryQuery{Outcome: "maybe"}, page)
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "outcome is not a known outcome"}, err)
+	})
+
+	t.Run("Should refuse a malformed time", func(t *testing.T) {
+		_, _, err := loginHistoryUseCase.SearchLoginHistory(context.Background(), &models.LoginHistoryQuery{To: "yesterday"}, page)
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "to must be an RFC 3339 time"}, err)
+	})
+}
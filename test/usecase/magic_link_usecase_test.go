@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestMagicLinkUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("magic_link.enabled", true)
+	validator := config.NewValidator()
+	user := &entity.User{Id: 1, Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+
+	setup := func() (*usecase.MagicLinkUseCase, *mocks.UserRepositoryMock, *mocks.MagicLinkRepositoryMock, *mocks.MailerMock) {
+		userRepositoryMock := mocks.NewUserRepositoryMock()
+		userRepositoryMock.Mock.On("FindOneByExactEmail", mock.Anything).Return(nil)
+		userRepositoryMock.Mock.On("FindOneByEmail", "danar@gmail.com").Return(user)
+		userRepositoryMock.Mock.On("FindOneByEmail", mock.Anything).Return(nil)
+		userRepositoryMock.Mock.On("FindOneById", 1).Return(user)
+		userRepositoryMock.Mock.On("UpdateLastLoginAt", mock.Anything, mock.Anything).Return(nil)
+		sessionRepositoryMock := mocks.NewSessionRepositoryMock()
+		sessionRepositoryMock.Mock.On("Save", mock.Anything).Return(&entity.Session{Id: "magic-session", UserId: 1})
+		authUseCase := usecase.NewAuthUseCase(userRepositoryMock, sessionRepositoryMock, newLoginAttemptRepositoryMock(), mocks.NewTrustedDeviceRepositoryMock(), validator, viper)
+		magicLinkRepositoryMock := mocks.NewMagicLinkRepositoryMock()
+		magicLinkRepositoryMock.Mock.On("FindLatest", 1).Return(nil)
+		magicLinkRepositoryMock.Mock.On("CountSince", 1).Return(int64(0))
+		mailerMock := mocks.NewMailerMock()
+		magicLinkUseCase := usecase.NewMagicLinkUseCase(authUseCase, userRepositoryMock, magicLinkRepositoryMock, mailerMock, validator, viper)
+		return magicLinkUseCase, userRepositoryMock, magicLinkRepositoryMock, mailerMock
+	}
+
+	request := func(t *testing.T, magicLinkUseCase *usecase.MagicLinkUseCase, magicLinkRepositoryMock *mocks.MagicLinkRepositoryMock, mailerMock *mocks.MailerMock) (*entity.MagicLink, string, string) {
+		var magicLink *entity.MagicLink
+		magicLinkRepositoryMock.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+			magicLink = args.Get(0).(*entity.MagicLink)
+			magicLink.Id = 5
+		}).Return(nil).Once()
+
+		browserSecret, err := magicLinkUseCase.RequestMagicLink(context.Background(), &models.MagicLinkRequest{Email: "danar@gmail.com"})
+		require.Nil(t, err)
+		require.NotEmpty(t, browserSecret)
+		require.Equal(t, "danar@gmail.com", mailerMock.Last().To)
+
+		token := regexp.MustCompile(`token=([0-9a-f]+)`).FindStringSubmatch(mailerMock.Last().Body)[1]
+		magicLinkRepositoryMock.Mock.On("FindOneByTokenHash", magicLink.TokenHash).Return(magicLink)
+		return magicLink, token, browserSecret
+	}
+
+	t.Run("Should sign in from the browser that requested the link", func(t *testing.T) {
+		magicLinkUseCase, _, magicLinkRepositoryMock, mailerMock := setup()
+		magicLink, token, browserSecret := request(t, magicLinkUseCase, magicLinkRepositoryMock, mailerMock)
+		require.NotContains(t, magicLink.TokenHash, token)
+		require.WithinDuration(t, time.Now().Add(15*time.Minute), magicLink.ExpiresAt, 5*time.Second)
+		magicLinkRepositoryMock.Mock.On("MarkUsed", 5).Return(true).Once()
+
+		response, err := magicLinkUseCase.RedeemMagicLink(context.Background(), token, browserSecret, models.ClientInfo{})
+		require.Nil(t, err)
+		require.NotEmpty(t, response.AccessToken)
+		require.NotEmpty(t, response.RefreshToken)
+	})
+
+	t.Run("Should refuse the link in another browser", func(t *testing.T) {
+		magicLinkUseCase, _, magicLinkRepositoryMock, mailerMock := setup()
+		_, token, _ := request(t, magicLinkUseCase, magicLinkRepositoryMock, mailerMock)
+
+		response, err := magicLinkUseCase.RedeemMagicLink(context.Background(), token, "forwarded", models.ClientInfo{})
+		require.Equal(t, &models.ErrorResponse{Code: 403, Status: "Forbidden", Message: "Magic link must be opened in the browser that requested it"}, err)
+		require.Nil(t, response)
+		magicLinkRepositoryMock.Mock.AssertNotCalled(t, "MarkUsed", mock.Anything)
+	})
+
+	t.Run("Should only use a link once", func(t *testing.T) {
+		magicLinkUseCase, _, magicLinkRepositoryMock, mailerMock := setup()
+		_, token, browserSecret := request(t, magicLinkUseCase, magicLinkRepositoryMock, mailerMock)
+		magicLinkRepositoryMock.Mock.On("MarkUsed", 5).Return(false).Once()
+
+		_, err := magicLinkUseCase.RedeemMagicLink(context.Background(), token, browserSecret, models.ClientInfo{})
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Magic link is invalid or expired"}, err)
+	})
+
+	t.Run("Should refuse an expired link", func(t *testing.T) {
+		magicLinkUseCase, _, magicLinkRepositoryMock, mailerMock := setup()
+		magicLink, token, browserSecret := request(t, magicLinkUseCase, magicLinkRepositoryMock, mailerMock)
+		magicLink.ExpiresAt = time.Now().Add(-time.Second)
+
+		_, err := magicLinkUseCase.RedeemMagicLink(context.Background(), token, browserSecret, models.ClientInfo{})
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Magic link is invalid or expired"}, err)
+	})
+
+	t.Run("Should answer the same for an unknown email without sending anything", func(t *testing.T) {
+		magicLinkUseCase, _, magicLinkRepositoryMock, mailerMock := setup()
+
+		browserSecret, err := magicLinkUseCase.RequestMagicLink(context.Background(), &models.MagicLinkRequest{Email: "nobody@gmail.com"})
+		require.Nil(t, err)
+		require.NotEmpty(t, browserSecret)
+		require.Empty(t, mailerMock.Messages)
+		magicLinkRepositoryMock.Mock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("Should not resend before the resend interval", func(t *testing.T) {
+		magicLinkUseCase, _, magicLinkRepositoryMock, mailerMock := setup()
+		magicLinkRepositoryMock.Mock.ExpectedCalls = nil
+		magicLinkRepositoryMock.Mock.On("FindLatest", 1).Return(&entity.MagicLink{CreatedAt: time.Now().Add(-10 * time.Second)})
+
+		browserSecret, err := magicLinkUseCase.RequestMagicLink(context.Background(), &models.MagicLinkRequest{Email: "danar@gmail.com"})
+		require.Nil(t, err)
+		require.NotEmpty(t, browserSecret)
+		require.Empty(t, mailerMock.Messages)
+		magicLinkRepositoryMock.Mock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("Should not send more than max_per_hour links", func(t *testing.T) {
+		magicLinkUseCase, _, magicLinkRepositoryMock, mailerMock := setup()
+		magicLinkRepositoryMock.Mock.ExpectedCalls = nil
+		magicLinkRepositoryMock.Mock.On("FindLatest", 1).Return(&entity.MagicLink{CreatedAt: time.Now().Add(-10 * time.Minute)})
+		magicLinkRepositoryMock.Mock.On("CountSince", 1).Return(int64(5))
+
+		browserSecret, err := magicLinkUseCase.RequestMagicLink(context.Background(), &models.MagicLinkRequest{Email: "danar@gmail.com"})
+		require.Nil(t, err)
+		require.NotEmpty(t, browserSecret)
+		require.Empty(t, mailerMock.Messages)
+		magicLinkRepositoryMock.Mock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+}
@@ -0,0 +1,467 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMfaUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("password.hashing.algorithm", "bcrypt")
+	viper.Set("key.pepper.current_version", 0)
+	viper.Set("otp.sms.enabled", true)
+	validator := config.NewValidator()
+	invalidCode := &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is invalid"}
+	secret := "JBSWY3DPEHPK3PXP"
+
+	type fixture struct {
+		mfaUseCase                   *usecase.MfaUseCase
+		authUseCase                  *usecase.AuthUseCase
+		userRepositoryMock           *mocks.UserRepositoryMock
+		totpCredentialRepositoryMock *mocks.TotpCredentialRepositoryMock
+		recoveryCodeRepositoryMock   *mocks.RecoveryCodeRepositoryMock
+		webAuthnRepositoryMock       *mocks.WebAuthnCredentialRepositoryMock
+		oneTimeCodeRepositoryMock    *mocks.OneTimeCodeRepositoryMock
+		smsSenderMock                *mocks.SmsSenderMock
+		sessionRepositoryMock        *mocks.SessionRepositoryMock
+		trustedDeviceRepositoryMock  *mocks.TrustedDeviceRepositoryMock
+	}
+
+	setup := func(user *entity.User) *fixture {
+		userRepositoryMock := mocks.NewUserRepositoryMock()
+		userRepositoryMock.Mock.On("FindOneByExactEmail", mock.Anything).Return(nil)
+		userRepositoryMock.Mock.On("FindOneByEmail", user.Email).Return(user)
+		userRepositoryMock.Mock.On("FindOneById", user.Id).Return(user)
+		userRepositoryMock.Mock.On("UpdateLastLoginAt", mock.Anything, mock.Anything).Return(nil)
+		userRepositoryMock.Mock.On("UpdateSignInAttempts", mock.Anything).Return(nil)
+		countFailedSignIns(userRepositoryMock)
+		userRepositoryMock.Mock.On("UpdateMfaEnabled", mock.Anything, mock.Anything).Return(nil)
+		sessionRepositoryMock := mocks.NewSessionRepositoryMock()
+		sessionRepositoryMock.Mock.On("Save", mock.Anything).Return(&entity.Session{Id: "mfa-session", UserId: user.Id})
+		trustedDeviceRepositoryMock := mocks.NewTrustedDeviceRepositoryMock()
+		trustedDeviceRepositoryMock.Mock.On("DeleteAllByUserId", 1).Return(nil)
+		authUseCase := usecase.NewAuthUseCase(userRepositoryMock, sessionRepositoryMock, newLoginAttemptRepositoryMock(), trustedDeviceRepositoryMock, validator, viper)
+		totpCredentialRepositoryMock := mocks.NewTotpCredentialRepositoryMock()
+		recoveryCodeRepositoryMock := mocks.NewRecoveryCodeRepositoryMock()
+		webAuthnRepositoryMock := mocks.NewWebAuthnCredentialRepositoryMock()
+		oneTimeCodeRepositoryMock := mocks.NewOneTimeCodeRepositoryMock()
+		oneTimeCodeRepositoryMock.Mock.On("CountSince", 1, mock.Anything).Return(int64(0))
+		smsSenderMock := mocks.NewSmsSenderMock()
+		mfaUseCase := usecase.NewMfaUseCase(authUseCase, userRepositoryMock, totpCredentialRepositoryMock, recoveryCodeRepositoryMock, webAuthnRepositoryMock, oneTimeCodeRepositoryMock, smsSenderMock, validator, viper)
+		return &fixture{mfaUseCase, authUseCase, userRepositoryMock, totpCredentialRepositoryMock, recoveryCodeRepositoryMock, webAuthnRepositoryMock, oneTimeCodeRepositoryMock, smsSenderMock, sessionRepositoryMock, trustedDeviceRepositoryMock}
+	}
+
+	//textCode mocks issuing a code for purpose and returns the texted code, which FindLatest then returns
+	textCode := func(t *testing.T, f *fixture, purpose string, send func() (*models.SmsCodeResponse, error)) string {
+		var saved *entity.OneTimeCode
+		f.oneTimeCodeRepositoryMock.Mock.On("FindLatest", 1, purpose).Return(nil).Once()
+		f.oneTimeCodeRepositoryMock.Mock.On("CountByDestinationSince", "+6281234567890").Return(int64(0))
+		f.oneTimeCodeRepositoryMock.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*entity.OneTimeCode)
+			saved.Id = 7
+			saved.CreatedAt = time.Now()
+		}).Return(nil).Once()
+
+		response, err := send()
+		require.Nil(t, err)
+		require.Equal(t, "+62*********90", response.PhoneNumber)
+		require.Equal(t, "+6281234567890", f.smsSenderMock.Last().To)
+		code := regexp.MustCompile(`\b\d{6}\b`).FindString(f.smsSenderMock.Last().Body)
+		require.NotEmpty(t, code)
+		require.NotContains(t, saved.CodeHash, code)
+		f.oneTimeCodeRepositoryMock.Mock.On("FindLatest", 1, purpose).Return(saved)
+		f.oneTimeCodeRepositoryMock.Mock.On("RegisterAttempt", 7).Return(true)
+		f.oneTimeCodeRepositoryMock.Mock.On("MarkUsed", 7).Return(true)
+		return code
+	}
+
+	confirmedCredential := func(t *testing.T, mfaUseCase *usecase.MfaUseCase) *entity.TotpCredential {
+		sealed, err := mfaUseCase.SecretBox.Seal(secret)
+		require.Nil(t, err)
+		confirmedAt := time.Now().Add(-time.Hour)
+		return &entity.TotpCredential{UserId: 1, Secret: sealed, ConfirmedAt: &confirmedAt}
+	}
+
+	newUser := func(mfaEnabled bool) *entity.User {
+		return &entity.User{Id: 1, Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS", MfaEnabled: mfaEnabled}
+	}
+
+	signIn := func(t *testing.T, f *fixture) string {
+		result, err := f.authUseCase.SignIn(context.Background(), &models.SignInRequest{Email: "danar@gmail.com", Password: "12345678"})
+		require.Nil(t, err)
+		require.True(t, result.MfaRequired)
+		require.NotEmpty(t, result.MfaToken)
+		require.Empty(t, result.AccessToken)
+		require.Empty(t, result.RefreshToken)
+		return result.MfaToken
+	}
+
+	t.Run("Should enroll and confirm an authenticator app", func(t *testing.T) {
+		f := setup(newUser(false))
+		var saved *entity.TotpCredential
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(nil).Once()
+		f.totpCredentialRepositoryMock.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*entity.TotpCredential)
+		}).Return(nil)
+
+		enrollment, err := f.mfaUseCase.EnrollTotp(context.Background(), 1)
+		require.Nil(t, err)
+		require.NotContains(t, saved.Secret, enrollment.Secret)
+		require.True(t, strings.HasPrefix(enrollment.ProvisioningUri, "otpauth://totp/"))
+		require.Contains(t, enrollment.ProvisioningUri, "secret="+enrollment.Secret)
+
+		var hashes []string
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(saved)
+		f.totpCredentialRepositoryMock.Mock.On("Confirm", 1, mock.Anything).Return(nil)
+		f.recoveryCodeRepositoryMock.Mock.On("ReplaceAll", 1, mock.Anything).Run(func(args mock.Arguments) {
+			hashes = args.Get(1).([]string)
+		}).Return(nil)
+		code, err := f.mfaUseCase.TOTP.Code(enrollment.Secret, time.Now())
+		require.Nil(t, err)
+
+		recoveryCodes, err := f.mfaUseCase.ConfirmTotp(context.Background(), 1, &models.MfaCodeRequest{Code: code})
+		require.Nil(t, err)
+		require.Len(t, recoveryCodes.RecoveryCodes, 10)
+		require.Len(t, hashes, 10)
+		for _, recoveryCode := range recoveryCodes.RecoveryCodes {
+			require.Regexp(t, `^[0-9a-f]{5}-[0-9a-f]{5}$`, recoveryCode)
+			require.NotContains(t, hashes, strings.ReplaceAll(recoveryCode, "-", ""))
+		}
+		f.userRepositoryMock.Mock.AssertCalled(t, "UpdateMfaEnabled", 1, true)
+	})
+
+	t.Run("Should keep the recovery codes when another second factor is on", func(t *testing.T) {
+		phoneNumber := "+6281234567890"
+		user := newUser(true)
+		user.PhoneNumber = &phoneNumber
+		f := setup(user)
+		credential := confirmedCredential(t, f.mfaUseCase)
+		credential.ConfirmedAt = nil
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(credential)
+		f.totpCredentialRepositoryMock.Mock.On("Confirm", 1, mock.Anything).Return(nil)
+
+		code, err := f.mfaUseCase.TOTP.Code(secret, time.Now())
+		require.Nil(t, err)
+		recoveryCodes, err := f.mfaUseCase.ConfirmTotp(context.Background(), 1, &models.MfaCodeRequest{Code: code})
+		require.Nil(t, err)
+		require.Empty(t, recoveryCodes.RecoveryCodes)
+		f.recoveryCodeRepositoryMock.Mock.AssertNotCalled(t, "ReplaceAll", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should not confirm with a wrong code", func(t *testing.T) {
+		f := setup(newUser(false))
+		credential := confirmedCredential(t, f.mfaUseCase)
+		credential.ConfirmedAt = nil
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(credential)
+
+		_, err := f.mfaUseCase.ConfirmTotp(context.Background(), 1, &models.MfaCodeRequest{Code: "000000"})
+		require.Equal(t, invalidCode, err)
+		f.userRepositoryMock.Mock.AssertNotCalled(t, "UpdateMfaEnabled", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should ask for the second factor and sign in with an authenticator code", func(t *testing.T) {
+		f := setup(newUser(true))
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(confirmedCredential(t, f.mfaUseCase))
+		f.totpCredentialRepositoryMock.Mock.On("UseStep", 1, mock.Anything).Return(true)
+		mfaToken := signIn(t, f)
+
+		_, err := f.authUseCase.VerifyAccessToken(mfaToken)
+		require.NotNil(t, err)
+
+		code, err := f.mfaUseCase.TOTP.Code(secret, time.Now())
+		require.Nil(t, err)
+		result, err := f.mfaUseCase.SignIn(context.Background(), &models.MfaSignInRequest{MfaToken: mfaToken, Code: code})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.AccessToken)
+		require.NotEmpty(t, result.RefreshToken)
+		f.sessionRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(session *entity.Session) bool {
+			return session.Amr == "pwd otp mfa" && session.Acr == usecase.AcrMultiFactor
+		}))
+	})
+
+	t.Run("Should reject an authenticator code that was already used", func(t *testing.T) {
+		f := setup(newUser(true))
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(confirmedCredential(t, f.mfaUseCase))
+		f.totpCredentialRepositoryMock.Mock.On("UseStep", 1, mock.Anything).Return(false)
+		mfaToken := signIn(t, f)
+
+		code, err := f.mfaUseCase.TOTP.Code(secret, time.Now())
+		require.Nil(t, err)
+		_, err = f.mfaUseCase.SignIn(context.Background(), &models.MfaSignInRequest{MfaToken: mfaToken, Code: code})
+		require.Equal(t, invalidCode, err)
+	})
+
+	t.Run("Should sign in with a recovery code", func(t *testing.T) {
+		f := setup(newUser(true))
+		f.recoveryCodeRepositoryMock.Mock.On("Use", 1, mock.Anything).Return(true)
+		mfaToken := signIn(t, f)
+
+		result, err := f.mfaUseCase.SignIn(context.Background(), &models.MfaSignInRequest{MfaToken: mfaToken, Code: "ABCDE-12345"})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.AccessToken)
+		//only the hash of the code is looked up
+		f.recoveryCodeRepositoryMock.Mock.AssertNotCalled(t, "Use", 1, "abcde12345")
+	})
+
+	t.Run("Should count a wrong second factor as a failed sign in", func(t *testing.T) {
+		user := newUser(true)
+		f := setup(user)
+		f.recoveryCodeRepositoryMock.Mock.On("Use", 1, mock.Anything).Return(false)
+		mfaToken := signIn(t, f)
+
+		_, err := f.mfaUseCase.SignIn(context.Background(), &models.MfaSignInRequest{MfaToken: mfaToken, Code: "abcde-12345"})
+		require.Equal(t, invalidCode, err)
+		require.Equal(t, 1, user.FailedSignInAttempts)
+	})
+
+	t.Run("Should reject an access token as the mfa token", func(t *testing.T) {
+		f := setup(newUser(true))
+		token, err := f.authUseCase.GenerateAccessToken(&entity.Session{UserId: 1})
+		require.Nil(t, err)
+
+		_, err = f.mfaUseCase.SignIn(context.Background(), &models.MfaSignInRequest{MfaToken: token, Code: "123456"})
+		require.Equal(t, &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Invalid token"}, err)
+	})
+
+	t.Run("Should remember the device and skip the second factor on it", func(t *testing.T) {
+		viper.Set("trusted_devices.enabled", true)
+		defer viper.Set("trusted_devices.enabled", false)
+		f := setup(newUser(true))
+		f.recoveryCodeRepositoryMock.Mock.On("Use", 1, mock.Anything).Return(true)
+		var saved *entity.TrustedDevice
+		f.trustedDeviceRepositoryMock.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*entity.TrustedDevice)
+			saved.Id = 3
+		}).Return(nil)
+		mfaToken := signIn(t, f)
+
+		result, err := f.mfaUseCase.SignIn(context.Background(), &models.MfaSignInRequest{MfaToken: mfaToken, Code: "abcde-12345", RememberDevice: true})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.TrustedDeviceToken)
+		require.NotContains(t, result.TrustedDeviceToken, saved.TokenHash)
+		require.True(t, result.TrustedDeviceExpiresAt.After(time.Now().Add(29*24*time.Hour)))
+
+		f.trustedDeviceRepositoryMock.Mock.On("FindOneByTokenHash", saved.TokenHash).Return(saved)
+		f.trustedDeviceRepositoryMock.Mock.On("UpdateLastUsedAt", 3).Return(nil)
+		result, err = f.authUseCase.SignIn(context.Background(), &models.SignInRequest{Email: "danar@gmail.com", Password: "12345678", ClientInfo: models.ClientInfo{TrustedDeviceToken: result.TrustedDeviceToken}})
+		require.Nil(t, err)
+		require.False(t, result.MfaRequired)
+		require.NotEmpty(t, result.AccessToken)
+		//a trusted device skips the second factor, it doesn't stand in for it
+		f.sessionRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(session *entity.Session) bool {
+			return session.Amr == "pwd" && session.Acr == usecase.AcrSingleFactor
+		}))
+	})
+
+	t.Run("Should ask for the second factor on a revoked or foreign device", func(t *testing.T) {
+		viper.Set("trusted_devices.enabled", true)
+		defer viper.Set("trusted_devices.enabled", false)
+		f := setup(newUser(true))
+		f.trustedDeviceRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+		token, _, err := f.authUseCase.TrustDevice(context.Background(), newUser(true), models.ClientInfo{})
+		require.Nil(t, err)
+		f.trustedDeviceRepositoryMock.Mock.On("FindOneByTokenHash", mock.Anything).Return(nil).Once()
+		result, err := f.authUseCase.SignIn(context.Background(), &models.SignInRequest{Email: "danar@gmail.com", Password: "12345678", ClientInfo: models.ClientInfo{TrustedDeviceToken: token}})
+		require.Nil(t, err)
+		require.True(t, result.MfaRequired)
+
+		f.trustedDeviceRepositoryMock.Mock.On("FindOneByTokenHash", mock.Anything).Return(&entity.TrustedDevice{Id: 3, UserId: 2, ExpiresAt: time.Now().Add(time.Hour)})
+		result, err = f.authUseCase.SignIn(context.Background(), &models.SignInRequest{Email: "danar@gmail.com", Password: "12345678", ClientInfo: models.ClientInfo{TrustedDeviceToken: token}})
+		require.Nil(t, err)
+		require.True(t, result.MfaRequired)
+		f.trustedDeviceRepositoryMock.Mock.AssertNotCalled(t, "UpdateLastUsedAt", 3)
+	})
+
+	t.Run("Should not remember the device while trusted devices are disabled", func(t *testing.T) {
+		f := setup(newUser(true))
+		f.recoveryCodeRepositoryMock.Mock.On("Use", 1, mock.Anything).Return(true)
+		mfaToken := signIn(t, f)
+
+		result, err := f.mfaUseCase.SignIn(context.Background(), &models.MfaSignInRequest{MfaToken: mfaToken, Code: "abcde-12345", RememberDevice: true})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.AccessToken)
+		require.Empty(t, result.TrustedDeviceToken)
+		f.trustedDeviceRepositoryMock.Mock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("Should disable with a valid code only", func(t *testing.T) {
+		f := setup(newUser(true))
+		//two lookups for each call, the credential is gone afterwards
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(confirmedCredential(t, f.mfaUseCase)).Times(4)
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(nil)
+		f.totpCredentialRepositoryMock.Mock.On("UseStep", 1, mock.Anything).Return(true)
+		f.totpCredentialRepositoryMock.Mock.On("DeleteByUserId", 1).Return(nil)
+		f.recoveryCodeRepositoryMock.Mock.On("DeleteAllByUserId", 1).Return(nil)
+		f.webAuthnRepositoryMock.Mock.On("CountByUserId", 1).Return(int64(0))
+
+		expiredCode, err := f.mfaUseCase.TOTP.Code(secret, time.Now().Add(-time.Hour))
+		require.Nil(t, err)
+		err = f.mfaUseCase.DisableTotp(context.Background(), 1, &models.MfaCodeRequest{Code: expiredCode})
+		require.Equal(t, invalidCode, err)
+		f.totpCredentialRepositoryMock.Mock.AssertNotCalled(t, "DeleteByUserId", 1)
+
+		code, err := f.mfaUseCase.TOTP.Code(secret, time.Now())
+		require.Nil(t, err)
+		err = f.mfaUseCase.DisableTotp(context.Background(), 1, &models.MfaCodeRequest{Code: code})
+		require.Nil(t, err)
+		f.userRepositoryMock.Mock.AssertCalled(t, "UpdateMfaEnabled", 1, false)
+		f.trustedDeviceRepositoryMock.Mock.AssertCalled(t, "DeleteAllByUserId", 1)
+	})
+
+	t.Run("Should keep two-factor authentication on while a passkey is left", func(t *testing.T) {
+		f := setup(newUser(true))
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(confirmedCredential(t, f.mfaUseCase))
+		f.totpCredentialRepositoryMock.Mock.On("UseStep", 1, mock.Anything).Return(true)
+		f.totpCredentialRepositoryMock.Mock.On("DeleteByUserId", 1).Return(nil)
+		f.webAuthnRepositoryMock.Mock.On("CountByUserId", 1).Return(int64(1))
+
+		code, err := f.mfaUseCase.TOTP.Code(secret, time.Now())
+		require.Nil(t, err)
+		err = f.mfaUseCase.DisableTotp(context.Background(), 1, &models.MfaCodeRequest{Code: code})
+		require.Nil(t, err)
+		f.userRepositoryMock.Mock.AssertNotCalled(t, "UpdateMfaEnabled", mock.Anything, mock.Anything)
+		f.recoveryCodeRepositoryMock.Mock.AssertNotCalled(t, "DeleteAllByUserId", 1)
+		f.trustedDeviceRepositoryMock.Mock.AssertCalled(t, "DeleteAllByUserId", 1)
+	})
+
+	t.Run("Should enroll a phone number and turn two-factor authentication on", func(t *testing.T) {
+		f := setup(newUser(false))
+		f.userRepositoryMock.Mock.On("UpdatePhoneNumber", 1, mock.Anything).Return(nil)
+		f.recoveryCodeRepositoryMock.Mock.On("ReplaceAll", 1, mock.Anything).Return(nil)
+
+		code := textCode(t, f, entity.OneTimeCodePurposeSmsEnrollment, func() (*models.SmsCodeResponse, error) {
+			return f.mfaUseCase.EnrollPhone(context.Background(), 1, &models.PhoneEnrollmentRequest{PhoneNumber: "+62 812-3456-7890"})
+		})
+
+		result, err := f.mfaUseCase.ConfirmPhone(context.Background(), 1, &models.MfaCodeRequest{Code: code})
+		require.Nil(t, err)
+		require.Equal(t, "+6281234567890", result.PhoneNumber)
+		require.Len(t, result.RecoveryCodes, 10)
+		phoneNumber := "+6281234567890"
+		f.userRepositoryMock.Mock.AssertCalled(t, "UpdatePhoneNumber", 1, &phoneNumber)
+		f.userRepositoryMock.Mock.AssertCalled(t, "UpdateMfaEnabled", 1, true)
+	})
+
+	t.Run("Should not enroll an invalid phone number", func(t *testing.T) {
+		f := setup(newUser(false))
+
+		_, err := f.mfaUseCase.EnrollPhone(context.Background(), 1, &models.PhoneEnrollmentRequest{PhoneNumber: "0812345"})
+		require.Equal(t, 400, err.(*models.ErrorResponse).Code)
+		require.Empty(t, f.smsSenderMock.Messages)
+	})
+
+	t.Run("Should not replace an enrolled phone number", func(t *testing.T) {
+		phoneNumber := "+6281234567890"
+		user := newUser(true)
+		user.PhoneNumber = &phoneNumber
+		f := setup(user)
+
+		_, err := f.mfaUseCase.EnrollPhone(context.Background(), 1, &models.PhoneEnrollmentRequest{PhoneNumber: "+6289876543210"})
+		require.Equal(t, &models.ErrorResponse{Code: 409, Status: "Conflict", Message: "Phone number is already enabled"}, err)
+		_, err = f.mfaUseCase.ConfirmPhone(context.Background(), 1, &models.MfaCodeRequest{Code: "123456"})
+		require.Equal(t, 409, err.(*models.ErrorResponse).Code)
+		require.Empty(t, f.smsSenderMock.Messages)
+		f.userRepositoryMock.Mock.AssertNotCalled(t, "UpdatePhoneNumber", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should sign in with a texted code", func(t *testing.T) {
+		phoneNumber := "+6281234567890"
+		user := newUser(true)
+		user.PhoneNumber = &phoneNumber
+		f := setup(user)
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(nil)
+		mfaToken := signIn(t, f)
+
+		code := textCode(t, f, entity.OneTimeCodePurposeSmsLogin, func() (*models.SmsCodeResponse, error) {
+			return f.mfaUseCase.SendSignInCode(context.Background(), &models.MfaSmsRequest{MfaToken: mfaToken})
+		})
+
+		result, err := f.mfaUseCase.SignIn(context.Background(), &models.MfaSignInRequest{MfaToken: mfaToken, Code: code})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.AccessToken)
+		f.oneTimeCodeRepositoryMock.Mock.AssertCalled(t, "MarkUsed", 7)
+	})
+
+	t.Run("Should limit the codes texted to one phone number", func(t *testing.T) {
+		f := setup(newUser(false))
+		f.oneTimeCodeRepositoryMock.Mock.On("FindLatest", 1, entity.OneTimeCodePurposeSmsEnrollment).Return(nil)
+		f.oneTimeCodeRepositoryMock.Mock.On("CountByDestinationSince", "+6281234567890").Return(int64(10))
+
+		_, err := f.mfaUseCase.EnrollPhone(context.Background(), 1, &models.PhoneEnrollmentRequest{PhoneNumber: "+6281234567890"})
+		require.Equal(t, 429, err.(*models.ErrorResponse).Code)
+		require.Empty(t, f.smsSenderMock.Messages)
+		f.oneTimeCodeRepositoryMock.Mock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("Should keep two-factor authentication on while a phone number is left", func(t *testing.T) {
+		phoneNumber := "+6281234567890"
+		user := newUser(true)
+		user.PhoneNumber = &phoneNumber
+		f := setup(user)
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(confirmedCredential(t, f.mfaUseCase))
+		f.totpCredentialRepositoryMock.Mock.On("UseStep", 1, mock.Anything).Return(true)
+		f.totpCredentialRepositoryMock.Mock.On("DeleteByUserId", 1).Return(nil)
+
+		code, err := f.mfaUseCase.TOTP.Code(secret, time.Now())
+		require.Nil(t, err)
+		err = f.mfaUseCase.DisableTotp(context.Background(), 1, &models.MfaCodeRequest{Code: code})
+		require.Nil(t, err)
+		f.userRepositoryMock.Mock.AssertNotCalled(t, "UpdateMfaEnabled", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should re-authenticate the session with the password and a second factor", func(t *testing.T) {
+		f := setup(newUser(true))
+		f.totpCredentialRepositoryMock.Mock.On("FindOneByUserId", 1).Return(confirmedCredential(t, f.mfaUseCase))
+		f.totpCredentialRepositoryMock.Mock.On("UseStep", 1, mock.Anything).Return(true)
+		session := &entity.Session{Id: "old-session", UserId: 1, AuthTime: time.Now().Add(-time.Hour), Amr: "pwd", Acr: usecase.AcrSingleFactor, ExpiresAt: time.Now().Add(time.Hour)}
+		f.sessionRepositoryMock.Mock.On("FindOneById", "old-session").Return(session)
+		f.sessionRepositoryMock.Mock.On("UpdateAuthentication", session).Return(nil)
+		claims := &usecase.AccessClaims{UserId: 1, SessionId: "old-session", AuthTime: session.AuthTime, Amr: []string{"pwd"}, Acr: usecase.AcrSingleFactor}
+
+		_, err := f.mfaUseCase.Reauthenticate(context.Background(), claims, &models.ReauthenticateRequest{Password: "12345678"})
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Code is required"}, err)
+
+		code, err := f.mfaUseCase.TOTP.Code(secret, time.Now())
+		require.Nil(t, err)
+		result, err := f.mfaUseCase.Reauthenticate(context.Background(), claims, &models.ReauthenticateRequest{Password: "12345678", Code: code})
+		require.Nil(t, err)
+
+		upgraded, err := f.authUseCase.VerifyAccessClaims(result.AccessToken)
+		require.Nil(t, err)
+		require.Equal(t, "old-session", upgraded.SessionId)
+		require.Equal(t, []string{"pwd", "otp", "mfa"}, upgraded.Amr)
+		require.Nil(t, f.authUseCase.CheckStepUp(upgraded, time.Minute, usecase.AcrMultiFactor))
+		f.sessionRepositoryMock.Mock.AssertCalled(t, "UpdateAuthentication", session)
+	})
+
+	t.Run("Should count a wrong password at re-authentication as a failed sign in", func(t *testing.T) {
+		user := newUser(false)
+		f := setup(user)
+		f.sessionRepositoryMock.Mock.On("FindOneById", "old-session").Return(&entity.Session{Id: "old-session", UserId: 1, ExpiresAt: time.Now().Add(time.Hour)})
+		claims := &usecase.AccessClaims{UserId: 1, SessionId: "old-session"}
+
+		_, err := f.mfaUseCase.Reauthenticate(context.Background(), claims, &models.ReauthenticateRequest{Password: "wrong password"})
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Password is invalid"}, err)
+		require.Equal(t, 1, user.FailedSignInAttempts)
+		f.sessionRepositoryMock.Mock.AssertNotCalled(t, "UpdateAuthentication", mock.Anything)
+	})
+
+	t.Run("Should not re-authenticate a revoked session", func(t *testing.T) {
+		f := setup(newUser(false))
+		revokedAt := time.Now()
+		f.sessionRepositoryMock.Mock.On("FindOneById", "old-session").Return(&entity.Session{Id: "old-session", UserId: 1, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt})
+
+		_, err := f.mfaUseCase.Reauthenticate(context.Background(), &usecase.AccessClaims{UserId: 1, SessionId: "old-session"}, &models.ReauthenticateRequest{Password: "12345678"})
+		require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+	})
+}
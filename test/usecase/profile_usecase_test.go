@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/security"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfileUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	validator := config.NewValidator()
+	repositoryMock := mocks.NewUserRepositoryMock()
+	passwordHistoryRepositoryMock := mocks.NewPasswordHistoryRepositoryMock()
+	trustedDeviceRepositoryMock := mocks.NewTrustedDeviceRepositoryMock()
+	emailDomainPolicy, err := security.NewEmailDomainPolicy(viper)
+	require.Nil(t, err)
+	profileUseCase := usecase.NewProfileUseCase(repositoryMock, passwordHistoryRepositoryMock, trustedDeviceRepositoryMock, emailDomainPolicy, validator, viper)
+
+	t.Run("Get profile", func(t *testing.T) {
+		t.Run("When user doesn't exist", func(t *testing.T) {
+			repositoryMock.Mock.On("FindOneById", 99).Return(nil)
+			result, err := profileUseCase.GetProfile(context.Background(), 99)
+			require.Equal(t, &models.ErrorResponse{Code: 404, Message: "User not found", Status: "Not Found"}, err)
+			require.Nil(t, result)
+		})
+
+		t.Run("When user exists", func(t *testing.T) {
+			user := &entity.User{
+				Id:        1,
+				Name:      "danar",
+				Email:     "danar@gmail.com",
+				CreatedAt: time.Date(2024, 3, 1, 8, 30, 0, 0, time.FixedZone("WITA", 8*60*60)),
+				UpdatedAt: time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC),
+			}
+			repositoryMock.Mock.On("FindOneById", 1).Return(user).Once()
+			result, err := profileUseCase.GetProfile(context.Background(), 1)
+			require.Nil(t, err)
+			require.Equal(t, 1, result.Id)
+			require.Equal(t, "danar", result.Name)
+			require.Equal(t, "danar@gmail.com", result.Email)
+			require.Equal(t, "2024-03-01T00:30:00Z", result.CreatedAt)
+			require.Equal(t, "2024-03-02T10:00:00Z", result.UpdatedAt)
+		})
+	})
+
+	t.Run("Update profile", func(t *testing.T) {
+		t.Run("Invalid email", func(t *testing.T) {
+			request := &models.UpdateUserRequest{Email: "gmail"}
+			result, err := profileUseCase.UpdateProfile(context.Background(), 1, request)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Email must be email", Status: "Bad Request"}, err)
+			require.Nil(t, result)
+		})
+
+		t.Run("Email already taken", func(t *testing.T) {
+			user := &entity.User{Id: 1, Name: "danar", Email: "danar@gmail.com"}
+			other := &entity.User{Id: 2, Name: "other", Email: "taken@gmail.com"}
+			repositoryMock.Mock.On("FindOneById", 1).Return(user).Once()
+			repositoryMock.Mock.On("FindOneByEmailWithDeleted", "taken@gmail.com").Return(other)
+
+			request := &models.UpdateUserRequest{Email: "taken@gmail.com"}
+			result, err := profileUseCase.UpdateProfile(context.Background(), 1, request)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Email already exists", Status: "Bad Request"}, err)
+			require.Nil(t, result)
+		})
+
+		t.Run("Email domain denied", func(t *testing.T) {
+			user := &entity.User{Id: 1, Name: "danar", Email: "danar@gmail.com"}
+			repositoryMock.Mock.On("FindOneById", 1).Return(user).Once()
+			profileUseCase.EmailDomainPolicy.Deny = []string{"blocked.com"}
+			defer func() { profileUseCase.EmailDomainPolicy.Deny = nil }()
+
+			request := &models.UpdateUserRequest{Email: "danar@blocked.com"}
+			result, err := profileUseCase.UpdateProfile(context.Background(), 1, request)
+			detail := models.ErrorDetail{Code: security.EmailDomainDenied, Message: "Email domain is not allowed"}
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Email domain is not allowed", Status: "Bad Request", Errors: []models.ErrorDetail{detail}}, err)
+			require.Nil(t, result)
+			require.Equal(t, "danar@gmail.com", user.Email)
+		})
+
+		t.Run("Update name", func(t *testing.T) {
+			user := &entity.User{Id: 1, Name: "danar", Email: "danar@gmail.com"}
+			repositoryMock.Mock.On("FindOneById", 1).Return(user).Once()
+			repositoryMock.Mock.On("UpdateProfile", user).Return(nil)
+
+			request := &models.UpdateUserRequest{Name: "Danar Cahyadi"}
+			result, err := profileUseCase.UpdateProfile(context.Background(), 1, request)
+			require.Nil(t, err)
+			require.Equal(t, "Danar Cahyadi", result.Name)
+			require.Equal(t, "danar@gmail.com", result.Email)
+		})
+
+		t.Run("Should validate the username once normalized", func(t *testing.T) {
+			user := &entity.User{Id: 1, Name: "danar", Email: "danar@gmail.com"}
+			repositoryMock.Mock.On("FindOneById", 1).Return(user).Once()
+			repositoryMock.Mock.On("FindOneByUsernameWithDeleted", "danar.cahyadi").Return(nil)
+			repositoryMock.Mock.On("UpdateProfile", user).Return(nil)
+
+			request := &models.UpdateUserRequest{Username: "  Danar.Cahyadi" + strings.Repeat(" ", 24)}
+			result, err := profileUseCase.UpdateProfile(context.Background(), 1, request)
+			require.Nil(t, err)
+			require.Equal(t, "danar.cahyadi", result.Username)
+			require.Equal(t, "danar@gmail.com", result.Email)
+		})
+	})
+
+	t.Run("Change password", func(t *testing.T) {
+		t.Run("When current password doesn't match", func(t *testing.T) {
+			user := &entity.User{Id: 4, Name: "danar", Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+			repositoryMock.Mock.On("FindOneById", 4).Return(user).Once()
+
+			request := &models.ChangePasswordRequest{CurrentPassword: "wrong-password", NewPassword: "correct-Horse-battery-Staple-42"}
+			err := profileUseCase.ChangePassword(context.Background(), 4, request)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Current password invalid", Status: "Bad Request"}, err)
+		})
+
+		t.Run("When new password is breached", func(t *testing.T) {
+			user := &entity.User{Id: 4, Name: "danar", Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+			repositoryMock.Mock.On("FindOneById", 4).Return(user).Once()
+			profileUseCase.PasswordUseCase.BreachChecker = breachChecker{"correct-Horse-battery-Staple-42": true}
+			defer func() { profileUseCase.PasswordUseCase.BreachChecker = &security.NoopBreachChecker{} }()
+
+			request := &models.ChangePasswordRequest{CurrentPassword: "12345678", NewPassword: "correct-Horse-battery-Staple-42"}
+			err := profileUseCase.ChangePassword(context.Background(), 4, request)
+			require.Equal(t, []models.ErrorDetail{
+				{Code: usecase.PasswordBreached, Message: "Password has appeared in a data breach. Please choose another password"},
+			}, err.(*models.ErrorResponse).Errors)
+		})
+
+		t.Run("When new password is the current password", func(t *testing.T) {
+			user := &entity.User{Id: 4, Name: "danar", Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+			repositoryMock.Mock.On("FindOneById", 4).Return(user).Once()
+			viper.Set("password.policy.common_passwords_file", "")
+			profileUseCase.PasswordUseCase = usecase.NewPasswordUseCase(viper)
+			defer func() {
+				viper.Set("password.policy.common_passwords_file", "data/common-passwords.txt")
+				profileUseCase.PasswordUseCase = usecase.NewPasswordUseCase(viper)
+			}()
+
+			request := &models.ChangePasswordRequest{CurrentPassword: "12345678", NewPassword: "12345678"}
+			err := profileUseCase.ChangePassword(context.Background(), 4, request)
+			require.Equal(t, []models.ErrorDetail{
+				{Code: usecase.PasswordReused, Message: "Password must not be one of your last 5 passwords"},
+			}, err.(*models.ErrorResponse).Errors)
+		})
+
+		t.Run("When new password is in the history", func(t *testing.T) {
+			previousHash, err := profileUseCase.PasswordUseCase.HashPassword("correct-Horse-battery-Staple-42")
+			require.Nil(t, err)
+			user := &entity.User{Id: 5, Name: "danar", Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+			repositoryMock.Mock.On("FindOneById", 5).Return(user).Once()
+			passwordHistoryRepositoryMock.Mock.On("FindLatestByUserId", 5, 4).Return([]*entity.PasswordHistory{
+				{Id: 1, UserId: 5, Password: previousHash},
+			}).Once()
+
+			request := &models.ChangePasswordRequest{CurrentPassword: "12345678", NewPassword: "correct-Horse-battery-Staple-42"}
+			err = profileUseCase.ChangePassword(context.Background(), 5, request)
+			require.Equal(t, []models.ErrorDetail{
+				{Code: usecase.PasswordReused, Message: "Password must not be one of your last 5 passwords"},
+			}, err.(*models.ErrorResponse).Errors)
+		})
+
+		t.Run("Should hash the new password and clear the change requirement", func(t *testing.T) {
+			currentHash := "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"
+			user := &entity.User{Id: 4, Name: "danar", Email: "danar@gmail.com", Password: currentHash, PasswordChangeRequired: true}
+			repositoryMock.Mock.On("FindOneById", 4).Return(user).Once()
+			repositoryMock.Mock.On("ChangePassword", user).Return(nil).Once()
+			passwordHistoryRepositoryMock.Mock.On("FindLatestByUserId", 4, 4).Return(nil).Once()
+			history := &entity.PasswordHistory{UserId: 4, Password: currentHash}
+			passwordHistoryRepositoryMock.Mock.On("Save", history).Return(history).Once()
+			passwordHistoryRepositoryMock.Mock.On("DeleteAllByUserIdExceptLatest", 4, 4).Return(nil).Once()
+			trustedDeviceRepositoryMock.Mock.On("DeleteAllByUserId", 4).Return(nil).Once()
+
+			request := &models.ChangePasswordRequest{CurrentPassword: "12345678", NewPassword: "correct-Horse-battery-Staple-42"}
+			err := profileUseCase.ChangePassword(context.Background(), 4, request)
+			require.Nil(t, err)
+			require.False(t, user.PasswordChangeRequired)
+			require.True(t, profileUseCase.PasswordUseCase.ComparePassword(user.Password, "correct-Horse-battery-Staple-42"))
+			repositoryMock.Mock.AssertCalled(t, "ChangePassword", user)
+			passwordHistoryRepositoryMock.Mock.AssertExpectations(t)
+			trustedDeviceRepositoryMock.Mock.AssertExpectations(t)
+		})
+	})
+
+	t.Run("Delete account", func(t *testing.T) {
+		user := &entity.User{Id: 3, Name: "danar", Email: "danar@gmail.com"}
+		repositoryMock.Mock.On("FindOneById", 3).Return(user).Once()
+		repositoryMock.Mock.On("SoftDeleteById", 3).Return(nil).Once()
+
+		result, err := profileUseCase.DeleteAccount(context.Background(), 3)
+		require.Nil(t, err)
+
+		purgeAt, err := time.Parse(time.RFC3339, result.PurgeAt)
+		require.Nil(t, err)
+		gracePeriod := time.Duration(viper.GetInt("account.deletion.grace_period_days")) * 24 * time.Hour
+		require.WithinDuration(t, time.Now().Add(gracePeriod), purgeAt, time.Minute)
+		repositoryMock.Mock.AssertCalled(t, "SoftDeleteById", 3)
+	})
+}
+
+// breachChecker is a fake breach dataset keyed by password
+type breachChecker map[string]bool
+
+func (c breachChecker) IsBreached(password string) (bool, error) {
+	return c[password], nil
+}
@@ -1,26 +1,173 @@
 package usecase
 
 import (
+	"context"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/security"
 	"golang-authentication/internal/usecase"
 	"golang-authentication/test/mocks"
-	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSignUpUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
 	validator := config.NewValidator()
 	repositoryMock := mocks.NewUserRepositoryMock()
-	signupUseCase := usecase.NewSignupUseCase(repositoryMock, validator)
+	invitationRepositoryMock := mocks.NewInvitationRepositoryMock()
+	emailDomainPolicy, err := security.NewEmailDomainPolicy(viper)
+	require.Nil(t, err)
+	signupUseCase := usecase.NewSignupUseCase(repositoryMock, invitationRepositoryMock, emailDomainPolicy, validator, viper)
 
 	t.Run("Generate hash password and compare", func(t *testing.T) {
 		hashedPassword, err := signupUseCase.HashPassword("password")
 		require.Nil(t, err)
 		require.NotNil(t, hashedPassword)
-		err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte("password"))
-		require.Nil(t, err)
+		require.True(t, strings.HasPrefix(hashedPassword, "$pepper$v=1$argon2id$"))
+		require.True(t, signupUseCase.PasswordUseCase.ComparePassword(hashedPassword, "password"))
+
+	})
+
+	t.Run("Should report every password policy failure", func(t *testing.T) {
+		request := &models.SignUpRequest{Name: "Danar", Email: "danar@gmail.com", Password: "danar"}
+		result, err := signupUseCase.CreateUser(context.Background(), request)
+		require.Nil(t, result)
+		require.Equal(t, "Password must be min 8", err.(*models.ErrorResponse).Message)
+		require.Equal(t, []models.ErrorDetail{
+			{Code: "password_too_short", Message: "Password must be min 8"},
+			{Code: "password_contains_personal_info", Message: "Password must not contain your name, username or email"},
+		}, err.(*models.ErrorResponse).Errors)
+	})
+
+	t.Run("Username", func(t *testing.T) {
+		repositoryMock.Mock.On("FindOneByEmailWithDeleted", "danar@gmail.com").Return(nil)
+
+		t.Run("Should refuse invalid characters", func(t *testing.T) {
+			for _, username := range []string{"1danar", "danar!", "da..nar", "danar_", "dan ar"} {
+				request := &models.SignUpRequest{Name: "Danar", Username: username, Email: "danar@gmail.com", Password: "s3cure-Passphrase"}
+				result, err := signupUseCase.CreateUser(context.Background(), request)
+				require.NotNil(t, err, username)
+				require.Equal(t, "Username must start with a letter and only contain letters, numbers, single dots or underscores", err.(*models.ErrorResponse).Message)
+				require.Nil(t, result)
+			}
+		})
+
+		t.Run("Should refuse reserved words regardless of case", func(t *testing.T) {
+			request := &models.SignUpRequest{Name: "Danar", Username: "Admin", Email: "danar@gmail.com", Password: "s3cure-Passphrase"}
+			result, err := signupUseCase.CreateUser(context.Background(), request)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Username is not available", Status: "Bad Request"}, err)
+			require.Nil(t, result)
+		})
+
+		t.Run("Should be unique regardless of case", func(t *testing.T) {
+			username := "danar"
+			repositoryMock.Mock.On("FindOneByUsernameWithDeleted", "danar").Return(&entity.User{Id: 1, Username: &username}).Once()
+			request := &models.SignUpRequest{Name: "Danar", Username: "DANAR", Email: "danar@gmail.com", Password: "s3cure-Passphrase"}
+			result, err := signupUseCase.CreateUser(context.Background(), request)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Username already exists", Status: "Bad Request"}, err)
+			require.Nil(t, result)
+		})
+
+		t.Run("Should save the lowercase username", func(t *testing.T) {
+			repositoryMock.Mock.On("FindOneByUsernameWithDeleted", "danar.cahyadi").Return(nil).Once()
+			username := "danar.cahyadi"
+			repositoryMock.Mock.On("Save", mock.MatchedBy(func(user *entity.User) bool {
+				return user.Username != nil && *user.Username == username
+			})).Return(&entity.User{Id: 2, Name: "Danar", Username: &username, Email: "danar@gmail.com"}).Once()
+			request := &models.SignUpRequest{Name: "Danar", Username: "Danar.Cahyadi", Email: "danar@gmail.com", Password: "s3cure-Passphrase"}
+			result, err := signupUseCase.CreateUser(context.Background(), request)
+			require.Nil(t, err)
+			require.Equal(t, "danar.cahyadi", result.Username)
+		})
+	})
+
+	t.Run("Signup mode", func(t *testing.T) {
+		defer viper.Set("signup.mode", usecase.SignUpModeOpen)
+		repositoryMock.Mock.On("FindOneByEmailWithDeleted", "invited@acme.com").Return(nil)
+		request := func(code string) *models.SignUpRequest {
+			return &models.SignUpRequest{Name: "Invited", Email: "invited@acme.com", Password: "s3cure-Passphrase", InvitationCode: code}
+		}
+		errorCode := func(err error) string {
+			return err.(*models.ErrorResponse).Errors[0].Code
+		}
 
+		t.Run("Should refuse every signup when closed", func(t *testing.T) {
+			viper.Set("signup.mode", usecase.SignUpModeClosed)
+			_, err := signupUseCase.CreateUser(context.Background(), request(""))
+			require.Equal(t, 403, err.(*models.ErrorResponse).Code)
+			require.Equal(t, usecase.SignUpClosed, errorCode(err))
+		})
+
+		t.Run("Should require an invitation when invite only", func(t *testing.T) {
+			viper.Set("signup.mode", usecase.SignUpModeInviteOnly)
+			_, err := signupUseCase.CreateUser(context.Background(), request(""))
+			require.Equal(t, 403, err.(*models.ErrorResponse).Code)
+			require.Equal(t, usecase.InvitationRequired, errorCode(err))
+
+			invitationRepositoryMock.Mock.On("FindOneByCodeHash", helpers.HashToken("unknown")).Return(nil).Once()
+			_, err = signupUseCase.CreateUser(context.Background(), request("unknown"))
+			require.Equal(t, usecase.InvitationInvalid, errorCode(err))
+		})
+
+		t.Run("Should only sign up the email an invitation was sent to", func(t *testing.T) {
+			viper.Set("signup.mode", usecase.SignUpModeInviteOnly)
+			email := "someone@acme.com"
+			invitationRepositoryMock.Mock.On("FindOneByCodeHash", helpers.HashToken("emailed")).Return(&entity.Invitation{Id: 4, Email: &email, Role: entity.RoleUser, MaxUses: 1, ExpiresAt: time.Now().Add(time.Hour)})
+
+			_, err := signupUseCase.CreateUser(context.Background(), request("emailed"))
+			require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Invitation was sent to another email",
+				Errors: []models.ErrorDetail{{Code: usecase.InvitationInvalid, Message: "Invitation was sent to another email"}}}, err)
+			invitationRepositoryMock.Mock.AssertNotCalled(t, "Use", 4)
+		})
+
+		t.Run("Should use the invitation and give its role", func(t *testing.T) {
+			viper.Set("signup.mode", usecase.SignUpModeInviteOnly)
+			invitationRepositoryMock.Mock.On("FindOneByCodeHash", helpers.HashToken("team-code")).Return(&entity.Invitation{Id: 5, Role: entity.RoleAdmin, MaxUses: 3, Uses: 1, ExpiresAt: time.Now().Add(time.Hour)})
+			invitationRepositoryMock.Mock.On("Use", 5).Return(true).Once()
+			repositoryMock.Mock.On("Save", mock.MatchedBy(func(user *entity.User) bool {
+				return user.Email == "invited@acme.com" && user.Role == entity.RoleAdmin
+			})).Return(&entity.User{Id: 3, Name: "Invited", Email: "invited@acme.com", Role: entity.RoleAdmin}).Once()
+
+			result, err := signupUseCase.CreateUser(context.Background(), request(" team-code "))
+			require.Nil(t, err)
+			require.Equal(t, entity.RoleAdmin, result.Role)
+		})
+
+		t.Run("Should refuse an invitation used up meanwhile", func(t *testing.T) {
+			viper.Set("signup.mode", usecase.SignUpModeInviteOnly)
+			invitationRepositoryMock.Mock.On("Use", 5).Return(false).Once()
+
+			_, err := signupUseCase.CreateUser(context.Background(), request("team-code"))
+			require.Equal(t, usecase.InvitationInvalid, errorCode(err))
+		})
+
+		t.Run("Should still sign up without an invitation when open", func(t *testing.T) {
+			viper.Set("signup.mode", usecase.SignUpModeOpen)
+			repositoryMock.Mock.On("Save", mock.MatchedBy(func(user *entity.User) bool {
+				return user.Email == "invited@acme.com" && user.Role == ""
+			})).Return(&entity.User{Id: 4, Name: "Invited", Email: "invited@acme.com", Role: entity.RoleUser}).Once()
+
+			result, err := signupUseCase.CreateUser(context.Background(), request(""))
+			require.Nil(t, err)
+			require.Equal(t, entity.RoleUser, result.Role)
+		})
 	})
 
+	t.Run("Should refuse an email domain with a clear error code", func(t *testing.T) {
+		signupUseCase.EmailDomainPolicy.Allow = []string{"*.acme.com"}
+		defer func() { signupUseCase.EmailDomainPolicy.Allow = nil }()
+
+		request := &models.SignUpRequest{Name: "Jane", Email: "jane@gmail.com", Password: "s3cure-Passphrase"}
+		result, err := signupUseCase.CreateUser(context.Background(), request)
+		require.Nil(t, result)
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Email domain is not allowed",
+			Errors: []models.ErrorDetail{{Code: security.EmailDomainNotAllowed, Message: "Email domain is not allowed"}}}, err)
+		repositoryMock.Mock.AssertNotCalled(t, "FindOneByEmailWithDeleted", "jane@gmail.com")
+	})
 }
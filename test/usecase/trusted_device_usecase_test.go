@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"testing"
+	"time"
+)
+
+func TestTrustedDeviceUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	validator := config.NewValidator()
+	trustedDeviceRepositoryMock := mocks.NewTrustedDeviceRepositoryMock()
+	authUseCase := usecase.NewAuthUseCase(mocks.NewUserRepositoryMock(), mocks.NewSessionRepositoryMock(), newLoginAttemptRepositoryMock(), trustedDeviceRepositoryMock, validator, viper)
+	trustedDeviceUseCase := usecase.NewTrustedDeviceUseCase(authUseCase, trustedDeviceRepositoryMock)
+	user := &entity.User{Id: 1}
+
+	t.Run("Should list the devices and mark the current one", func(t *testing.T) {
+		var saved *entity.TrustedDevice
+		trustedDeviceRepositoryMock.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*entity.TrustedDevice)
+			saved.Id = 4
+		}).Return(nil).Once()
+		token, _, err := authUseCase.TrustDevice(context.Background(), user, models.ClientInfo{IpAddress: "10.0.0.1", UserAgent: "curl/8.0"})
+		require.Nil(t, err)
+
+		expiresAt := time.Now().Add(time.Hour)
+		trustedDeviceRepositoryMock.Mock.On("FindOneByTokenHash", saved.TokenHash).Return(saved)
+		trustedDeviceRepositoryMock.Mock.On("FindAllActiveByUserId", 1).Return([]entity.TrustedDevice{
+			{Id: 3, UserId: 1, IpAddress: "10.0.0.2", ExpiresAt: expiresAt},
+			*saved,
+		})
+
+		result, err := trustedDeviceUseCase.GetDevices(context.Background(), 1, token)
+		require.Nil(t, err)
+		require.Len(t, result, 2)
+		require.False(t, result[0].Current)
+		require.True(t, result[1].Current)
+		require.Equal(t, "curl/8.0", result[1].UserAgent)
+
+		result, err = trustedDeviceUseCase.GetDevices(context.Background(), 1, "not-a-token")
+		require.Nil(t, err)
+		require.False(t, result[1].Current)
+	})
+
+	t.Run("Should revoke a device of the user only", func(t *testing.T) {
+		trustedDeviceRepositoryMock.Mock.On("Delete", 1, 3).Return(true)
+		trustedDeviceRepositoryMock.Mock.On("Delete", 1, 8).Return(false)
+
+		err := trustedDeviceUseCase.RevokeDevice(context.Background(), 1, 3)
+		require.Nil(t, err)
+
+		err = trustedDeviceUseCase.RevokeDevice(context.Background(), 1, 8)
+		require.Equal(t, &models.ErrorResponse{Code: 404, Status: "Not Found", Message: "Trusted device not found"}, err)
+	})
+
+	t.Run("Should revoke every device of the user", func(t *testing.T) {
+		trustedDeviceRepositoryMock.Mock.On("DeleteAllByUserId", 1).Return(nil)
+
+		err := trustedDeviceUseCase.RevokeAllDevices(context.Background(), 1)
+		require.Nil(t, err)
+		trustedDeviceRepositoryMock.Mock.AssertCalled(t, "DeleteAllByUserId", 1)
+	})
+}
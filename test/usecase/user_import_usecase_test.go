@@ -0,0 +1,225 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"os"
+	"strings"
+	"testing"
+)
+
+const importCSV = `name,email,username,password_hash
+Danar,danar@gmail.com,danar,$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS
+Taken,taken@gmail.com,,{SSHA}IbmSpl71D9zoXWwMIXWGhS8VTOtzYWx0c2FsdA==
+Plain,plain@gmail.com,,5f4dcc3b5aa765d61d8327deb882cf99
+,noname@gmail.com,,{SSHA}IbmSpl71D9zoXWwMIXWGhS8VTOtzYWx0c2FsdA==
+Scrypt,scrypt@gmail.com,,"$scrypt$ln=14,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$LLP61ttm3z3DWEeq2HIZpVEfPYfB2aLgurtIsW8/FCE"
+`
+
+func TestUserImportUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("import.directory", t.TempDir())
+	validator := config.NewValidator()
+
+	setup := func() (*usecase.UserImportUseCase, *mocks.UserRepositoryMock, *mocks.UserImportRepositoryMock) {
+		userRepositoryMock := mocks.NewUserRepositoryMock()
+		userImportRepositoryMock := mocks.NewUserImportRepositoryMock()
+		userImportRepositoryMock.Mock.On("UpdateJobProgress", mock.Anything).Return(nil)
+		userImportRepositoryMock.Mock.On("SaveError", mock.Anything).Return(nil)
+		return usecase.NewUserImportUseCase(userRepositoryMock, userImportRepositoryMock, validator, viper), userRepositoryMock, userImportRepositoryMock
+	}
+
+	createJob := func(t *testing.T, userImportUseCase *usecase.UserImportUseCase, userImportRepositoryMock *mocks.UserImportRepositoryMock, format string, content string) *entity.UserImportJob {
+		var job *entity.UserImportJob
+		userImportRepositoryMock.Mock.On("SaveJob", mock.Anything).Run(func(args mock.Arguments) {
+			job = args.Get(0).(*entity.UserImportJob)
+			job.Id = 1
+		}).Return(&entity.UserImportJob{Id: 1}).Once()
+
+		_, err := userImportUseCase.CreateImport(context.Background(), format, strings.NewReader(content), nil)
+		require.Nil(t, err)
+		return job
+	}
+
+	t.Run("Should refuse an unknown format", func(t *testing.T) {
+		userImportUseCase, _, _ := setup()
+		result, err := userImportUseCase.CreateImport(context.Background(), "xml", strings.NewReader(""), nil)
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Format must be csv or jsonl"}, err)
+		require.Nil(t, result)
+	})
+
+	t.Run("Should import the valid rows and report the others", func(t *testing.T) {
+		userImportUseCase, userRepositoryMock, userImportRepositoryMock := setup()
+		job := createJob(t, userImportUseCase, userImportRepositoryMock, "csv", importCSV)
+		content, err := os.ReadFile(job.FilePath)
+		require.Nil(t, err)
+		require.Equal(t, importCSV, string(content))
+
+		userRepositoryMock.Mock.On("FindOneByEmailWithDeleted", "danar@gmail.com").Return(nil)
+		userRepositoryMock.Mock.On("FindOneByUsernameWithDeleted", "danar").Return(nil)
+		userRepositoryMock.Mock.On("FindOneByEmailWithDeleted", "taken@gmail.com").Return(&entity.User{Id: 9})
+		userRepositoryMock.Mock.On("FindOneByEmailWithDeleted", "scrypt@gmail.com").Return(nil)
+		userRepositoryMock.Mock.On("Save", mock.Anything).Return(&entity.User{})
+		userImportRepositoryMock.Mock.On("ClaimJob", 1).Return(true).Once()
+		userImportRepositoryMock.Mock.On("FindJobById", 1).Return(job).Once()
+
+		result, err := userImportUseCase.RunImport(context.Background(), 1)
+		require.Nil(t, err)
+		require.Equal(t, entity.UserImportStatusCompleted, result.Status)
+		require.Equal(t, 5, result.ProcessedRows)
+		require.Equal(t, 2, result.ImportedRows)
+		require.Equal(t, 3, result.FailedRows)
+
+		userRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(user *entity.User) bool {
+			return user.Email == "danar@gmail.com" && *user.Username == "danar" && strings.HasPrefix(user.Password, "$2a$10$")
+		}))
+		userImportRepositoryMock.Mock.AssertCalled(t, "SaveError", &entity.UserImportError{JobId: 1, RowNumber: 2, Email: "taken@gmail.com", Message: "Email already exists"})
+		userImportRepositoryMock.Mock.AssertCalled(t, "SaveError", &entity.UserImportError{JobId: 1, RowNumber: 3, Email: "plain@gmail.com", Message: "Password hash format is not supported"})
+		userImportRepositoryMock.Mock.AssertCalled(t, "SaveError", &entity.UserImportError{JobId: 1, RowNumber: 4, Email: "noname@gmail.com", Message: "Name must be required"})
+
+		_, err = os.Stat(job.FilePath)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Should import an Auth0 export", func(t *testing.T) {
+		userImportUseCase, userRepositoryMock, userImportRepositoryMock := setup()
+		content := `{"_id":{"$oid":"60425dc43519d90068f82973"},"email":"auth0@gmail.com","email_verified":true,"passwordHash":"$2b$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}
+{"email":"custom@gmail.com","given_name":"Custom","family_name":"Hash","custom_password_hash":{"algorithm":"bcrypt","hash":{"value":"$2b$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS"}}}
+`
+		job := createJob(t, userImportUseCase, userImportRepositoryMock, "jsonl", content)
+		userRepositoryMock.Mock.On("FindOneByEmailWithDeleted", "auth0@gmail.com").Return(nil)
+		userRepositoryMock.Mock.On("FindOneByEmailWithDeleted", "custom@gmail.com").Return(nil)
+		userRepositoryMock.Mock.On("Save", mock.Anything).Return(&entity.User{})
+		userImportRepositoryMock.Mock.On("ClaimJob", 1).Return(true).Once()
+		userImportRepositoryMock.Mock.On("FindJobById", 1).Return(job).Once()
+
+		result, err := userImportUseCase.RunImport(context.Background(), 1)
+		require.Nil(t, err)
+		require.Equal(t, 2, result.ImportedRows)
+		userRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(user *entity.User) bool {
+			return user.Name == "auth0@gmail.com" && strings.HasPrefix(user.Password, "$2b$10$")
+		}))
+		userRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(user *entity.User) bool {
+			return user.Name == "Custom Hash" && strings.HasPrefix(user.Password, "$2b$10$")
+		}))
+	})
+
+	t.Run("Should resume after the processed rows", func(t *testing.T) {
+		userImportUseCase, userRepositoryMock, userImportRepositoryMock := setup()
+		content := `{"name":"First","email":"first@gmail.com","password_hash":"{SSHA}IbmSpl71D9zoXWwMIXWGhS8VTOtzYWx0c2FsdA=="}
+
+not json
+{"name":"Third","email":"third@gmail.com","password_hash":"{SSHA}IbmSpl71D9zoXWwMIXWGhS8VTOtzYWx0c2FsdA=="}
+`
+		job := createJob(t, userImportUseCase, userImportRepositoryMock, "jsonl", content)
+		job.Status = entity.UserImportStatusRunning
+		job.ProcessedRows = 1
+		job.ImportedRows = 1
+
+		userRepositoryMock.Mock.On("FindOneByEmailWithDeleted", "third@gmail.com").Return(nil)
+		userRepositoryMock.Mock.On("Save", mock.Anything).Return(&entity.User{})
+		userImportRepositoryMock.Mock.On("ClaimNextJob").Return(job).Once()
+		userImportRepositoryMock.Mock.On("ClaimNextJob").Return(nil).Once()
+
+		processed, err := userImportUseCase.ProcessPendingImports(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, 1, processed)
+		require.Equal(t, entity.UserImportStatusCompleted, job.Status)
+		require.Equal(t, 3, job.ProcessedRows)
+		require.Equal(t, 2, job.ImportedRows)
+		require.Equal(t, 1, job.FailedRows)
+		userRepositoryMock.Mock.AssertNotCalled(t, "FindOneByEmailWithDeleted", "first@gmail.com")
+		userImportRepositoryMock.Mock.AssertCalled(t, "SaveError", mock.MatchedBy(func(importError *entity.UserImportError) bool {
+			return importError.RowNumber == 2 && strings.HasPrefix(importError.Message, "invalid row")
+		}))
+	})
+
+	t.Run("Should stop at a database error and retry the row when resumed", func(t *testing.T) {
+		userImportUseCase, userRepositoryMock, userImportRepositoryMock := setup()
+		content := `{"name":"First","email":"first@gmail.com","password_hash":"{SSHA}IbmSpl71D9zoXWwMIXWGhS8VTOtzYWx0c2FsdA=="}
+{"name":"Second","email":"second@gmail.com","password_hash":"{SSHA}IbmSpl71D9zoXWwMIXWGhS8VTOtzYWx0c2FsdA=="}
+`
+		job := createJob(t, userImportUseCase, userImportRepositoryMock, "jsonl", content)
+		job.Status = entity.UserImportStatusRunning
+
+		userRepositoryMock.Mock.On("FindOneByEmailWithDeleted", "first@gmail.com").Return(nil).Once()
+		userRepositoryMock.Mock.On("Save", mock.Anything).Return(nil, errors.New("connection refused")).Once()
+		userImportRepositoryMock.Mock.On("ClaimNextJob").Return(job).Once()
+
+		_, err := userImportUseCase.ProcessPendingImports(context.Background())
+		require.NotNil(t, err)
+		require.Equal(t, entity.UserImportStatusRunning, job.Status)
+		require.Equal(t, 0, job.ProcessedRows)
+		require.Equal(t, 0, job.FailedRows)
+		userImportRepositoryMock.Mock.AssertNotCalled(t, "SaveError", mock.Anything)
+		_, err = os.Stat(job.FilePath)
+		require.Nil(t, err)
+
+		//the first user was saved after all, only its progress was lost
+		userRepositoryMock.Mock.On("FindOneByEmailWithDeleted", "first@gmail.com").Return(&entity.User{Id: 3, Email: "first@gmail.com", Password: "{SSHA}IbmSpl71D9zoXWwMIXWGhS8VTOtzYWx0c2FsdA=="})
+		userRepositoryMock.Mock.On("FindOneByEmailWithDeleted", "second@gmail.com").Return(nil)
+		userRepositoryMock.Mock.On("Save", mock.Anything).Return(&entity.User{})
+		userImportRepositoryMock.Mock.On("ClaimNextJob").Return(job).Once()
+		userImportRepositoryMock.Mock.On("ClaimNextJob").Return(nil).Once()
+
+		processed, err := userImportUseCase.ProcessPendingImports(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, 1, processed)
+		require.Equal(t, entity.UserImportStatusCompleted, job.Status)
+		require.Equal(t, 2, job.ImportedRows)
+		require.Equal(t, 0, job.FailedRows)
+	})
+
+	t.Run("Should refuse hashes with parameters out of bounds", func(t *testing.T) {
+		userImportUseCase, userRepositoryMock, userImportRepositoryMock := setup()
+		content := `name,email,username,password_hash
+Zero,zero@gmail.com,,"$argon2id$v=19$m=65536,t=0,p=0$c29tZXNhbHRzb21lc2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+Huge,huge@gmail.com,,"$scrypt$ln=30,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$LLP61ttm3z3DWEeq2HIZpVEfPYfB2aLgurtIsW8/FCE"
+Slow,slow@gmail.com,,$pbkdf2-sha256$2000000000$MDEyMzQ1Njc4OWFiY2RlZg$cTz3gk3UdWQZMqIuASuTgJzTcoQTvJe/HL9yRSZa4JE
+`
+		job := createJob(t, userImportUseCase, userImportRepositoryMock, "csv", content)
+		userImportRepositoryMock.Mock.On("ClaimJob", 1).Return(true).Once()
+		userImportRepositoryMock.Mock.On("FindJobById", 1).Return(job).Once()
+
+		result, err := userImportUseCase.RunImport(context.Background(), 1)
+		require.Nil(t, err)
+		require.Equal(t, 3, result.FailedRows)
+		require.Equal(t, 0, result.ImportedRows)
+		userRepositoryMock.Mock.AssertNotCalled(t, "Save", mock.Anything)
+		userImportRepositoryMock.Mock.AssertCalled(t, "SaveError", mock.MatchedBy(func(importError *entity.UserImportError) bool {
+			return importError.RowNumber == 1 && strings.HasPrefix(importError.Message, "Password hash is invalid: argon2id iterations 0")
+		}))
+	})
+
+	t.Run("Should fail the job when the csv header is incomplete", func(t *testing.T) {
+		userImportUseCase, _, userImportRepositoryMock := setup()
+		job := createJob(t, userImportUseCase, userImportRepositoryMock, "csv", "name,email\nDanar,danar@gmail.com\n")
+		userImportRepositoryMock.Mock.On("ClaimJob", 1).Return(true).Once()
+		userImportRepositoryMock.Mock.On("FindJobById", 1).Return(job).Once()
+
+		result, err := userImportUseCase.RunImport(context.Background(), 1)
+		require.Nil(t, err)
+		require.Equal(t, entity.UserImportStatusFailed, result.Status)
+		require.Equal(t, "csv header is missing the password_hash column", result.ErrorMessage)
+
+		_, err = os.Stat(job.FilePath)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Should not run an import twice", func(t *testing.T) {
+		userImportUseCase, _, userImportRepositoryMock := setup()
+		userImportRepositoryMock.Mock.On("ClaimJob", 2).Return(false).Once()
+		userImportRepositoryMock.Mock.On("FindJobById", 2).Return(&entity.UserImportJob{Id: 2, Status: entity.UserImportStatusRunning}).Once()
+
+		result, err := userImportUseCase.RunImport(context.Background(), 2)
+		require.Equal(t, 409, err.(*models.ErrorResponse).Code)
+		require.Nil(t, result)
+	})
+}
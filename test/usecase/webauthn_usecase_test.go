@@ -0,0 +1,253 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang-authentication/internal/config"
+	"golang-authentication/internal/entity"
+	"golang-authentication/internal/helpers"
+	"golang-authentication/internal/models"
+	"golang-authentication/internal/usecase"
+	"golang-authentication/test/mocks"
+	"testing"
+	"time"
+)
+
+func TestWebAuthnUseCase(t *testing.T) {
+	viper := config.NewViper("./../../")
+	viper.Set("password.hashing.algorithm", "bcrypt")
+	viper.Set("key.pepper.current_version", 0)
+	viper.Set("webauthn.enabled", true)
+	validator := config.NewValidator()
+	invalidPasskey := &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Passkey is invalid"}
+
+	type fixture struct {
+		webAuthnUseCase            *usecase.WebAuthnUseCase
+		authUseCase                *usecase.AuthUseCase
+		sessionRepositoryMock      *mocks.SessionRepositoryMock
+		userRepositoryMock         *mocks.UserRepositoryMock
+		credentialRepositoryMock   *mocks.WebAuthnCredentialRepositoryMock
+		challengeRepositoryMock    *mocks.WebAuthnChallengeRepositoryMock
+		recoveryCodeRepositoryMock *mocks.RecoveryCodeRepositoryMock
+		authenticator              *mocks.SoftwareAuthenticator
+	}
+
+	setup := func(user *entity.User) *fixture {
+		userRepositoryMock := mocks.NewUserRepositoryMock()
+		userRepositoryMock.Mock.On("FindOneByExactEmail", mock.Anything).Return(nil)
+		userRepositoryMock.Mock.On("FindOneByEmail", user.Email).Return(user)
+		userRepositoryMock.Mock.On("FindOneById", user.Id).Return(user)
+		userRepositoryMock.Mock.On("UpdateLastLoginAt", mock.Anything, mock.Anything).Return(nil)
+		userRepositoryMock.Mock.On("UpdateMfaEnabled", mock.Anything, mock.Anything).Return(nil)
+		sessionRepositoryMock := mocks.NewSessionRepositoryMock()
+		sessionRepositoryMock.Mock.On("Save", mock.Anything).Return(&entity.Session{Id: "passkey-session", UserId: user.Id})
+		authUseCase := usecase.NewAuthUseCase(userRepositoryMock, sessionRepositoryMock, newLoginAttemptRepositoryMock(), mocks.NewTrustedDeviceRepositoryMock(), validator, viper)
+		credentialRepositoryMock := mocks.NewWebAuthnCredentialRepositoryMock()
+		recoveryCodeRepositoryMock := mocks.NewRecoveryCodeRepositoryMock()
+		recoveryCodeRepositoryMock.Mock.On("ReplaceAll", user.Id, mock.Anything).Return(nil)
+		mfaUseCase := usecase.NewMfaUseCase(authUseCase, userRepositoryMock, mocks.NewTotpCredentialRepositoryMock(), recoveryCodeRepositoryMock, credentialRepositoryMock, mocks.NewOneTimeCodeRepositoryMock(), mocks.NewSmsSenderMock(), validator, viper)
+		challengeRepositoryMock := mocks.NewWebAuthnChallengeRepositoryMock()
+		challengeRepositoryMock.Mock.On("MarkUsed", 5).Return(true)
+		webAuthnUseCase := usecase.NewWebAuthnUseCase(authUseCase, mfaUseCase, userRepositoryMock, credentialRepositoryMock, challengeRepositoryMock, validator, viper)
+		authenticator := mocks.NewSoftwareAuthenticator("localhost", "http://localhost:8080")
+
+		return &fixture{webAuthnUseCase: webAuthnUseCase, authUseCase: authUseCase, sessionRepositoryMock: sessionRepositoryMock, userRepositoryMock: userRepositoryMock,
+			credentialRepositoryMock: credentialRepositoryMock, challengeRepositoryMock: challengeRepositoryMock,
+			recoveryCodeRepositoryMock: recoveryCodeRepositoryMock, authenticator: authenticator}
+	}
+
+	//expectChallenge keeps the next saved challenge, so it can be found again when the ceremony finishes
+	expectChallenge := func(f *fixture) {
+		f.challengeRepositoryMock.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+			challenge := args.Get(0).(*entity.WebAuthnChallenge)
+			challenge.Id = 5
+			f.challengeRepositoryMock.Mock.On("FindOneByChallengeHash", challenge.ChallengeHash).Return(challenge)
+		}).Return(nil).Once()
+	}
+
+	registered := func(f *fixture, userID int) *entity.WebAuthnCredential {
+		credential := &entity.WebAuthnCredential{Id: 9, UserId: userID, CredentialId: f.authenticator.Id(), PublicKey: f.authenticator.COSEKey(), Transports: "internal"}
+		f.credentialRepositoryMock.Mock.On("FindOneByCredentialIdHash", helpers.HashToken(f.authenticator.Id())).Return(credential)
+		f.credentialRepositoryMock.Mock.On("FindAllByUserId", userID).Return([]entity.WebAuthnCredential{*credential})
+		return credential
+	}
+
+	newUser := func(mfaEnabled bool) *entity.User {
+		return &entity.User{Id: 1, Email: "danar@gmail.com", Password: "$2a$10$rzGrygHegWythHS9wnC8u.jdM7MAgqFoUsPuTIMnIugZSWa5hsfUS", MfaEnabled: mfaEnabled}
+	}
+
+	t.Run("Should register a passkey and turn two-factor authentication on", func(t *testing.T) {
+		f := setup(newUser(false))
+		f.credentialRepositoryMock.Mock.On("FindAllByUserId", 1).Return([]entity.WebAuthnCredential{})
+		f.credentialRepositoryMock.Mock.On("FindOneByCredentialIdHash", mock.Anything).Return(nil)
+		var saved *entity.WebAuthnCredential
+		f.credentialRepositoryMock.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*entity.WebAuthnCredential)
+			saved.Id = 9
+		}).Return(nil)
+		expectChallenge(f)
+
+		options, err := f.webAuthnUseCase.BeginRegistration(context.Background(), 1)
+		require.Nil(t, err)
+		require.Equal(t, "localhost", options.Rp.Id)
+		require.Equal(t, "danar@gmail.com", options.User.Name)
+		require.Equal(t, -7, options.PubKeyCredParams[0].Alg)
+
+		response, err := f.webAuthnUseCase.FinishRegistration(context.Background(), 1, &models.WebAuthnRegistrationRequest{
+			Name:                       "Laptop",
+			PublicKeyCredentialRequest: f.authenticator.Register(options.Challenge, "packed"),
+		})
+		require.Nil(t, err)
+		require.Equal(t, "Laptop", response.Credential.Name)
+		require.Equal(t, []string{"internal", "hybrid"}, response.Credential.Transports)
+		require.Len(t, response.RecoveryCodes, 10)
+		require.Equal(t, f.authenticator.Id(), saved.CredentialId)
+		require.Equal(t, f.authenticator.COSEKey(), saved.PublicKey)
+		f.userRepositoryMock.Mock.AssertCalled(t, "UpdateMfaEnabled", 1, true)
+	})
+
+	t.Run("Should not register a passkey with the challenge of another user", func(t *testing.T) {
+		f := setup(newUser(false))
+		f.credentialRepositoryMock.Mock.On("FindAllByUserId", 1).Return([]entity.WebAuthnCredential{})
+		expectChallenge(f)
+
+		options, err := f.webAuthnUseCase.BeginRegistration(context.Background(), 1)
+		require.Nil(t, err)
+
+		_, err = f.webAuthnUseCase.FinishRegistration(context.Background(), 2, &models.WebAuthnRegistrationRequest{
+			PublicKeyCredentialRequest: f.authenticator.Register(options.Challenge, "none"),
+		})
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Passkey challenge is invalid or expired"}, err)
+	})
+
+	t.Run("Should sign in without a password", func(t *testing.T) {
+		f := setup(newUser(true))
+		registered(f, 1)
+		f.credentialRepositoryMock.Mock.On("UpdateSignCount", 9, int64(1)).Return(true)
+		//the user.id of the registration options, user 1 as 8 bytes big endian
+		f.authenticator.UserHandle = "AAAAAAAAAAE"
+		expectChallenge(f)
+
+		options, err := f.webAuthnUseCase.BeginSignIn(context.Background(), &models.WebAuthnSignInOptionsRequest{})
+		require.Nil(t, err)
+		require.Empty(t, options.AllowCredentials)
+		require.Equal(t, "required", options.UserVerification)
+
+		result, err := f.webAuthnUseCase.SignIn(context.Background(), &models.WebAuthnSignInRequest{PublicKeyCredentialRequest: f.authenticator.Assert(options.Challenge)})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.AccessToken)
+		require.NotEmpty(t, result.RefreshToken)
+	})
+
+	t.Run("Should require user verification without a password", func(t *testing.T) {
+		f := setup(newUser(false))
+		registered(f, 1)
+		f.authenticator.UserVerified = false
+		expectChallenge(f)
+
+		options, err := f.webAuthnUseCase.BeginSignIn(context.Background(), &models.WebAuthnSignInOptionsRequest{})
+		require.Nil(t, err)
+
+		_, err = f.webAuthnUseCase.SignIn(context.Background(), &models.WebAuthnSignInRequest{PublicKeyCredentialRequest: f.authenticator.Assert(options.Challenge)})
+		require.Equal(t, invalidPasskey, err)
+	})
+
+	t.Run("Should sign in with a passkey as the second factor", func(t *testing.T) {
+		f := setup(newUser(true))
+		registered(f, 1)
+		f.credentialRepositoryMock.Mock.On("UpdateSignCount", 9, int64(1)).Return(true)
+		f.authenticator.UserVerified = false
+		expectChallenge(f)
+
+		first, err := f.authUseCase.SignIn(context.Background(), &models.SignInRequest{Email: "danar@gmail.com", Password: "12345678"})
+		require.Nil(t, err)
+		require.True(t, first.MfaRequired)
+
+		options, err := f.webAuthnUseCase.BeginSignIn(context.Background(), &models.WebAuthnSignInOptionsRequest{MfaToken: first.MfaToken})
+		require.Nil(t, err)
+		require.Len(t, options.AllowCredentials, 1)
+		require.Equal(t, f.authenticator.Id(), options.AllowCredentials[0].Id)
+
+		_, err = f.webAuthnUseCase.SignIn(context.Background(), &models.WebAuthnSignInRequest{PublicKeyCredentialRequest: f.authenticator.Assert(options.Challenge)})
+		require.Equal(t, &models.ErrorResponse{Code: 401, Status: "Unauthorized", Message: "Invalid token"}, err)
+
+		f.authenticator.SignCount = 0
+		result, err := f.webAuthnUseCase.SignIn(context.Background(), &models.WebAuthnSignInRequest{MfaToken: first.MfaToken, PublicKeyCredentialRequest: f.authenticator.Assert(options.Challenge)})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.AccessToken)
+	})
+
+	t.Run("Should reject a passkey whose sign count went back", func(t *testing.T) {
+		f := setup(newUser(true))
+		registered(f, 1)
+		f.credentialRepositoryMock.Mock.On("UpdateSignCount", 9, int64(1)).Return(false)
+		expectChallenge(f)
+
+		options, err := f.webAuthnUseCase.BeginSignIn(context.Background(), &models.WebAuthnSignInOptionsRequest{})
+		require.Nil(t, err)
+
+		_, err = f.webAuthnUseCase.SignIn(context.Background(), &models.WebAuthnSignInRequest{PublicKeyCredentialRequest: f.authenticator.Assert(options.Challenge)})
+		require.Equal(t, invalidPasskey, err)
+	})
+
+	t.Run("Should re-authenticate the session with a passkey", func(t *testing.T) {
+		f := setup(newUser(true))
+		registered(f, 1)
+		f.credentialRepositoryMock.Mock.On("UpdateSignCount", 9, mock.Anything).Return(true)
+		f.userRepositoryMock.Mock.On("UpdateSignInAttempts", mock.Anything).Return(nil)
+		session := &entity.Session{Id: "old-session", UserId: 1, ExpiresAt: time.Now().Add(time.Hour)}
+		f.sessionRepositoryMock.Mock.On("FindOneById", "old-session").Return(session)
+		f.sessionRepositoryMock.Mock.On("UpdateAuthentication", session).Return(nil)
+		claims := &usecase.AccessClaims{UserId: 1, SessionId: "old-session", Amr: []string{"pwd"}, Acr: usecase.AcrSingleFactor}
+		expectChallenge(f)
+
+		options, err := f.webAuthnUseCase.BeginReauthentication(context.Background(), claims)
+		require.Nil(t, err)
+		require.Len(t, options.AllowCredentials, 1)
+		require.Equal(t, "required", options.UserVerification)
+		f.challengeRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(challenge *entity.WebAuthnChallenge) bool {
+			return *challenge.UserId == 1 && challenge.Purpose == entity.WebAuthnChallengePurposeReauthentication
+		}))
+
+		//the challenge is bound to the user of the session
+		_, err = f.webAuthnUseCase.Reauthenticate(context.Background(), &usecase.AccessClaims{UserId: 2, SessionId: "old-session"}, &models.WebAuthnReauthenticateRequest{PublicKeyCredentialRequest: f.authenticator.Assert(options.Challenge)})
+		require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+
+		result, err := f.webAuthnUseCase.Reauthenticate(context.Background(), claims, &models.WebAuthnReauthenticateRequest{PublicKeyCredentialRequest: f.authenticator.Assert(options.Challenge)})
+		require.Nil(t, err)
+		upgraded, err := f.authUseCase.VerifyAccessClaims(result.AccessToken)
+		require.Nil(t, err)
+		require.Equal(t, "old-session", upgraded.SessionId)
+		require.Equal(t, []string{"hwk", "mfa"}, upgraded.Amr)
+		require.Nil(t, f.authUseCase.CheckStepUp(upgraded, time.Minute, usecase.AcrMultiFactor))
+	})
+
+	t.Run("Should not re-authenticate with a sign in challenge", func(t *testing.T) {
+		f := setup(newUser(true))
+		registered(f, 1)
+		f.sessionRepositoryMock.Mock.On("FindOneById", "old-session").Return(&entity.Session{Id: "old-session", UserId: 1, ExpiresAt: time.Now().Add(time.Hour)})
+		expectChallenge(f)
+
+		options, err := f.webAuthnUseCase.BeginSignIn(context.Background(), &models.WebAuthnSignInOptionsRequest{})
+		require.Nil(t, err)
+
+		_, err = f.webAuthnUseCase.Reauthenticate(context.Background(), &usecase.AccessClaims{UserId: 1, SessionId: "old-session"}, &models.WebAuthnReauthenticateRequest{PublicKeyCredentialRequest: f.authenticator.Assert(options.Challenge)})
+		require.Equal(t, &models.ErrorResponse{Code: 400, Status: "Bad Request", Message: "Passkey challenge is invalid or expired"}, err)
+		f.sessionRepositoryMock.Mock.AssertNotCalled(t, "UpdateAuthentication", mock.Anything)
+	})
+
+	t.Run("Should turn two-factor authentication off with the last passkey", func(t *testing.T) {
+		f := setup(newUser(true))
+		f.credentialRepositoryMock.Mock.On("Delete", 1, 9).Return(true)
+		f.credentialRepositoryMock.Mock.On("CountByUserId", 1).Return(int64(0))
+		f.webAuthnUseCase.MfaUseCase.TotpCredentialRepository.(*mocks.TotpCredentialRepositoryMock).Mock.On("FindOneByUserId", 1).Return(nil)
+		f.recoveryCodeRepositoryMock.Mock.On("DeleteAllByUserId", 1).Return(nil)
+		f.authUseCase.TrustedDeviceRepo.(*mocks.TrustedDeviceRepositoryMock).Mock.On("DeleteAllByUserId", 1).Return(nil)
+
+		err := f.webAuthnUseCase.DeleteCredential(context.Background(), 1, 9)
+		require.Nil(t, err)
+		f.userRepositoryMock.Mock.AssertCalled(t, "UpdateMfaEnabled", 1, false)
+		f.recoveryCodeRepositoryMock.Mock.AssertCalled(t, "DeleteAllByUserId", 1)
+	})
+}